@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+func newAggregateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "aggregate [file ...]",
+		Short: "Summarize IP addresses into the fewest prefixes",
+		Long: `The aggregate command reads IP addresses and networks in CIDR notation, and prints
+the fewest prefixes covering exactly them, IPv4 before IPv6.
+Lines that are neither IP addresses nor networks are ignored.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ips []IPAddress
+			if len(args) == 0 {
+				read, err := readAddresses(cmd.InOrStdin(), "")
+				if err != nil {
+					return err
+				}
+				ips = read
+			}
+			for _, file := range args {
+				f, err := os.Open(file)
+				if err != nil {
+					return err
+				}
+				read, err := readAddresses(f, file)
+				f.Close()
+				if err != nil {
+					return err
+				}
+				ips = append(ips, read...)
+			}
+
+			w := bufio.NewWriter(cmd.OutOrStdout())
+			defer w.Flush()
+			for _, n := range Aggregate(ips) {
+				fmt.Fprintln(w, n)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// readAddresses reads the IP addresses and the networks of in, one per line.
+func readAddresses(in io.Reader, filename string) ([]IPAddress, error) {
+	name := displayName(filename)
+	in, err := decompress(in, filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	var ips []IPAddress
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		ip, err := parseIPBytes(sc.Bytes())
+		if err != nil {
+			n, err := ParseNetwork(sc.Text())
+			if err != nil {
+				continue
+			}
+			ip = n
+		}
+		ips = append(ips, ip)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ips, nil
+}
+
+// Aggregate returns the fewest prefixes covering exactly the IP addresses,
+// sorted with IPv4 before IPv6. A Network in ips stands for all of its
+// addresses. Zones are dropped.
+func Aggregate(ips []IPAddress) []Network {
+	// the ranges of the addresses
+	type span struct {
+		first, last IPAddress
+	}
+	spans := make([]span, 0, len(ips))
+	for _, ip := range ips {
+		if n, ok := ip.(Network); ok {
+			spans = append(spans, span{first: n.First(), last: n.Last()})
+			continue
+		}
+		ip = ipFromBytes(ip.Bytes())
+		spans = append(spans, span{first: ip, last: ip})
+	}
+	slices.SortFunc(spans, func(a, b span) int {
+		return compareIP(a.first, b.first)
+	})
+
+	// merge the overlapping and adjacent ranges of the same version
+	var merged []span
+	for _, s := range spans {
+		if len(merged) > 0 {
+			cur := &merged[len(merged)-1]
+			if cur.last.Version() == s.first.Version() &&
+				(compareIP(s.first, cur.last) <= 0 || compareIP(s.first, nextIP(cur.last)) == 0) {
+				if compareIP(s.last, cur.last) > 0 {
+					cur.last = s.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+
+	var networks []Network
+	for _, s := range merged {
+		networks = appendRangePrefixes(networks, s.first, s.last)
+	}
+	return networks
+}
+
+// appendRangePrefixes appends the fewest prefixes covering from first to last.
+func appendRangePrefixes(networks []Network, first, last IPAddress) []Network {
+	width := len(first.Bytes()) * 8
+	for {
+		// the largest prefix starting at first and ending by last
+		var n Network
+		for bits := 0; bits <= width; bits++ {
+			n = Network{IP: first, Bits: bits}
+			if compareIP(n.First(), first) == 0 && compareIP(n.Last(), last) <= 0 {
+				break
+			}
+		}
+		networks = append(networks, n)
+		if compareIP(n.Last(), last) == 0 {
+			return networks
+		}
+		first = nextIP(n.Last())
+	}
+}
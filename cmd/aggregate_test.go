@@ -0,0 +1,92 @@
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestAggregate(t *testing.T) {
+	testCases := []struct {
+		description string
+		ips         []string
+		expected    []string
+	}{
+		{
+			description: "Single Prefix",
+			ips:         []string{"10.0.0.3", "10.0.0.0", "10.0.0.2", "10.0.0.1"},
+			expected:    []string{"10.0.0.0/30"},
+		},
+		{
+			description: "Unaligned Run",
+			ips:         []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"},
+			expected:    []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"},
+		},
+		{
+			description: "Duplicated and Overlapping",
+			ips:         []string{"10.0.0.1", "10.0.0.1", "10.0.0.0/31", "10.0.0.0/24"},
+			expected:    []string{"10.0.0.0/24"},
+		},
+		{
+			description: "Adjacent Networks",
+			ips:         []string{"192.168.1.0/24", "192.168.0.0/24", "192.168.2.0/23"},
+			expected:    []string{"192.168.0.0/22"},
+		},
+		{
+			description: "Top of IPv4 Space",
+			ips:         []string{"255.255.255.255", "255.255.255.254", "255.255.255.252/31"},
+			expected:    []string{"255.255.255.252/30"},
+		},
+		{
+			description: "Both Versions",
+			ips:         []string{"2001:db8::1", "10.0.0.1", "2001:db8::", "::ffff:10.0.0.0"},
+			expected:    []string{"10.0.0.1/32", "::ffff:10.0.0.0/128", "2001:db8::/127"},
+		},
+		{
+			description: "IPv6 Run",
+			ips:         []string{"2001:db8::/33", "2001:db8:8000::/33", "2001:db9::"},
+			expected:    []string{"2001:db8::/32", "2001:db9::/128"},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ips := make([]cmd.IPAddress, len(tc.ips))
+		for i, s := range tc.ips {
+			if strings.Contains(s, "/") {
+				n, err := cmd.ParseNetwork(s)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				ips[i] = n
+				continue
+			}
+			ips[i] = mustParseIp(t, s)
+		}
+		networks := cmd.Aggregate(ips)
+		got := make([]string, len(networks))
+		for i, n := range networks {
+			got[i] = n.String()
+		}
+		if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+			t.Errorf("expected: %v, got: %v", tc.expected, got)
+		}
+	}
+}
+
+func TestAggregateCmd(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.0\n10.0.0.1\nhello\n2001:db8::1\n", "10.0.0.2/31\n2001:db8::\n")
+
+	out, err := execute(t, "aggregate", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `10.0.0.0/30
+2001:db8::/127
+`
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Next returns the address following ip. It wraps around from
+// 255.255.255.255 to 0.0.0.0.
+func (ip IPv4Address) Next() IPAddress {
+	return ip.Add(1)
+}
+
+// Prev returns the address preceding ip. It wraps around from 0.0.0.0 to
+// 255.255.255.255.
+func (ip IPv4Address) Prev() IPAddress {
+	return ip.Add(-1)
+}
+
+// Add returns the address n after ip, or -n before ip if n is negative,
+// wrapping around modulo 2^32.
+func (ip IPv4Address) Add(n int) IPAddress {
+	var r IPv4Address
+	binary.BigEndian.PutUint32(r.IP[:], binary.BigEndian.Uint32(ip.IP[:])+uint32(n))
+	return r
+}
+
+// Next returns the address following ip. It wraps around from the all-ones
+// address to ::. The zone is kept.
+func (ip IPv6Address) Next() IPAddress {
+	return ip.Add(1)
+}
+
+// Prev returns the address preceding ip. It wraps around from :: to the
+// all-ones address. The zone is kept.
+func (ip IPv6Address) Prev() IPAddress {
+	return ip.Add(-1)
+}
+
+// Add returns the address n after ip, or -n before ip if n is negative,
+// wrapping around modulo 2^128. The zone is kept.
+func (ip IPv6Address) Add(n int) IPAddress {
+	hi := binary.BigEndian.Uint64(ip.IP[:8])
+	lo := binary.BigEndian.Uint64(ip.IP[8:])
+	// extend the sign of n to 128 bits
+	var ext uint64
+	if n < 0 {
+		ext = ^uint64(0)
+	}
+	lo, carry := bits.Add64(lo, uint64(n), 0)
+	hi, _ = bits.Add64(hi, ext, carry)
+
+	r := IPv6Address{Zone: ip.Zone}
+	binary.BigEndian.PutUint64(r.IP[:8], hi)
+	binary.BigEndian.PutUint64(r.IP[8:], lo)
+	return r
+}
+
+// nextIP returns the address following ip, which is an IPv4Address or an
+// IPv6Address.
+func nextIP(ip IPAddress) IPAddress {
+	return ip.(interface{ Next() IPAddress }).Next()
+}
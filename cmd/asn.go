@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"net"
+	"slices"
+)
+
+// ASNLookup looks up the autonomous systems of IP addresses.
+type ASNLookup interface {
+	// ASN returns the autonomous system number of ip, or 0 if ip has no
+	// ASN data.
+	ASN(ip IPAddress) (uint, error)
+}
+
+// ASN returns the autonomous system number in the record of ip, which is
+// in the MaxMind DB file of ASNs, e.g. GeoLite2-ASN.mmdb.
+func (db *MaxMindDB) ASN(ip IPAddress) (uint, error) {
+	var record struct {
+		ASN uint `maxminddb:"autonomous_system_number"`
+	}
+	if err := db.r.Lookup(net.IP(ip.Bytes()), &record); err != nil {
+		return 0, err
+	}
+	return record.ASN, nil
+}
+
+// asnFilter selects the IP addresses in the autonomous systems.
+type asnFilter struct {
+	lookup ASNLookup
+	asns   []uint
+}
+
+// asn returns the autonomous system number of ip. Networks and the IP
+// addresses failed to look up have no ASN data, which is 0.
+func (f *asnFilter) asn(ip IPAddress) uint {
+	if _, ok := ip.(Network); ok {
+		return 0
+	}
+	asn, err := f.lookup.ASN(ip)
+	if err != nil {
+		return 0
+	}
+	return asn
+}
+
+// match reports whether ip is in any of the autonomous systems.
+func (f *asnFilter) match(ip IPAddress) bool {
+	return slices.Contains(f.asns, f.asn(ip))
+}
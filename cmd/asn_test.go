@@ -0,0 +1,108 @@
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// fakeASNLookup maps the IP addresses in canonical form to their ASNs.
+type fakeASNLookup map[string]uint
+
+func (l fakeASNLookup) ASN(ip cmd.IPAddress) (uint, error) {
+	if ip.String() == "10.222.0.9" {
+		return 0, errors.New("broken record")
+	}
+	return l[ip.String()], nil
+}
+
+func TestRunWithOptionsASNs(t *testing.T) {
+	lookup := fakeASNLookup{
+		"10.222.0.1":   13335,
+		"10.222.0.2":   15169,
+		"10.222.0.3":   2914,
+		"192.168.57.1": 15169,
+	}
+	input := "10.222.0.1\n10.222.0.2\n10.222.0.3\n10.222.0.4\n10.222.0.9\n172.16.0.1\n"
+
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "ASNs",
+			input:       input,
+			opts:        cmd.Options{ASNs: []uint{13335, 15169}},
+			expected:    "10.222.0.1\n10.222.0.2\n",
+		},
+		{
+			description: "Inverted",
+			input:       input,
+			opts:        cmd.Options{ASNs: []uint{13335, 15169, 2914}, Invert: true},
+			expected:    "10.222.0.4\n10.222.0.9\n172.16.0.1\n",
+		},
+		{
+			description: "Show ASN",
+			input:       input,
+			opts:        cmd.Options{ShowASN: true},
+			expected:    "10.222.0.1 AS13335\n10.222.0.2 AS15169\n10.222.0.3 AS2914\n10.222.0.4 AS0\n10.222.0.9 AS0\n",
+		},
+		{
+			description: "Show ASN with ASNs",
+			input:       input,
+			opts:        cmd.Options{ASNs: []uint{2914}, ShowASN: true, LineNumber: true},
+			expected:    "3:10.222.0.3 AS2914\n",
+		},
+		{
+			description: "Extract",
+			input:       "from 10.222.0.3 to 192.168.57.1\nfrom 10.222.0.4\n",
+			opts:        cmd.Options{ASNs: []uint{15169}, Extract: true, OnlyMatching: true, ShowASN: true},
+			expected:    "192.168.57.1 AS15169\n",
+		},
+		{
+			description: "JSON",
+			input:       input,
+			opts:        cmd.Options{ASNs: []uint{13335, 15169}, ShowASN: true, Output: "json"},
+			expected:    "{\"ip\":\"10.222.0.1\",\"version\":4,\"asn\":13335}\n{\"ip\":\"10.222.0.2\",\"version\":4,\"asn\":15169}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		tc.opts.ASNLookup = lookup
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsASNsWithoutLookup(t *testing.T) {
+	for _, opts := range []cmd.Options{{ASNs: []uint{13335}}, {ShowASN: true}} {
+		_, err := cmd.RunWithOptions(strings.NewReader("10.222.0.1\n"), &bytes.Buffer{}, &bytes.Buffer{}, samplePatterns, opts)
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	}
+}
+
+func TestRootCmdASN(t *testing.T) {
+	paths := writeFiles(t, sampleInput)
+
+	for _, args := range [][]string{{"--asn", "13335"}, {"--show-asn"}} {
+		_, err := execute(t, append(append([]string{"-e", "10.0.0.0/8"}, args...), paths[0])...)
+		if err == nil || !strings.Contains(err.Error(), "--asn-db") {
+			t.Errorf("expected an error asking for --asn-db, got: %v", err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxBloomBits is the largest number of the bits of a Bloom filter, which
+// take 1 GiB.
+const maxBloomBits = 1 << 33
+
+// bloomPageWords is the number of the words of a page of the bits. The
+// pages are allocated when their first bits are set, so that a filter sized
+// for many strings takes little memory for a few.
+const bloomPageWords = 64
+
+// bloomFilter is a set of strings in a fixed size, which may report a
+// string not added as added, but never the reverse.
+type bloomFilter struct {
+	pages []*[bloomPageWords]uint64
+	// m is the number of the bits.
+	m uint64
+	// k is the number of the bits set for each string.
+	k int
+}
+
+// newBloomFilter returns a Bloom filter sized for n strings with the false
+// positive rate fp, or an error if n or fp is out of range or the filter
+// would take more than maxBloomBits.
+func newBloomFilter(n int, fp float64) (*bloomFilter, error) {
+	if !(fp > 0 && fp < 1) {
+		return nil, fmt.Errorf("invalid false positive rate: %v", fp)
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid size: %d", n)
+	}
+	// m = -n ln(fp) / (ln 2)^2 and k = m/n ln 2 minimize the false positives
+	m := math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2))
+	if m > maxBloomBits {
+		return nil, fmt.Errorf("invalid size: %d addresses at the false positive rate %v take more than 1 GiB", n, fp)
+	}
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	words := (uint64(m) + 63) / 64
+	return &bloomFilter{
+		pages: make([]*[bloomPageWords]uint64, (words+bloomPageWords-1)/bloomPageWords),
+		m:     words * 64,
+		k:     max(k, 1),
+	}, nil
+}
+
+// add adds s and reports whether s may have been added before.
+func (f *bloomFilter) add(s string) bool {
+	// derive the k indexes from two hashes (Kirsch and Mitzenmacher), the
+	// FNV-1a hash of s and its mix
+	h1 := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h1 ^= uint64(s[i])
+		h1 *= 1099511628211
+	}
+	h2 := mix64(h1) | 1
+
+	added := true
+	for i := 0; i < f.k; i++ {
+		index := (h1 + uint64(i)*h2) % f.m
+		word, bit := index/64, uint64(1)<<(index%64)
+		page := f.pages[word/bloomPageWords]
+		if page == nil {
+			page = &[bloomPageWords]uint64{}
+			f.pages[word/bloomPageWords] = page
+		}
+		if page[word%bloomPageWords]&bit == 0 {
+			added = false
+			page[word%bloomPageWords] |= bit
+		}
+	}
+	return added
+}
+
+// mix64 is the finalizer of splitmix64, scattering the bits of x.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
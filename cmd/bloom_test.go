@@ -0,0 +1,106 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRunWithOptionsUniqueApprox(t *testing.T) {
+	// 20000 distinct addresses, each of them twice
+	const n = 20000
+	var sb strings.Builder
+	for pass := 0; pass < 2; pass++ {
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&sb, "10.%d.%d.%d\n", i>>16, i>>8&0xff, i&0xff)
+		}
+	}
+
+	for _, fp := range []float64{0.01, 0.001} {
+		fmt.Println(fp)
+		outbuf := &bytes.Buffer{}
+		opts := cmd.Options{UniqueApprox: true, UniqueFP: fp, UniqueSize: n}
+		matched, err := cmd.RunWithOptions(strings.NewReader(sb.String()), outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"}, opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// no duplicates are printed
+		lines := strings.Split(strings.TrimSuffix(outbuf.String(), "\n"), "\n")
+		seen := map[string]bool{}
+		for _, line := range lines {
+			if seen[line] {
+				t.Errorf("expected %v to be printed once", line)
+			}
+			seen[line] = true
+		}
+		if matched != len(lines) {
+			t.Errorf("expected: %v, got: %v", len(lines), matched)
+		}
+		// a few distinct addresses are dropped, allowing three times the rate
+		if dropped := n - matched; float64(dropped) > 3*fp*n {
+			t.Errorf("expected at most %v dropped addresses, got: %v", 3*fp*n, dropped)
+		}
+	}
+}
+
+func TestRunWithOptionsUniqueApproxInvalid(t *testing.T) {
+	for _, opts := range []cmd.Options{
+		{UniqueApprox: true, UniqueFP: 1},
+		{UniqueApprox: true, UniqueFP: -0.1},
+		{UniqueApprox: true, UniqueSize: -1},
+		{UniqueApprox: true, UniqueFP: math.NaN()},
+		{UniqueApprox: true, UniqueFP: 1e-300, UniqueSize: 10000000},
+		{UniqueApprox: true, UniqueSize: math.MaxInt},
+	} {
+		_, err := cmd.RunWithOptions(strings.NewReader("10.0.0.1\n"), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, opts)
+		if err == nil {
+			t.Errorf("expected an error: %+v", opts)
+		}
+	}
+}
+
+func TestRunWithOptionsUniqueApproxMemory(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := cmd.RunWithOptions(strings.NewReader("10.0.0.1\n10.0.0.2\n"), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{UniqueApprox: true})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	// the filter of the default size takes about 18 MB when it is full
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 2<<20 {
+		t.Errorf("expected at most 2 MB allocated for two addresses, got: %v", allocated)
+	}
+}
+
+func TestRootCmdUniqueApprox(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n10.0.0.2\n10.0.0.1\n::1\n0:0::1\n")
+
+	out, err := execute(t, "--unique-approx", "--unique-fp", "0.01", "--all-ips", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "10.0.0.1\n10.0.0.2\n::1\n" {
+		t.Errorf("expected: %v, got: %v", "10.0.0.1\n10.0.0.2\n::1\n", out)
+	}
+
+	for _, args := range [][]string{
+		{"--unique-fp", "0.01"},
+		{"--unique-size", "100"},
+		{"-u", "--unique-approx"},
+		{"--unique-approx", "--unique-fp", "0"},
+		{"--unique-approx", "--unique-size", "0"},
+		{"--unique-approx", "--unique-size", "1000000000000"},
+	} {
+		_, err := execute(t, append(append(args, "--all-ips"), paths[0])...)
+		if err == nil {
+			t.Errorf("expected an error: %v", args)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// SGR sequences used to highlight the matched IP addresses.
+const (
+	colorMatch = "\x1b[01;31m"
+	colorReset = "\x1b[m"
+)
+
+// useColor decides whether to colorize the output written to out.
+// mode is one of "auto", "always" and "never". Setting the NO_COLOR
+// environment variable disables colors regardless of mode.
+func useColor(mode string, out io.Writer) (bool, error) {
+	var color bool
+	switch mode {
+	case "always":
+		color = true
+	case "never":
+		color = false
+	case "auto":
+		color = isTerminal(out)
+	default:
+		return false, fmt.Errorf("invalid color mode: %s", mode)
+	}
+
+	// https://no-color.org/
+	if os.Getenv("NO_COLOR") != "" {
+		return false, nil
+	}
+	return color, nil
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in the SGR sequences.
+func colorize(s string) string {
+	return colorMatch + s + colorReset
+}
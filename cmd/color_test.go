@@ -0,0 +1,70 @@
+package cmd_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRootCmdColor(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n192.168.0.1\n")
+
+	testCases := []struct {
+		description string
+		args        []string
+		noColor     string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "Color Always",
+			args:        []string{"--color=always", "-e", "10.0.0.0/8", paths[0]},
+			expected:    "\x1b[01;31m10.0.0.1\x1b[m\n",
+		},
+		{
+			description: "Color Always with Line Number",
+			args:        []string{"--color=always", "-n", "-e", "10.0.0.0/8", paths[0]},
+			expected:    "1:\x1b[01;31m10.0.0.1\x1b[m\n",
+		},
+		{
+			description: "Color Always Inverted",
+			args:        []string{"--color=always", "-v", "-e", "10.0.0.0/8", paths[0]},
+			expected:    "192.168.0.1\n",
+		},
+		{
+			description: "Color Never",
+			args:        []string{"--color=never", "-e", "10.0.0.0/8", paths[0]},
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "Color Auto without Terminal",
+			args:        []string{"-e", "10.0.0.0/8", paths[0]},
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "Color Always with NO_COLOR",
+			args:        []string{"--color=always", "-e", "10.0.0.0/8", paths[0]},
+			noColor:     "1",
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "Invalid Color Mode",
+			args:        []string{"--color=sometimes", "-e", "10.0.0.0/8", paths[0]},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		t.Setenv("NO_COLOR", tc.noColor)
+		out, err := execute(t, tc.args...)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if tc.expectedErr {
+			continue
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %q, got: %q", tc.expected, out)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompletionCmd(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		fmt.Println(shell)
+		out, err := execute(t, "completion", shell)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !strings.Contains(out, "gipp") {
+			t.Errorf("expected the script to reference gipp, got: %v", out)
+		}
+	}
+}
+
+func TestCompleteFlags(t *testing.T) {
+	testCases := []struct {
+		description string
+		args        []string
+		expected    []string
+	}{
+		{
+			description: "Named Patterns",
+			args:        []string{"-e", ""},
+			expected:    []string{"bogon", "documentation", "link-local", "loopback", "multicast", "private", ":4"},
+		},
+		{
+			description: "Color Modes",
+			args:        []string{"--color", ""},
+			expected:    []string{"auto", "always", "never", ":4"},
+		},
+		{
+			description: "Files",
+			args:        []string{"-e", "private", ""},
+			// the directive of the default completion of files
+			expected: []string{":0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append([]string{"__complete"}, tc.args...)...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		expected := strings.Join(tc.expected, "\n") + "\n"
+		if out != expected {
+			t.Errorf("expected: %v, got: %v", expected, out)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// compression is a compressed format read transparently.
+type compression struct {
+	// magics are the headers the streams start with, any of them.
+	magics [][]byte
+	// ext is the extension of the file names.
+	ext string
+	// newReader returns a reader of the decompressed content of r.
+	newReader func(r io.Reader) (io.Reader, error)
+}
+
+// compressions are the compressed formats detected by decompress.
+var compressions = []compression{
+	{
+		magics: [][]byte{{0x1f, 0x8b}},
+		ext:    ".gz",
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		magics: bzip2Magics(),
+		ext:    ".bz2",
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+	{
+		magics: [][]byte{{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+		ext:    ".xz",
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return xz.NewReader(r)
+		},
+	},
+	{
+		magics: [][]byte{{0x28, 0xb5, 0x2f, 0xfd}},
+		ext:    ".zst",
+		newReader: func(r io.Reader) (io.Reader, error) {
+			// decode in the calling goroutine so that nothing is left to close
+			return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		},
+	},
+}
+
+// bzip2Magics returns the headers of the bzip2 streams: "BZh", the block
+// size from '1' to '9', and the magic of the first block, or of the end of
+// the stream if it is empty. "BZh" alone is too likely at the start of text.
+func bzip2Magics() [][]byte {
+	var magics [][]byte
+	for size := byte('1'); size <= '9'; size++ {
+		for _, block := range [][]byte{
+			{0x31, 0x41, 0x59, 0x26, 0x53, 0x59},
+			{0x17, 0x72, 0x45, 0x38, 0x50, 0x90},
+		} {
+			magics = append(magics, append([]byte{'B', 'Z', 'h', size}, block...))
+		}
+	}
+	return magics
+}
+
+// decompress returns a reader of the decompressed content of in if in is
+// compressed, or of in itself otherwise. The content is detected by its
+// magic bytes, so compressed standard input is also read. A file named
+// with the extension of a format, e.g. ".gz", is always read in the format
+// so that a broken one is reported.
+//
+// Only the bytes available by the first read are sniffed, and more are
+// awaited only while they can still be magic bytes, so that a short line
+// written to a pipe is not held back until the next one arrives.
+func decompress(in io.Reader, filename string) (io.Reader, error) {
+	br := bufio.NewReader(in)
+	// fill the buffer by a read without waiting for more bytes
+	br.Peek(1)
+	for _, c := range compressions {
+		if !c.sniff(br) && !strings.HasSuffix(filename, c.ext) {
+			continue
+		}
+		zr, err := c.newReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	}
+	return br, nil
+}
+
+// sniff reports whether br starts with any of the magic bytes of c.
+func (c compression) sniff(br *bufio.Reader) bool {
+	for _, magic := range c.magics {
+		head, _ := br.Peek(min(br.Buffered(), len(magic)))
+		if len(head) > 0 && len(head) < len(magic) && bytes.HasPrefix(magic, head) {
+			head, _ = br.Peek(len(magic))
+		}
+		if bytes.Equal(head, magic) {
+			return true
+		}
+	}
+	return false
+}
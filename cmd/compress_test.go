@@ -0,0 +1,291 @@
+package cmd_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/kusshi94/gipp/cmd"
+	"github.com/ulikunitz/xz"
+)
+
+// gzipString returns the gzip compressed content.
+func gzipString(t *testing.T, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRootCmdGzip(t *testing.T) {
+	compressed := gzipString(t, sampleInput)
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "input.txt")
+	named := filepath.Join(dir, "input.txt.gz")
+	unnamed := filepath.Join(dir, "input.dump")
+	for path, content := range map[string][]byte{plain: []byte(sampleInput), named: compressed, unnamed: compressed} {
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args := []string{"-e", strings.Join(samplePatterns, ",")}
+
+	expected, err := execute(t, append(args, plain)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		file        string
+	}{
+		{description: "Gzip File", file: named},
+		{description: "Gzip File without Extension", file: unnamed},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append(args, tc.file)...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != expected {
+			t.Errorf("expected: %v, got: %v", expected, out)
+		}
+	}
+}
+
+func TestRunWithOptionsGzipStdin(t *testing.T) {
+	expected := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(sampleInput), expected, &bytes.Buffer{}, samplePatterns, cmd.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	outbuf := &bytes.Buffer{}
+	_, err = cmd.RunWithOptions(bytes.NewReader(gzipString(t, sampleInput)), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if outbuf.String() != expected.String() {
+		t.Errorf("expected: %v, got: %v", expected.String(), outbuf.String())
+	}
+}
+
+func TestRootCmdGzipCorrupt(t *testing.T) {
+	compressed := gzipString(t, sampleInput)
+	// break the checksum in the trailer
+	broken := append([]byte{}, compressed...)
+	broken[len(broken)-8] ^= 0xff
+
+	dir := t.TempDir()
+	testCases := []struct {
+		description string
+		content     []byte
+		expectedErr error
+	}{
+		{description: "Not Gzip", content: []byte(sampleInput), expectedErr: gzip.ErrHeader},
+		{description: "Invalid Checksum", content: broken, expectedErr: gzip.ErrChecksum},
+	}
+
+	for i, tc := range testCases {
+		fmt.Println(tc.description)
+		path := filepath.Join(dir, fmt.Sprintf("input%d.gz", i+1))
+		if err := os.WriteFile(path, tc.content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := execute(t, "-e", "10.0.0.0/8", path)
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("expected: %v, got: %v", tc.expectedErr, err)
+		}
+		if err != nil && !strings.HasPrefix(err.Error(), path+": ") {
+			t.Errorf("expected the error to name %v, got: %v", path, err)
+		}
+	}
+}
+
+// compressedSamples returns sampleInput compressed in each format other than
+// gzip by the extension. The standard library has no bzip2 writer, so the
+// bzip2 one is read from testdata.
+func compressedSamples(t *testing.T) map[string][]byte {
+	t.Helper()
+	bz, err := os.ReadFile(filepath.Join("testdata", "sample.txt.bz2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xzbuf := &bytes.Buffer{}
+	xw, err := xz.NewWriter(xzbuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xw.Write([]byte(sampleInput)); err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst := zw.EncodeAll([]byte(sampleInput), nil)
+	zw.Close()
+
+	return map[string][]byte{".bz2": bz, ".xz": xzbuf.Bytes(), ".zst": zst}
+}
+
+func TestRootCmdCompressed(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(plain, []byte(sampleInput), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"-e", strings.Join(samplePatterns, ",")}
+
+	expected, err := execute(t, append(args, plain)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for ext, compressed := range compressedSamples(t) {
+		named := filepath.Join(dir, "input.txt"+ext)
+		unnamed := filepath.Join(dir, "input"+ext[1:]+".dump")
+		for _, path := range []string{named, unnamed} {
+			fmt.Println(filepath.Base(path))
+			if err := os.WriteFile(path, compressed, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := execute(t, append(args, path)...)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if out != expected {
+				t.Errorf("expected: %v, got: %v", expected, out)
+			}
+		}
+	}
+}
+
+func TestRunWithOptionsCompressedStdin(t *testing.T) {
+	expected := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(sampleInput), expected, &bytes.Buffer{}, samplePatterns, cmd.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for ext, compressed := range compressedSamples(t) {
+		fmt.Println(ext)
+		outbuf := &bytes.Buffer{}
+		_, err = cmd.RunWithOptions(bytes.NewReader(compressed), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != expected.String() {
+			t.Errorf("expected: %v, got: %v", expected.String(), outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdCompressedCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	for _, ext := range []string{".bz2", ".xz", ".zst"} {
+		fmt.Println(ext)
+		path := filepath.Join(dir, "input.txt"+ext)
+		if err := os.WriteFile(path, []byte(sampleInput), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := execute(t, "-e", "10.0.0.0/8", path)
+		if err == nil || !strings.HasPrefix(err.Error(), path+": ") {
+			t.Errorf("expected an error naming %v, got: %v", path, err)
+		}
+	}
+}
+
+func TestRunWithOptionsBZhText(t *testing.T) {
+	// the text starting like bzip2 without its block magic
+	input := "BZh9 is not bzip2\n10.0.0.1\n"
+	outbuf := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := "10.0.0.1\n"; outbuf.String() != expected {
+		t.Errorf("expected: %v, got: %v", expected, outbuf.String())
+	}
+}
+
+func TestRunWithOptionsCompressedStdinOneByte(t *testing.T) {
+	expected := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(sampleInput), expected, &bytes.Buffer{}, samplePatterns, cmd.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	samples := compressedSamples(t)
+	samples[".gz"] = gzipString(t, sampleInput)
+	for ext, compressed := range samples {
+		fmt.Println(ext)
+		// the magic bytes arrive by a read each
+		outbuf := &bytes.Buffer{}
+		_, err = cmd.RunWithOptions(iotest.OneByteReader(bytes.NewReader(compressed)), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != expected.String() {
+			t.Errorf("expected: %v, got: %v", expected.String(), outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsPipeShortLine(t *testing.T) {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := cmd.RunWithOptions(inr, outw, &bytes.Buffer{}, []string{"::1"}, cmd.Options{LineBuffered: true})
+		outw.Close()
+		done <- err
+	}()
+
+	// the line shorter than the magic bytes is printed before the input ends
+	if _, err := inw.Write([]byte("::1\n")); err != nil {
+		t.Fatal(err)
+	}
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(outr).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		if expected := "::1\n"; line != expected {
+			t.Errorf("expected: %v, got: %v", expected, line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the line to be printed before the input ends")
+	}
+
+	inw.Close()
+	go io.Copy(io.Discard, outr)
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import "net"
+
+// MACFromEUI64 returns the MAC address embedded in the interface ID of ip
+// by the modified EUI-64 format of SLAAC, e.g. 00:12:34:56:78:90 of
+// fe80::212:34ff:fe56:7890, or false if the interface ID has no "ff:fe" in
+// the middle.
+func MACFromEUI64(ip IPv6Address) ([6]byte, bool) {
+	id := ip.IP[8:]
+	if id[3] != 0xff || id[4] != 0xfe {
+		return [6]byte{}, false
+	}
+	// the universal/local bit is inverted in the interface ID
+	return [6]byte{id[0] ^ 0x02, id[1], id[2], id[5], id[6], id[7]}, true
+}
+
+// MACToEUI64 returns the IPv6 address of the prefix whose interface ID is
+// generated from the MAC address by the modified EUI-64 format of SLAAC,
+// e.g. fe80::212:34ff:fe56:7890 of 00:12:34:56:78:90 in fe80::/64. The
+// lower 64 bits of the prefix are replaced. It is the inverse of
+// MACFromEUI64.
+func MACToEUI64(mac [6]byte, prefix IPv6Address) IPv6Address {
+	ip := prefix
+	ip.IP[8], ip.IP[9], ip.IP[10] = mac[0]^0x02, mac[1], mac[2]
+	ip.IP[11], ip.IP[12] = 0xff, 0xfe
+	ip.IP[13], ip.IP[14], ip.IP[15] = mac[3], mac[4], mac[5]
+	return ip
+}
+
+// macText returns the MAC address embedded in ip to be appended for
+// Options.ShowMAC, e.g. " mac=00:12:34:56:78:90", or an empty string if ip
+// is not an IPv6 address of the EUI-64 format.
+func macText(ip IPAddress) string {
+	v6, ok := ip.(IPv6Address)
+	if !ok {
+		return ""
+	}
+	mac, ok := MACFromEUI64(v6)
+	if !ok {
+		return ""
+	}
+	return " mac=" + net.HardwareAddr(mac[:]).String()
+}
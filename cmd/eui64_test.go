@@ -0,0 +1,229 @@
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestMACFromEUI64(t *testing.T) {
+	testCases := []struct {
+		description string
+		ip          string
+		expectedMAC [6]byte
+		expectedOk  bool
+	}{
+		{
+			description: "Link Local",
+			ip:          "fe80::0212:34ff:fe56:7890",
+			expectedMAC: [6]byte{0x00, 0x12, 0x34, 0x56, 0x78, 0x90},
+			expectedOk:  true,
+		},
+		{
+			description: "Global",
+			ip:          "2001:db8:1:2:a8bb:ccff:fedd:eeff",
+			expectedMAC: [6]byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+			expectedOk:  true,
+		},
+		{
+			description: "Local MAC",
+			ip:          "fe80::12:34ff:fe56:7890",
+			expectedMAC: [6]byte{0x02, 0x12, 0x34, 0x56, 0x78, 0x90},
+			expectedOk:  true,
+		},
+		{
+			description: "Zone",
+			ip:          "fe80::212:34ff:fe56:7890%eth0",
+			expectedMAC: [6]byte{0x00, 0x12, 0x34, 0x56, 0x78, 0x90},
+			expectedOk:  true,
+		},
+		{
+			description: "Random Interface ID",
+			ip:          "2001:db8::1234:5678:9abc:def0",
+			expectedOk:  false,
+		},
+		{
+			description: "ff:fe Elsewhere",
+			ip:          "2001:db8::ff:fe00:0:1",
+			expectedOk:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		mac, ok := cmd.MACFromEUI64(mustParseIp(t, tc.ip).(cmd.IPv6Address))
+		if ok != tc.expectedOk {
+			t.Errorf("expected: %v, got: %v", tc.expectedOk, ok)
+		}
+		if mac != tc.expectedMAC {
+			t.Errorf("expected: %v, got: %v", tc.expectedMAC, mac)
+		}
+	}
+}
+
+func TestMACToEUI64(t *testing.T) {
+	mac := [6]byte{0x00, 0x12, 0x34, 0x56, 0x78, 0x90}
+	testCases := []struct {
+		description string
+		prefix      string
+		expected    string
+	}{
+		{description: "Link Local", prefix: "fe80::", expected: "fe80::212:34ff:fe56:7890"},
+		{description: "Global", prefix: "2001:db8:1:2::", expected: "2001:db8:1:2:212:34ff:fe56:7890"},
+		{description: "Interface ID Replaced", prefix: "2001:db8::1", expected: "2001:db8::212:34ff:fe56:7890"},
+		{description: "Zone", prefix: "fe80::%eth0", expected: "fe80::212:34ff:fe56:7890%eth0"},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ip := cmd.MACToEUI64(mac, mustParseIp(t, tc.prefix).(cmd.IPv6Address))
+		if ip.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, ip)
+		}
+		// the MAC address round trips
+		if got, ok := cmd.MACFromEUI64(ip); !ok || got != mac {
+			t.Errorf("expected: %v, got: %v", mac, got)
+		}
+	}
+}
+
+func TestMACPatternMatch(t *testing.T) {
+	generated := cmd.MACToEUI64([6]byte{0x00, 0x12, 0x34, 0x56, 0x78, 0x90}, mustParseIp(t, "2001:db8:abcd:1::").(cmd.IPv6Address))
+
+	testCases := []struct {
+		description string
+		pattern     string
+		ip          string
+		expected    bool
+	}{
+		{description: "Generated", pattern: "mac:00:12:34:56:78:90/-64", ip: generated.String(), expected: true},
+		{description: "Link Local", pattern: "mac:00:12:34:56:78:90/-64", ip: "fe80::212:34ff:fe56:7890", expected: true},
+		{description: "Zone", pattern: "mac:00:12:34:56:78:90/-64", ip: "fe80::212:34ff:fe56:7890%eth0", expected: true},
+		{description: "Without Mask", pattern: "mac:00:12:34:56:78:90", ip: "2001:db8::212:34ff:fe56:7890", expected: true},
+		{description: "Hyphens", pattern: "mac:00-12-34-56-78-90/-64", ip: "fe80::212:34ff:fe56:7890", expected: true},
+		{description: "Upper Case", pattern: "mac:AA:BB:CC:DD:EE:FF/-64", ip: "fe80::a8bb:ccff:fedd:eeff", expected: true},
+		{description: "Other MAC", pattern: "mac:00:12:34:56:78:91/-64", ip: "fe80::212:34ff:fe56:7890", expected: false},
+		{description: "U/L Bit Not Inverted", pattern: "mac:00:12:34:56:78:90/-64", ip: "fe80::12:34ff:fe56:7890", expected: false},
+		{description: "Shorter Suffix", pattern: "mac:00:12:34:56:78:90/-24", ip: "fe80::1:fe56:7890", expected: true},
+		{description: "IPv4", pattern: "mac:00:12:34:56:78:90/-64", ip: "10.0.0.1", expected: false},
+		{description: "Negated", pattern: "!mac:00:12:34:56:78:90/-64", ip: "fe80::1", expected: true},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		pattern, err := cmd.ParsePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("parse pattern: unexpected error: %v", err)
+			continue
+		}
+		ip, err := cmd.ParseIp(tc.ip)
+		if err != nil {
+			t.Errorf("parse ip: unexpected error: %v", err)
+			continue
+		}
+		if pattern.Match(ip) != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, pattern.Match(ip))
+		}
+	}
+}
+
+func TestParseMACPatternInvalid(t *testing.T) {
+	testCases := []struct {
+		description string
+		pattern     string
+	}{
+		{description: "Empty", pattern: "mac:"},
+		{description: "Short MAC", pattern: "mac:00:12:34:56:78/-64"},
+		{description: "Long MAC", pattern: "mac:00:12:34:56:78:90:ab:cd/-64"},
+		{description: "EUI-64", pattern: "mac:00:12:34:ff:fe:56:78:90/-64"},
+		{description: "Not Hex", pattern: "mac:00:12:34:56:78:zz/-64"},
+		{description: "IPv6 Address", pattern: "mac:2001:db8::1/-64"},
+		{description: "Prefix", pattern: "mac:00:12:34:56:78:90/64"},
+		{description: "Longer Suffix", pattern: "mac:00:12:34:56:78:90/-72"},
+		{description: "Invalid Mask", pattern: "mac:00:12:34:56:78:90/-x"},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		_, err := cmd.ParsePattern(tc.pattern)
+		if !errors.Is(err, cmd.ErrInvalidPattern) {
+			t.Errorf("expected: %v, got: %v", cmd.ErrInvalidPattern, err)
+		}
+	}
+}
+
+func TestRootCmdMACPattern(t *testing.T) {
+	paths := writeFiles(t, "fe80::212:34ff:fe56:7890\n2001:db8::212:34ff:fe56:7890\n2001:db8::1\n")
+
+	out, err := execute(t, "-e", "mac:00:12:34:56:78:90/-64", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "fe80::212:34ff:fe56:7890\n2001:db8::212:34ff:fe56:7890\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
+
+func TestRunWithOptionsShowMAC(t *testing.T) {
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Lines",
+			input: `fe80::0212:34ff:fe56:7890
+fe80::1
+10.0.0.1
+2001:db8::a8bb:ccff:fedd:eeff`,
+			expected: `fe80::0212:34ff:fe56:7890 mac=00:12:34:56:78:90
+fe80::1
+10.0.0.1
+2001:db8::a8bb:ccff:fedd:eeff mac=aa:bb:cc:dd:ee:ff
+`,
+		},
+		{
+			description: "Only Matching",
+			opts:        cmd.Options{Extract: true, OnlyMatching: true},
+			input:       "NS from fe80::212:34ff:fe56:7890 to ff02::1\n",
+			expected:    "fe80::212:34ff:fe56:7890 mac=00:12:34:56:78:90\nff02::1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		tc.opts.ShowMAC = true
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, []string{"::/0", "0.0.0.0/0"}, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdMAC(t *testing.T) {
+	paths := writeFiles(t, "fe80::212:34ff:fe56:7890\n")
+
+	out, err := execute(t, "--mac", "-e", "fe80::/10", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "fe80::212:34ff:fe56:7890 mac=00:12:34:56:78:90\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	_, err = execute(t, "--mac", "--output", "json", "-e", "fe80::/10", paths[0])
+	if err == nil {
+		t.Errorf("expected an error of --mac with --output")
+	}
+}
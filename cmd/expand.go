@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultExpandLimit is the default maximum number of addresses expanded
+// from a prefix.
+const defaultExpandLimit = 1 << 16
+
+func newExpandCmd() *cobra.Command {
+	var limit uint64
+
+	cmd := &cobra.Command{
+		Use:   "expand [flags] prefix ...",
+		Short: "Print every address of the prefixes",
+		Long: `The expand command prints every address of the given prefixes in canonical form.
+Prefixes with more addresses than --limit are refused unless --limit is 0.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// check all the prefixes before printing anything
+			networks := make([]Network, len(args))
+			for i, arg := range args {
+				pattern, err := ParsePattern(arg)
+				if err != nil {
+					return &PatternError{Index: i, Pattern: arg, Err: err}
+				}
+				n, ok := prefixNetwork(pattern)
+				if !ok {
+					return fmt.Errorf("not a prefix: %s", arg)
+				}
+				hostBits := len(n.Bytes())*8 - n.Bits
+				if limit > 0 && (hostBits >= 64 || uint64(1)<<hostBits > limit) {
+					return fmt.Errorf("too many addresses: %s has 2^%d addresses, more than --limit %d", arg, hostBits, limit)
+				}
+				networks[i] = n
+			}
+
+			w := bufio.NewWriter(cmd.OutOrStdout())
+			defer w.Flush()
+			for _, n := range networks {
+				expand(w, n)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Uint64Var(&limit, "limit", defaultExpandLimit, "maximum number of addresses of a prefix, or 0 for no limit")
+
+	return cmd
+}
+
+// expand writes the addresses of the network one per line.
+func expand(w *bufio.Writer, n Network) {
+	last := n.Last()
+	ip := n.First()
+	for {
+		fmt.Fprintln(w, ip)
+		if ip.Equal(last) {
+			return
+		}
+		ip = nextIP(ip)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpandCmd(t *testing.T) {
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "IPv4 Prefix",
+			args:        []string{"192.168.1.0/30"},
+			expected: `192.168.1.0
+192.168.1.1
+192.168.1.2
+192.168.1.3
+`,
+		},
+		{
+			description: "IPv4 Prefix across Octets",
+			args:        []string{"10.0.0.255/31", "10.0.1.0/32"},
+			expected: `10.0.0.254
+10.0.0.255
+10.0.1.0
+`,
+		},
+		{
+			description: "IPv6 Prefix",
+			args:        []string{"2001:db8::fffe/126"},
+			expected: `2001:db8::fffc
+2001:db8::fffd
+2001:db8::fffe
+2001:db8::ffff
+`,
+		},
+		{
+			description: "Top of IPv6 Space",
+			args:        []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127"},
+			expected: `ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe
+ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff
+`,
+		},
+		{
+			description: "Within Limit",
+			args:        []string{"--limit", "4", "10.0.0.0/30"},
+			expected: `10.0.0.0
+10.0.0.1
+10.0.0.2
+10.0.0.3
+`,
+		},
+		{
+			description: "Over Limit",
+			args:        []string{"--limit", "3", "10.0.0.0/30"},
+			expectedErr: true,
+		},
+		{
+			description: "Over Default Limit",
+			args:        []string{"10.0.0.0/8"},
+			expectedErr: true,
+		},
+		{
+			description: "Huge IPv6 Prefix",
+			args:        []string{"2001:db8::/32"},
+			expectedErr: true,
+		},
+		{
+			description: "Suffix",
+			args:        []string{"0.0.0.1/-8"},
+			expectedErr: true,
+		},
+		{
+			description: "Invalid Prefix",
+			args:        []string{"10.0.0.0/33"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append([]string{"expand"}, tc.args...)...)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
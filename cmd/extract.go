@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ipSpan is an IP address embedded in a line.
+type ipSpan struct {
+	// start and end are the byte offsets of the address in the line.
+	start, end int
+	ip         IPAddress
+	// matched reports whether the address matches the patterns.
+	matched bool
+	// pattern is the first pattern the address matches with ShowPattern.
+	pattern string
+}
+
+// isIPByte reports whether c can be a part of an IP address.
+func isIPByte(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F' || c == '.' || c == ':'
+}
+
+// extractIPs returns the IP addresses embedded in the line in order.
+// The candidates are the runs of hex digits, dots and colons, so an address
+// must be separated from the surrounding text by other characters.
+func extractIPs(line []byte) []ipSpan {
+	var spans []ipSpan
+	for i := 0; i < len(line); {
+		if !isIPByte(line[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(line) && isIPByte(line[j]) {
+			j++
+		}
+		if span, ok := findIP(line, i, j); ok {
+			spans = append(spans, span)
+		}
+		i = j
+	}
+	return spans
+}
+
+// findIP finds an IP address in the candidate line[start:end].
+func findIP(line []byte, start, end int) (ipSpan, bool) {
+	// trim the dots around the address, e.g. at the end of a sentence
+	for start < end && line[start] == '.' {
+		start++
+	}
+	for start < end && line[end-1] == '.' {
+		end--
+	}
+	if ip, err := parseIPBytes(line[start:end]); err == nil {
+		return ipSpan{start: start, end: end, ip: ip}, true
+	}
+
+	// an IPv4 address followed by a port or a colon, e.g. "10.0.0.1:8080"
+	if i := bytes.IndexByte(line[start:end], ':'); i > 0 {
+		if ip, err := parseIPv4Bytes(line[start : start+i]); err == nil {
+			return ipSpan{start: start, end: start + i, ip: ip}, true
+		}
+	}
+	return ipSpan{}, false
+}
+
+// rewriteSpans returns the line with the embedded IP addresses in the format
+// of Options.Format if normalize is set, and the matched ones highlighted if
+// color is set.
+func rewriteSpans(line []byte, spans []ipSpan, format string, normalize, color bool) string {
+	var sb strings.Builder
+	last := 0
+	for _, span := range spans {
+		sb.Write(line[last:span.start])
+		text := string(line[span.start:span.end])
+		if normalize {
+			text = formatIP(span.ip, format)
+		}
+		if color && span.matched {
+			text = colorize(text)
+		}
+		sb.WriteString(text)
+		last = span.end
+	}
+	sb.Write(line[last:])
+	return sb.String()
+}
@@ -0,0 +1,194 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+const sampleLog = `10.0.0.5 - - [16/Oct/2023:10:00:00 +0900] "GET / HTTP/1.1" 200 512
+192.168.0.7 - - [16/Oct/2023:10:00:01 +0900] "GET /login HTTP/1.1" 302 0
+sshd[1234]: Failed password for root from 203.0.113.9 port 22 ssh2
+sshd[1234]: Accepted publickey for deploy from 10.222.0.1 port 51022 ssh2
+proxy: 192.168.0.7:443 -> [2001:db8::1]:8443
+proxy: 192.168.0.8:443 -> [2001:0db8::0002]:8443
+cron: job finished at 12:30:00
+connection from 10.0.0.256 refused.
+client 10.1.2.3.`
+
+func TestRunWithOptionsExtract(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Extract",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Extract: true},
+			expected: `10.0.0.5 - - [16/Oct/2023:10:00:00 +0900] "GET / HTTP/1.1" 200 512
+sshd[1234]: Accepted publickey for deploy from 10.222.0.1 port 51022 ssh2
+client 10.1.2.3.
+`,
+		},
+		{
+			description: "Extract Second Address",
+			patterns:    []string{"2001:db8::/32"},
+			opts:        cmd.Options{Extract: true, LineNumber: true},
+			expected: `5:proxy: 192.168.0.7:443 -> [2001:db8::1]:8443
+6:proxy: 192.168.0.8:443 -> [2001:0db8::0002]:8443
+`,
+		},
+		{
+			description: "Extract Inverted",
+			patterns:    []string{"10.0.0.0/8", "2001:db8::/32"},
+			opts:        cmd.Options{Extract: true, Invert: true},
+			expected: `192.168.0.7 - - [16/Oct/2023:10:00:01 +0900] "GET /login HTTP/1.1" 302 0
+sshd[1234]: Failed password for root from 203.0.113.9 port 22 ssh2
+`,
+		},
+		{
+			description: "Extract Unique",
+			patterns:    []string{"192.168.0.0/16"},
+			opts:        cmd.Options{Extract: true, Unique: true},
+			expected: `192.168.0.7 - - [16/Oct/2023:10:00:01 +0900] "GET /login HTTP/1.1" 302 0
+proxy: 192.168.0.8:443 -> [2001:0db8::0002]:8443
+`,
+		},
+		{
+			description: "Extract Normalize",
+			patterns:    []string{"192.168.0.8"},
+			opts:        cmd.Options{Extract: true, Normalize: true},
+			expected: `proxy: 192.168.0.8:443 -> [2001:db8::2]:8443
+`,
+		},
+		{
+			description: "Extract Color",
+			patterns:    []string{"2001:db8::/32"},
+			opts:        cmd.Options{Extract: true, Color: true},
+			expected: "proxy: 192.168.0.7:443 -> [\x1b[01;31m2001:db8::1\x1b[m]:8443\n" +
+				"proxy: 192.168.0.8:443 -> [\x1b[01;31m2001:0db8::0002\x1b[m]:8443\n",
+		},
+		{
+			description: "Extract Show Pattern",
+			patterns:    []string{"203.0.113.0/24", "10.222.0.0/16"},
+			opts:        cmd.Options{Extract: true, ShowPattern: true},
+			expected: `sshd[1234]: Failed password for root from 203.0.113.9 port 22 ssh2 [203.0.113.0/24]
+sshd[1234]: Accepted publickey for deploy from 10.222.0.1 port 51022 ssh2 [10.222.0.0/16]
+`,
+		},
+		{
+			description: "Extract Count",
+			patterns:    []string{"192.168.0.0/16"},
+			opts:        cmd.Options{Extract: true, Count: true},
+			expected:    "3\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(sampleLog), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsOnlyMatching(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Whole Line",
+			input:       "10.0.0.1  \n\t2001:0db8::0001\n192.168.0.1",
+			patterns:    []string{"10.0.0.0/8", "2001:db8::/32"},
+			opts:        cmd.Options{OnlyMatching: true},
+			expected: `10.0.0.1
+2001:db8::1
+`,
+		},
+		{
+			description: "Whole Line Inverted",
+			input:       "10.0.0.1  \n\t2001:0db8::0001\n192.168.0.1",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{OnlyMatching: true, Invert: true, LineNumber: true},
+			expected: `2:2001:db8::1
+3:192.168.0.1
+`,
+		},
+		{
+			description: "Embedded",
+			input:       sampleLog,
+			patterns:    []string{"192.168.0.0/16", "2001:db8::/32"},
+			opts:        cmd.Options{OnlyMatching: true, Extract: true, LineNumber: true},
+			expected: `2:192.168.0.7
+5:192.168.0.7
+5:2001:db8::1
+6:192.168.0.8
+6:2001:db8::2
+`,
+		},
+		{
+			description: "Embedded with Show Pattern",
+			input:       sampleLog,
+			patterns:    []string{"192.168.0.0/16", "2001:db8::/32"},
+			opts:        cmd.Options{OnlyMatching: true, Extract: true, ShowPattern: true},
+			expected: `192.168.0.7 [192.168.0.0/16]
+192.168.0.7 [192.168.0.0/16]
+2001:db8::1 [2001:db8::/32]
+192.168.0.8 [192.168.0.0/16]
+2001:db8::2 [2001:db8::/32]
+`,
+		},
+		{
+			description: "Embedded Sorted",
+			input:       sampleLog,
+			patterns:    []string{"192.168.0.0/16", "2001:db8::/32"},
+			opts:        cmd.Options{OnlyMatching: true, Extract: true, Sort: true},
+			expected: `192.168.0.7
+192.168.0.7
+192.168.0.8
+2001:db8::1
+2001:db8::2
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsExtractVersion(t *testing.T) {
+	outbuf := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(sampleLog), outbuf, &bytes.Buffer{}, nil, cmd.Options{Extract: true, OnlyMatching: true, Version: 6})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `2001:db8::1
+2001:db8::2
+`
+	if outbuf.String() != expected {
+		t.Errorf("expected: %v, got: %v", expected, outbuf.String())
+	}
+}
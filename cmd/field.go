@@ -0,0 +1,48 @@
+package cmd
+
+import "bytes"
+
+// fieldSpan returns the byte offsets of the nth field of the line, counted
+// from 1, without the spaces around it. The fields are separated by delim,
+// or by runs of whitespace if delim is empty. The third result is false if
+// the line has fewer fields.
+func fieldSpan(line []byte, n int, delim string) (start, end int, ok bool) {
+	if delim == "" {
+		for i := 0; ; i++ {
+			for start < len(line) && isSpace(line[start]) {
+				start++
+			}
+			if start == len(line) {
+				return 0, 0, false
+			}
+			end = start
+			for end < len(line) && !isSpace(line[end]) {
+				end++
+			}
+			if i == n-1 {
+				return start, end, true
+			}
+			start = end
+		}
+	}
+
+	for i := 0; i < n-1; i++ {
+		j := bytes.Index(line[start:], []byte(delim))
+		if j < 0 {
+			return 0, 0, false
+		}
+		start += j + len(delim)
+	}
+	end = len(line)
+	if j := bytes.Index(line[start:], []byte(delim)); j >= 0 {
+		end = start + j
+	}
+	// the spaces after the delimiter, e.g. "a, b"
+	for start < end && isSpace(line[start]) {
+		start++
+	}
+	for start < end && isSpace(line[end-1]) {
+		end--
+	}
+	return start, end, true
+}
@@ -0,0 +1,123 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRunWithOptionsField(t *testing.T) {
+	csv := `id,name,ip,note
+1,db,10.0.0.10,primary
+2,web,192.168.1.5,"10.0.0.1"
+3,cache, 10.0.0.11 ,
+4,short
+`
+	tsv := "host\tsrc\tdst\n" +
+		"a\t10.0.0.1\t192.168.0.1\n" +
+		"b\t192.168.0.2\t10.0.0.2\n" +
+		"c\t\t10.0.0.3\n"
+	log := "2024-01-01 10:00:00   10.0.0.5 GET /\n2024-01-01 10:00:01\t192.168.0.9 GET /\n"
+
+	testCases := []struct {
+		description  string
+		input        string
+		opts         cmd.Options
+		expected     string
+		expectedEout string
+	}{
+		{
+			description:  "Comma",
+			input:        csv,
+			opts:         cmd.Options{Field: 3, Delimiter: ","},
+			expected:     "1,db,10.0.0.10,primary\n3,cache, 10.0.0.11 ,\n",
+			expectedEout: "(standard input): 2 lines skipped: not IP addresses\n",
+		},
+		{
+			description: "Comma Other Column",
+			input:       csv,
+			opts:        cmd.Options{Field: 4, Delimiter: ","},
+			expected:    "",
+		},
+		{
+			description: "Tab Source",
+			input:       tsv,
+			opts:        cmd.Options{Field: 2, Delimiter: "\t"},
+			expected:    "a\t10.0.0.1\t192.168.0.1\n",
+		},
+		{
+			description: "Tab Destination",
+			input:       tsv,
+			opts:        cmd.Options{Field: 3, Delimiter: "\t", LineNumber: true},
+			expected:    "3:b\t192.168.0.2\t10.0.0.2\n4:c\t\t10.0.0.3\n",
+		},
+		{
+			description: "Whitespace",
+			input:       log,
+			opts:        cmd.Options{Field: 3},
+			expected:    "2024-01-01 10:00:00   10.0.0.5 GET /\n",
+		},
+		{
+			description: "Only Matching",
+			input:       csv,
+			opts:        cmd.Options{Field: 3, Delimiter: ",", OnlyMatching: true},
+			expected:    "10.0.0.10\n10.0.0.11\n",
+		},
+		{
+			description:  "Strict",
+			input:        csv,
+			opts:         cmd.Options{Field: 3, Delimiter: ",", Strict: true},
+			expected:     "1,db,10.0.0.10,primary\n3,cache, 10.0.0.11 ,\n",
+			expectedEout: "(standard input):1: invalid ip: id,name,ip,note\n(standard input):5: invalid ip: 4,short\n",
+		},
+		{
+			description: "Inverted",
+			input:       tsv,
+			opts:        cmd.Options{Field: 2, Delimiter: "\t", Invert: true},
+			expected:    "b\t192.168.0.2\t10.0.0.2\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, eoutbuf, []string{"10.0.0.0/8"}, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		if tc.expectedEout != "" && eoutbuf.String() != tc.expectedEout {
+			t.Errorf("expected: %v, got: %v", tc.expectedEout, eoutbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsInvalidField(t *testing.T) {
+	_, err := cmd.RunWithOptions(strings.NewReader("10.0.0.1\n"), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{Field: -1})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestRootCmdField(t *testing.T) {
+	paths := writeFiles(t, "a;10.0.0.1\nb;192.168.0.1\n")
+
+	out, err := execute(t, "--field", "2", "--delimiter", ";", "-e", "10.0.0.0/8", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "a;10.0.0.1\n" {
+		t.Errorf("expected: %v, got: %v", "a;10.0.0.1\n", out)
+	}
+
+	_, err = execute(t, "--delimiter", ";", "-e", "10.0.0.0/8", paths[0])
+	if err == nil || !strings.Contains(err.Error(), "--field") {
+		t.Errorf("expected an error of --field, got: %v", err)
+	}
+}
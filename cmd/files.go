@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// fileWalker collects the input files from the command line arguments.
+type fileWalker struct {
+	// Recursive walks directory arguments instead of rejecting them.
+	Recursive bool
+	// FollowSymlinks follows symbolic links found while walking directories.
+	// It implies Recursive. Symbolic links given as arguments are always followed.
+	FollowSymlinks bool
+	// Includes selects only the files whose base names match any of the glob
+	// patterns while walking directories. Empty selects all the files.
+	Includes []string
+	// Excludes skips the files whose base names match any of the glob
+	// patterns while walking directories, even if they match Includes.
+	Excludes []string
+
+	// visited holds the real paths of the walked directories to avoid loops.
+	visited map[string]bool
+}
+
+// Collect returns the regular files named by args, walking directories in
+// lexical order. "-" is returned as it is for the standard input. The
+// second result reports whether any directory was walked.
+func (w *fileWalker) Collect(args []string) ([]string, bool, error) {
+	w.visited = map[string]bool{}
+	// report the malformed globs even if no files are walked
+	for _, glob := range slices.Concat(w.Includes, w.Excludes) {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", glob, err)
+		}
+	}
+
+	var files []string
+	walked := false
+	for _, arg := range args {
+		// the standard input is read in place of "-"
+		if arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, false, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		if !w.Recursive && !w.FollowSymlinks {
+			return nil, false, fmt.Errorf("%s: is a directory", arg)
+		}
+		walked = true
+		files, err = w.walk(arg, files)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return files, walked, nil
+}
+
+func (w *fileWalker) walk(root string, files []string) ([]string, error) {
+	// skip directories already walked through another path
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	if w.visited[real] {
+		return files, nil
+	}
+	w.visited[real] = true
+
+	// walk the real directory since WalkDir does not descend into a symlink
+	// root, but report the paths under the given root
+	err = filepath.WalkDir(real, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(real, path)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(root, rel)
+		switch {
+		case d.Type().IsRegular():
+			if w.selects(path) {
+				files = append(files, path)
+			}
+		case d.Type()&fs.ModeSymlink != 0 && w.FollowSymlinks:
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				files, err = w.walk(path, files)
+				return err
+			}
+			if info.Mode().IsRegular() && w.selects(path) {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// selects reports whether the file found while walking directories is
+// selected by Includes and Excludes.
+func (w *fileWalker) selects(path string) bool {
+	name := filepath.Base(path)
+	for _, glob := range w.Excludes {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return false
+		}
+	}
+	if len(w.Includes) == 0 {
+		return true
+	}
+	for _, glob := range w.Includes {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readPatternFile reads the patterns written one per line in the file.
+// Blank lines and lines starting with '#' are skipped.
+func readPatternFile(name string) ([]string, error) {
+	lines, err := readPatternLines(name)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]string, len(lines))
+	for i, l := range lines {
+		patterns[i] = l.text
+	}
+	return patterns, nil
+}
+
+// patternLine is a pattern read from a pattern file with its 1-based line
+// number.
+type patternLine struct {
+	lineno int
+	text   string
+}
+
+// readPatternLines is like readPatternFile but also returns the line numbers
+// of the patterns.
+func readPatternLines(name string) ([]patternLine, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []patternLine
+	sc := bufio.NewScanner(f)
+	lineno := 0
+	for sc.Scan() {
+		lineno++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, patternLine{lineno: lineno, text: line})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,166 @@
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files under a temporary directory. The keys of files are
+// slash separated paths relative to the directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRootCmdRecursive(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.txt":          "10.0.0.1\n192.168.0.1\n",
+		"sub/b.txt":      "10.0.0.2\n",
+		"sub/deep/c.txt": "172.16.0.1\n10.0.0.3\n",
+	})
+	// a directory outside of the tree reached only through a symlink
+	outside := writeTree(t, map[string]string{
+		"d.txt": "10.0.0.4\n",
+	})
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			description: "Recursive",
+			args:        []string{"-r", "-c", "-e", "10.0.0.0/8", dir},
+			expected: []string{
+				filepath.Join(dir, "a.txt") + ":1",
+				filepath.Join(dir, "sub", "b.txt") + ":1",
+				filepath.Join(dir, "sub", "deep", "c.txt") + ":1",
+			},
+		},
+		{
+			description: "Recursive Following Symlinks",
+			args:        []string{"-R", "-c", "-e", "10.0.0.0/8", dir},
+			expected: []string{
+				filepath.Join(dir, "a.txt") + ":1",
+				filepath.Join(dir, "link", "d.txt") + ":1",
+				filepath.Join(dir, "sub", "b.txt") + ":1",
+				filepath.Join(dir, "sub", "deep", "c.txt") + ":1",
+			},
+		},
+		{
+			description: "Recursive Subdirectory",
+			args:        []string{"-r", "-e", "10.0.0.0/8", filepath.Join(dir, "sub", "deep")},
+			expected: []string{
+				filepath.Join(dir, "sub", "deep", "c.txt") + ":10.0.0.3",
+			},
+		},
+		{
+			description: "Directory without Recursive",
+			args:        []string{"-e", "10.0.0.0/8", dir},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, tc.args...)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if tc.expectedErr {
+			if err != nil && !strings.Contains(err.Error(), "is a directory") {
+				t.Errorf("expected directory error, got: %v", err)
+			}
+			continue
+		}
+		expected := strings.Join(tc.expected, "\n") + "\n"
+		if out != expected {
+			t.Errorf("expected: %v, got: %v", expected, out)
+		}
+	}
+}
+
+func TestRootCmdRecursiveGlobs(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.log":          "10.0.0.1\n",
+		"a.log.gz":       "10.0.0.2\n",
+		"b.txt":          "10.0.0.3\n",
+		"sub/c.log":      "10.0.0.4\n",
+		"sub/d.csv":      "10.0.0.5\n",
+		"sub/skip.log":   "10.0.0.6\n",
+		"sub/deep/e.txt": "10.0.0.7\n",
+	})
+	file := func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name)) + ":1"
+	}
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    []string
+	}{
+		{
+			description: "Include",
+			args:        []string{"--include", "*.log"},
+			expected:    []string{file("a.log"), file("sub/c.log"), file("sub/skip.log")},
+		},
+		{
+			description: "Multiple Includes",
+			args:        []string{"--include", "*.log", "--include", "*.csv"},
+			expected:    []string{file("a.log"), file("sub/c.log"), file("sub/d.csv"), file("sub/skip.log")},
+		},
+		{
+			description: "Exclude",
+			args:        []string{"--exclude-glob", "*.gz,*.txt"},
+			expected:    []string{file("a.log"), file("sub/c.log"), file("sub/d.csv"), file("sub/skip.log")},
+		},
+		{
+			description: "Exclude over Include",
+			args:        []string{"--include", "*.log*", "--exclude-glob", "*.gz", "--exclude-glob", "skip.*"},
+			expected:    []string{file("a.log"), file("sub/c.log")},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append(tc.args, "-r", "-c", "-e", "10.0.0.0/8", dir)...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		expected := strings.Join(tc.expected, "\n") + "\n"
+		if out != expected {
+			t.Errorf("expected: %v, got: %v", expected, out)
+		}
+	}
+
+	// the files given as arguments are always read
+	out, err := execute(t, "--include", "*.log", "-e", "10.0.0.0/8", filepath.Join(dir, "b.txt"))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "10.0.0.3\n" {
+		t.Errorf("expected: %v, got: %v", "10.0.0.3\n", out)
+	}
+
+	_, err = execute(t, "-r", "--include", "[a", "-e", "10.0.0.0/8", dir)
+	if err == nil {
+		t.Errorf("expected an error of the malformed glob")
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// followInterval is the interval of checking a followed file for appended
+// data.
+const followInterval = 100 * time.Millisecond
+
+// followReader reads a file like tail -F. At the end of the file, it waits
+// for appended data instead of returning io.EOF, and reopens the file when
+// it is truncated or replaced by rotation.
+type followReader struct {
+	ctx  context.Context
+	name string
+	f    *os.File
+	// offset is the number of bytes read from f.
+	offset int64
+}
+
+// openFollow opens the file to be followed until ctx is done.
+func openFollow(ctx context.Context, name string) (*followReader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &followReader{ctx: ctx, name: name, f: f}, nil
+}
+
+// Read reads the file, waiting for appended data at its end. It returns the
+// error of ctx when ctx is done.
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(followInterval):
+		}
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// reopen reopens the file if it is replaced, or reads it from the start if
+// it is truncated.
+func (r *followReader) reopen() error {
+	fi, err := os.Stat(r.name)
+	if err != nil {
+		// the file is being rotated, so wait for the new one
+		return nil
+	}
+	cur, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(fi, cur) {
+		f, err := os.Open(r.name)
+		if err != nil {
+			return nil
+		}
+		r.f.Close()
+		r.f = f
+		r.offset = 0
+		return nil
+	}
+	if fi.Size() < r.offset {
+		if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		r.offset = 0
+	}
+	return nil
+}
+
+// Close closes the file being read.
+func (r *followReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,120 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// syncBuffer is a bytes.Buffer safe to write and read concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitOutput waits until the output is expected.
+func waitOutput(t *testing.T, out *syncBuffer, expected string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected: %q, got: %q", expected, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRootCmdFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendFile(t, path, "10.0.0.1\n172.16.0.1\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &syncBuffer{}
+	root := cmd.NewRootCmd()
+	root.SetArgs([]string{"--follow", "-e", "10.0.0.0/8", path})
+	root.SetOut(out)
+	root.SetErr(&bytes.Buffer{})
+	done := make(chan error, 1)
+	go func() {
+		done <- root.ExecuteContext(ctx)
+	}()
+
+	// the existing lines
+	expected := "10.0.0.1\n"
+	waitOutput(t, out, expected)
+
+	// the appended lines, including a line written in two parts
+	appendFile(t, path, "10.0.0.2\n192.168.0.1\n10.0.")
+	expected += "10.0.0.2\n"
+	waitOutput(t, out, expected)
+	appendFile(t, path, "0.3\n")
+	expected += "10.0.0.3\n"
+	waitOutput(t, out, expected)
+
+	// the truncated file is read from the start
+	if err := os.WriteFile(path, []byte("10.0.0.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expected += "10.0.0.4\n"
+	waitOutput(t, out, expected)
+
+	// the rotated file is reopened
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "172.16.0.2\n10.0.0.5\n")
+	expected += "10.0.0.5\n"
+	waitOutput(t, out, expected)
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected: %v, got: %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected following to stop")
+	}
+}
+
+func TestRootCmdFollowFiles(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n", "10.0.0.2\n")
+
+	_, err := execute(t, "--follow", "-e", "10.0.0.0/8", paths[0], paths[1])
+	if err == nil || !strings.Contains(err.Error(), "--follow") {
+		t.Errorf("expected an error of --follow, got: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// checkFormat returns an error if format is not a format of Options.Format.
+func checkFormat(format string) error {
+	switch format {
+	case "", "text", "int", "hex":
+		return nil
+	}
+	return fmt.Errorf("invalid format: %s", format)
+}
+
+// formatIP returns ip in the format of Options.Format. The text format is the
+// canonical form. The prefix length of a network follows its address.
+func formatIP(ip IPAddress, format string) string {
+	var s string
+	switch format {
+	case "int":
+		// the 128-bit values of IPv6 do not fit in uint64
+		s = new(big.Int).SetBytes(ip.Bytes()).String()
+	case "hex":
+		s = "0x" + hex.EncodeToString(ip.Bytes())
+	default:
+		return ip.String()
+	}
+	if n, ok := ip.(Network); ok {
+		s += "/" + strconv.Itoa(n.Bits)
+	}
+	return s
+}
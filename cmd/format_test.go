@@ -0,0 +1,92 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRunWithOptionsFormat(t *testing.T) {
+	input := `0.0.0.0
+127.0.0.1
+255.255.255.255
+::1
+2001:db8::1
+ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff
+10.0.0.0/8`
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "Integer",
+			opts:        cmd.Options{Format: "int"},
+			expected: `0
+2130706433
+4294967295
+1
+42540766411282592856903984951653826561
+340282366920938463463374607431768211455
+167772160/8
+`,
+		},
+		{
+			description: "Hexadecimal",
+			opts:        cmd.Options{Format: "hex"},
+			expected: `0x00000000
+0x7f000001
+0xffffffff
+0x00000000000000000000000000000001
+0x20010db8000000000000000000000001
+0xffffffffffffffffffffffffffffffff
+0x0a000000/8
+`,
+		},
+		{
+			description: "Text",
+			opts:        cmd.Options{Format: "text"},
+			expected:    input + "\n",
+		},
+		{
+			description: "Invalid Format",
+			opts:        cmd.Options{Format: "octal"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, []string{"0.0.0.0/1", "128.0.0.0/1", "::/1", "8000::/1"}, tc.opts)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsFormatExtract(t *testing.T) {
+	outbuf := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader("from 10.0.0.1 port 22\nfrom 192.168.0.1\n"), outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{Extract: true, Format: "hex"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "from 0x0a000001 port 22\n"
+	if outbuf.String() != expected {
+		t.Errorf("expected: %v, got: %v", expected, outbuf.String())
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoIP looks up the countries of IP addresses.
+type GeoIP interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country of ip, or
+	// an empty string if ip has no geo data.
+	Country(ip IPAddress) (string, error)
+}
+
+// NoCountry is the country code of Options.Countries selecting the IP
+// addresses without geo data.
+const NoCountry = "-"
+
+// MaxMindDB is a GeoIP reading a MaxMind DB file, e.g. GeoLite2-Country.mmdb.
+type MaxMindDB struct {
+	r *maxminddb.Reader
+}
+
+// OpenMaxMindDB opens the MaxMind DB file. It must be closed after use.
+func OpenMaxMindDB(path string) (*MaxMindDB, error) {
+	r, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MaxMindDB{r: r}, nil
+}
+
+// Country returns the code of the country in the record of ip.
+func (db *MaxMindDB) Country(ip IPAddress) (string, error) {
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := db.r.Lookup(net.IP(ip.Bytes()), &record); err != nil {
+		return "", err
+	}
+	return record.Country.ISOCode, nil
+}
+
+// Close closes the file.
+func (db *MaxMindDB) Close() error {
+	return db.r.Close()
+}
+
+// countryFilter selects the IP addresses in the countries.
+type countryFilter struct {
+	geoip GeoIP
+	// countries are the upper case codes of the countries.
+	countries []string
+}
+
+func newCountryFilter(geoip GeoIP, countries []string) *countryFilter {
+	f := &countryFilter{geoip: geoip}
+	for _, country := range countries {
+		f.countries = append(f.countries, strings.ToUpper(country))
+	}
+	return f
+}
+
+// match reports whether ip is in any of the countries. Networks and the IP
+// addresses failed to look up have no geo data.
+func (f *countryFilter) match(ip IPAddress) bool {
+	country := ""
+	if _, ok := ip.(Network); !ok {
+		country, _ = f.geoip.Country(ip)
+	}
+	if country == "" {
+		country = NoCountry
+	}
+	return slices.Contains(f.countries, strings.ToUpper(country))
+}
@@ -0,0 +1,103 @@
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// fakeGeoIP maps the IP addresses in canonical form to their countries.
+type fakeGeoIP map[string]string
+
+func (g fakeGeoIP) Country(ip cmd.IPAddress) (string, error) {
+	if ip.String() == "10.222.0.9" {
+		return "", errors.New("broken record")
+	}
+	return g[ip.String()], nil
+}
+
+func TestRunWithOptionsCountries(t *testing.T) {
+	geoip := fakeGeoIP{
+		"10.222.0.1":   "US",
+		"10.222.0.2":   "JP",
+		"10.222.0.3":   "DE",
+		"192.168.57.1": "JP",
+	}
+	input := "10.222.0.1\n10.222.0.2\n10.222.0.3\n10.222.0.4\n10.222.0.9\n172.16.0.1\n"
+
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Countries",
+			input:       input,
+			opts:        cmd.Options{Countries: []string{"US", "JP"}},
+			expected:    "10.222.0.1\n10.222.0.2\n",
+		},
+		{
+			description: "Lower Case",
+			input:       input,
+			opts:        cmd.Options{Countries: []string{"de"}},
+			expected:    "10.222.0.3\n",
+		},
+		{
+			description: "No Geo Data",
+			input:       input,
+			opts:        cmd.Options{Countries: []string{cmd.NoCountry}},
+			expected:    "10.222.0.4\n10.222.0.9\n",
+		},
+		{
+			description: "Inverted",
+			input:       input,
+			opts:        cmd.Options{Countries: []string{"US", "JP", "DE"}, Invert: true},
+			expected:    "10.222.0.4\n10.222.0.9\n172.16.0.1\n",
+		},
+		{
+			description: "Extract",
+			input:       "from 10.222.0.3 to 192.168.57.1\nfrom 10.222.0.4\n",
+			opts:        cmd.Options{Countries: []string{"JP"}, Extract: true, OnlyMatching: true},
+			expected:    "192.168.57.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		tc.opts.GeoIP = geoip
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsCountriesWithoutGeoIP(t *testing.T) {
+	_, err := cmd.RunWithOptions(strings.NewReader("10.222.0.1\n"), &bytes.Buffer{}, &bytes.Buffer{}, samplePatterns, cmd.Options{Countries: []string{"US"}})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestRootCmdCountry(t *testing.T) {
+	paths := writeFiles(t, sampleInput)
+
+	_, err := execute(t, "-e", "10.0.0.0/8", "--country", "US", paths[0])
+	if err == nil || !strings.Contains(err.Error(), "--geoip-db") {
+		t.Errorf("expected an error asking for --geoip-db, got: %v", err)
+	}
+
+	_, err = execute(t, "-e", "10.0.0.0/8", "--country", "US", "--geoip-db", paths[0], paths[0])
+	if err == nil || err.Error() == "no lines selected" {
+		t.Errorf("expected an invalid database error, got: %v", err)
+	}
+}
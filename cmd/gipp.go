@@ -4,19 +4,36 @@ Copyright © 2023 J.Kushibiki
 package cmd
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 func NewRootCmd() *cobra.Command {
 	var patterns []string
+	var patternFiles []string
+	var opts Options
+	var walker fileWalker
+	var colorMode string
+	var withFilename, noFilename bool
+	var private bool
+	var bogon, noBogon bool
+	var allIPs bool
+	var ipv4, ipv6 bool
+	var geoipDB string
+	var asnDB string
+	var matchedFile, unmatchedFile, invalidFile string
+	var invertFile string
+	var cpuProfile, memProfile string
 
 	cmd := &cobra.Command{
-		Use:   "gipp [flags] [-e pattern] [file ...]",
+		Use:   "gipp [flags] [-e pattern] [-f file] [file ...]",
 		Short: "IP Prefix/Suffix Version of grep",
 		Long: `The gipp utility searches any given IP address list files, selecting lines that match one or more patterns.
 The pattern is written in an extended cidr notation that allows suffixes to be expressed.
@@ -24,38 +41,333 @@ The pattern is written in an extended cidr notation that allows suffixes to be e
 following are examples of the pattern:
 	192.168.100.0/24
 	0.0.0.1/-8
-	::abcd:01ff:fe00:0/-64/24`,
+	::abcd:01ff:fe00:0/-64/104
+	192.168.1.10-192.168.1.50
+	192.168.*.5
+	mac:00:12:34:56:78:90/-64
+	private
+
+the names of special-purpose ranges are also available as patterns:
+	private, loopback, multicast, link-local, documentation and bogon`,
 		DisableFlagsInUseLine: true,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// check if patterns are specified
-			if len(patterns) == 0 {
+		// the arguments are files unless they name a subcommand
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			// load pattern files
+			for _, file := range patternFiles {
+				ps, err := readPatternFile(file)
+				if err != nil {
+					return err
+				}
+				patterns = append(patterns, ps...)
+			}
+			if private {
+				patterns = append(patterns, "private")
+			}
+			if bogon {
+				patterns = append(patterns, "bogon")
+			}
+			if noBogon {
+				opts.Excludes = append(opts.Excludes, "bogon")
+			}
+			patterns = append(patterns, negatePatterns(opts.Excludes)...)
+
+			if cmd.Flags().Changed("delimiter") && opts.Field == 0 {
+				return fmt.Errorf("--delimiter needs --field")
+			}
+			if cmd.Flags().Changed("comment-char") && !opts.SkipComments {
+				return fmt.Errorf("--comment-char needs --skip-comments")
+			}
+			if (cmd.Flags().Changed("unique-fp") || cmd.Flags().Changed("unique-size")) && !opts.UniqueApprox {
+				return fmt.Errorf("--unique-fp and --unique-size need --unique-approx")
+			}
+			// zero stands for the defaults in Options, which the flags already have
+			if opts.UniqueApprox && opts.UniqueFP == 0 {
+				return fmt.Errorf("invalid false positive rate: %v", opts.UniqueFP)
+			}
+			if opts.UniqueApprox && opts.UniqueSize == 0 {
+				return fmt.Errorf("invalid size: %d", opts.UniqueSize)
+			}
+
+			if ipv4 {
+				opts.Version = 4
+			}
+			if ipv6 {
+				opts.Version = 6
+			}
+
+			// check if patterns are specified, which are not needed to filter by
+			// version or to select all the IP addresses
+			if len(patterns) == 0 && opts.Version == 0 && !allIPs {
 				return fmt.Errorf("no patterns specified")
 			}
 
-			// with files
-			if len(args) > 0 {
-				// open files
-				var files []io.Reader
-				for _, arg := range args {
-					f, err := os.Open(arg)
-					if err != nil {
-						return err
-					}
-					defer f.Close()
-					files = append(files, f)
+			out := cmd.OutOrStdout()
+			eout := cmd.ErrOrStderr()
+
+			// check if the output is colorized
+			color, err := useColor(colorMode, out)
+			if err != nil {
+				return err
+			}
+			opts.Color = color
+
+			if err := checkFormat(opts.Format); err != nil {
+				return err
+			}
+			if err := checkOutput(opts.Output); err != nil {
+				return err
+			}
+
+			// open the database of the countries
+			if len(opts.Countries) > 0 {
+				if geoipDB == "" {
+					return fmt.Errorf("--country needs --geoip-db")
 				}
-				// concat files
-				reader := io.MultiReader(files...)
-				// run gipp
-				return Run(reader, os.Stdout, os.Stderr, patterns)
+				db, err := OpenMaxMindDB(geoipDB)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				opts.GeoIP = db
+			}
+
+			// open the database of the autonomous systems
+			if len(opts.ASNs) > 0 || opts.ShowASN {
+				if asnDB == "" {
+					return fmt.Errorf("--asn and --show-asn need --asn-db")
+				}
+				db, err := OpenMaxMindDB(asnDB)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				opts.ASNLookup = db
+			}
+
+			// load patterns
+			if opts.StrictCIDR {
+				if err := checkStrictCIDR(patterns); err != nil {
+					return err
+				}
+			}
+			m, err := newMatcher(patterns, opts)
+			if err != nil {
+				return err
+			}
+
+			// create the files the lines are split into once the patterns are
+			// valid, not to truncate them on an error
+			for _, split := range []struct {
+				name string
+				w    *io.Writer
+			}{
+				{matchedFile, &opts.MatchedOut},
+				{unmatchedFile, &opts.UnmatchedOut},
+				{invalidFile, &opts.InvalidOut},
+				{invertFile, &opts.InvertOut},
+			} {
+				if split.name == "" {
+					continue
+				}
+				f, err := os.Create(split.name)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				*split.w = f
+			}
+			s, err := newSearcher(m, out, eout, opts)
+			if err != nil {
+				return err
 			}
+			defer s.flushWriters()
+
+			// profile the search, reporting the error of writing the profiles
+			// unless the search fails
+			stopProfiles, err := startProfiles(cpuProfile, memProfile)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if perr := stopProfiles(); perr != nil && err == nil {
+					err = perr
+				}
+			}()
 
 			// without files
-			return Run(os.Stdin, os.Stdout, os.Stderr, patterns)
+			if len(args) == 0 {
+				matched, err := s.search(cmd.Context(), cmd.InOrStdin(), "", withFilename)
+				if err != nil {
+					return err
+				}
+				s.flush()
+				return checkMatched(cmd, found(opts, matched))
+			}
+
+			// with files, one by one so that the output can tell them apart
+			files, walked, err := walker.Collect(args)
+			if err != nil {
+				return err
+			}
+			// the files other than the followed one would never be read
+			if opts.Follow && len(files) != 1 {
+				return fmt.Errorf("--follow needs exactly one file")
+			}
+			total := 0
+			for _, file := range files {
+				name := (len(files) > 1 || walked || withFilename) && !noFilename
+				var matched int
+				var err error
+				// "-" stands for the standard input like grep
+				if file == "-" {
+					matched, err = s.search(cmd.Context(), cmd.InOrStdin(), "", name)
+				} else {
+					matched, err = s.searchFile(cmd.Context(), file, name)
+				}
+				if err != nil {
+					return err
+				}
+				total += found(opts, matched)
+				// the exit status is already decided
+				if opts.Quiet && total > 0 {
+					break
+				}
+			}
+			s.flush()
+			return checkMatched(cmd, total)
 		},
 	}
 
 	cmd.Flags().StringSliceVarP(&patterns, "pattern", "e", []string{}, "pattern")
+	cmd.Flags().StringSliceVarP(&patternFiles, "file", "f", []string{}, "read patterns from the file, one per line")
+	cmd.Flags().BoolVar(&private, "private", false, "same as -e private")
+	cmd.Flags().BoolVar(&bogon, "bogon", false, "same as -e bogon, selecting only the special-use and reserved addresses")
+	cmd.Flags().BoolVar(&noBogon, "no-bogon", false, "same as --exclude bogon, dropping the special-use and reserved addresses")
+	cmd.Flags().BoolVar(&allIPs, "all-ips", false, "select every line that is an IP address, without patterns")
+	cmd.Flags().StringSliceVar(&opts.Excludes, "exclude", []string{}, "exclude the IP addresses matching the pattern, same as -e '!pattern'")
+	cmd.Flags().BoolVarP(&ipv4, "ipv4", "4", false, "select only IPv4 addresses")
+	cmd.Flags().BoolVarP(&ipv6, "ipv6", "6", false, "select only IPv6 addresses")
+	cmd.Flags().BoolVar(&opts.StrictCIDR, "strict-cidr", false, "reject the prefix patterns whose host bits are set, e.g. 192.168.1.5/24")
+	cmd.Flags().BoolVar(&opts.MapIPv4, "map", false, "match IPv4 addresses and IPv4-mapped IPv6 addresses, e.g. ::ffff:192.168.1.1, as the same addresses")
+	cmd.Flags().BoolVar(&opts.DecodeTransition, "decode-transition", false, "also match 6to4 and Teredo IPv6 addresses as the IPv4 addresses embedded in them")
+	cmd.Flags().BoolVar(&opts.Overlaps, "overlaps", false, "select the networks overlapping with the patterns instead of contained in them")
+	cmd.Flags().BoolVarP(&opts.Invert, "invert-match", "v", false, "select IP addresses that match none of the patterns")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "select IP addresses that match all of the patterns instead of any of them")
+	cmd.Flags().BoolVarP(&opts.Count, "count", "c", false, "print only a count of selected lines")
+	cmd.Flags().BoolVarP(&opts.FilesWithMatches, "files-with-matches", "l", false, "print only the names of files with selected lines")
+	cmd.Flags().BoolVarP(&opts.FilesWithoutMatches, "files-without-match", "L", false, "print only the names of files with no selected lines")
+	cmd.Flags().BoolVarP(&opts.Quiet, "quiet", "q", false, "print nothing and exit immediately with zero status if any line is selected")
+	cmd.Flags().BoolVarP(&opts.Unique, "unique", "u", false, "print each selected IP address only once")
+	cmd.Flags().BoolVar(&opts.UniqueApprox, "unique-approx", false, "like --unique but in bounded memory, dropping a few addresses not printed before")
+	cmd.Flags().Float64Var(&opts.UniqueFP, "unique-fp", DefaultUniqueFP, "rate of the addresses wrongly dropped by --unique-approx")
+	cmd.Flags().IntVar(&opts.UniqueSize, "unique-size", DefaultUniqueSize, "number of the distinct addresses --unique-approx is sized for")
+	cmd.Flags().IntVar(&opts.PerPatternLimit, "per-pattern-limit", 0, "print at most the number of lines matching each pattern; 0 means no limit")
+	cmd.Flags().BoolVar(&opts.Sort, "sort", false, "print the selected lines sorted by IP address after reading all the input")
+	cmd.Flags().BoolVar(&opts.Normalize, "normalize", false, "print the selected IP addresses in canonical form")
+	cmd.Flags().BoolVar(&opts.ShowPattern, "show-pattern", false, "print the first pattern each selected line matches after the line")
+	cmd.Flags().BoolVar(&opts.Stats, "stats", false, "print the number of selected lines matching each pattern to stderr at the end")
+	cmd.Flags().BoolVar(&opts.LPM, "lpm", false, "show the matching prefix pattern with the longest prefix length instead of the first one")
+	cmd.Flags().BoolVar(&opts.Classify, "classify", false, "print the special-purpose range of each selected IP address after the line")
+	cmd.Flags().BoolVar(&opts.ShowNetwork, "show-network", false, "print the network, broadcast and host portion of each selected IP address in its matching prefix after the line")
+	cmd.Flags().BoolVar(&opts.ShowMAC, "mac", false, "print the MAC address embedded in each selected IPv6 address of the EUI-64 format after the line")
+	cmd.Flags().StringVar(&opts.Format, "format", "text", "print the selected IP addresses as integers; text, int or hex")
+	cmd.Flags().StringVar(&opts.Output, "output", "text", "print the selected IP addresses in the structured format; text, json, csv or ipset")
+	cmd.Flags().BoolVar(&opts.NoHeader, "no-header", false, "omit the header row of the csv output")
+	cmd.Flags().StringVar(&opts.IPSetName, "ipset-name", DefaultIPSetName, "name of the set of the ipset output, suffixed with -v6 for IPv6")
+	cmd.Flags().StringVar(&opts.Template, "template", "", "print each selected IP address with the Go template, e.g. '{{.IP}} {{.Line}}'")
+	cmd.Flags().StringVar(&colorMode, "color", "auto", "colorize the selected IP addresses; auto, always or never")
+	cmd.Flags().BoolVarP(&walker.Recursive, "recursive", "r", false, "read all files under each directory, recursively")
+	cmd.Flags().BoolVarP(&walker.FollowSymlinks, "dereference-recursive", "R", false, "likewise, but follow all symlinks")
+	cmd.Flags().StringSliceVar(&walker.Includes, "include", []string{}, "read only the files whose base names match the glob while walking directories")
+	cmd.Flags().StringSliceVar(&walker.Excludes, "exclude-glob", []string{}, "skip the files whose base names match the glob while walking directories, even if they match --include")
+	cmd.Flags().IntVar(&opts.Jobs, "jobs", 1, "number of goroutines parsing and matching lines")
+	cmd.Flags().BoolVar(&opts.NoOrder, "no-order", false, "with --jobs, print lines as soon as they are matched regardless of input order")
+	cmd.Flags().BoolVarP(&opts.OnlyMatching, "only-matching", "o", false, "print only the selected IP addresses in canonical form")
+	cmd.Flags().BoolVar(&opts.LooseIPv4, "loose-ipv4", false, "also read IPv4 addresses in the forms accepted by inet_aton, e.g. 127.1 and 0x7f000001")
+	cmd.Flags().StringSliceVar(&opts.Countries, "country", []string{}, "select only the IP addresses in the countries of the ISO codes, or - for no geo data")
+	cmd.Flags().StringVar(&geoipDB, "geoip-db", "", "MaxMind DB file looking up the countries of --country")
+	cmd.Flags().UintSliceVar(&opts.ASNs, "asn", []uint{}, "select only the IP addresses in the autonomous systems of the numbers")
+	cmd.Flags().StringVar(&asnDB, "asn-db", "", "MaxMind DB file looking up the autonomous systems of --asn and --show-asn")
+	cmd.Flags().BoolVar(&opts.ShowASN, "show-asn", false, "print the autonomous system number of each selected IP address after the line")
+	cmd.Flags().StringVar(&opts.PTRMatches, "ptr-matches", "", "select only the IP addresses whose PTR records match the regular expression; looks up DNS")
+	cmd.Flags().DurationVar(&opts.PTRTimeout, "ptr-timeout", DefaultPTRTimeout, "timeout of each PTR lookup of --ptr-matches")
+	cmd.Flags().BoolVar(&opts.SkipComments, "skip-comments", false, "ignore blank lines and comment lines of the input instead of treating them as invalid lines")
+	cmd.Flags().StringVar(&opts.CommentChar, "comment-char", DefaultCommentChar, "prefix of the comment lines for --skip-comments")
+	cmd.Flags().BoolVar(&opts.Hosts, "hosts", false, "match the address at the start of each line of a hosts file, skipping blank and comment lines")
+	cmd.Flags().IntVar(&opts.Field, "field", 0, "match the nth field of each line, counted from 1, instead of the whole line")
+	cmd.Flags().StringVar(&opts.Delimiter, "delimiter", "", "delimiter of the fields for --field (default whitespace)")
+	cmd.Flags().BoolVar(&opts.Extract, "extract", false, "match the IP addresses embedded in each line instead of the whole line")
+	cmd.Flags().BoolVar(&opts.Passthrough, "passthrough", false, "print the lines that are not IP addresses as they are")
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "report each line that is not an IP address")
+	cmd.Flags().BoolVar(&opts.AbortOnInvalid, "abort-on-invalid", false, "exit with an error at the first line that is not an IP address")
+	cmd.Flags().BoolVarP(&opts.Follow, "follow", "F", false, "keep reading the lines appended to the file, reopening it when rotated")
+	cmd.Flags().StringVar(&matchedFile, "matched-file", "", "also write the selected lines to the file")
+	cmd.Flags().StringVar(&unmatchedFile, "unmatched-file", "", "also write the lines not selected to the file, including invalid lines without --invalid-file")
+	cmd.Flags().StringVar(&invertFile, "invert-file", "", "also write the IP addresses not selected to the file, without the lines that are not IP addresses")
+	cmd.Flags().StringVar(&invalidFile, "invalid-file", "", "also write the lines that are not IP addresses to the file")
+	cmd.Flags().BoolVar(&opts.LineBuffered, "line-buffered", false, "flush the output after every line")
+	cmd.Flags().BoolVarP(&opts.NullData, "null-data", "z", false, "read the input lines terminated by NUL instead of newline")
+	cmd.Flags().BoolVarP(&opts.NullOutput, "null", "Z", false, "terminate the output lines by NUL instead of newline")
+	cmd.Flags().IntVar(&opts.MaxLineLength, "max-line-length", DefaultMaxLineLength, "maximum length of an input line in bytes")
+	cmd.Flags().BoolVarP(&withFilename, "with-filename", "H", false, "print the file name for each line")
+	cmd.Flags().BoolVarP(&noFilename, "no-filename", "h", false, "suppress the file name prefix on output")
+	cmd.Flags().BoolVarP(&opts.LineNumber, "line-number", "n", false, "prefix each line with its line number in the file")
+	cmd.Flags().StringVar(&cpuProfile, "cpuprofile", "", "write the CPU profile of the search to the file")
+	cmd.Flags().StringVar(&memProfile, "memprofile", "", "write the heap profile to the file after the search")
+
+	cmd.MarkFlagsMutuallyExclusive("files-with-matches", "files-without-match")
+	cmd.MarkFlagsMutuallyExclusive("with-filename", "no-filename")
+	cmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+	cmd.MarkFlagsMutuallyExclusive("template", "output")
+	cmd.MarkFlagsMutuallyExclusive("hosts", "extract", "field")
+	cmd.MarkFlagsMutuallyExclusive("unique", "unique-approx")
+	cmd.MarkFlagsMutuallyExclusive("per-pattern-limit", "invert-match")
+	cmd.MarkFlagsMutuallyExclusive("show-network", "output")
+	cmd.MarkFlagsMutuallyExclusive("show-network", "template")
+	cmd.MarkFlagsMutuallyExclusive("mac", "output")
+	cmd.MarkFlagsMutuallyExclusive("mac", "template")
+	cmd.MarkFlagsMutuallyExclusive("bogon", "no-bogon")
+	for _, flag := range []string{"pattern", "file", "private", "bogon"} {
+		cmd.MarkFlagsMutuallyExclusive("all-ips", flag)
+	}
+	// --sort needs all the selected lines while the others stop early or print
+	// no lines, the lines passed through have no IP addresses to sort by and
+	// the followed file never ends
+	for _, flag := range []string{"quiet", "count", "files-with-matches", "files-without-match", "passthrough", "follow"} {
+		cmd.MarkFlagsMutuallyExclusive("sort", flag)
+	}
+
+	// -h is taken by --no-filename like grep
+	cmd.Flags().Bool("help", false, "help for gipp")
+
+	// complete the files, the subcommands and the values of the flags;
+	// the completion subcommand is added by cobra
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	cmd.RegisterFlagCompletionFunc("pattern", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names := make([]string, 0, len(namedPatterns))
+		for name := range namedPatterns {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"text", "int", "hex"}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"text", "json", "csv", "ipset"}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+
+	// --version without a shorthand as -v is taken by --invert-match
+	cmd.Version = Version
+	cmd.SetVersionTemplate(versionString())
+	cmd.AddCommand(newVersionCmd())
+
+	cmd.AddCommand(newExpandCmd())
+	cmd.AddCommand(newAggregateCmd())
+	cmd.AddCommand(newRulesCmd())
+	cmd.AddCommand(newWhichCmd())
+	cmd.AddCommand(newValidateCmd())
 
 	cmd.SetOut(os.Stdout)
 	cmd.SetErr(os.Stderr)
@@ -63,41 +375,410 @@ following are examples of the pattern:
 	return cmd
 }
 
+// DefaultMaxLineLength is the maximum length of an input line used when
+// Options.MaxLineLength is zero.
+const DefaultMaxLineLength = 1024 * 1024
+
+// DefaultUniqueFP is the false positive rate used when Options.UniqueFP is
+// zero.
+const DefaultUniqueFP = 0.001
+
+// DefaultUniqueSize is the number of the distinct addresses used when
+// Options.UniqueSize is zero, which takes up to about 18 MB with
+// DefaultUniqueFP as the addresses are added.
+const DefaultUniqueSize = 10000000
+
+// DefaultCommentChar is the prefix of the comment lines used when
+// Options.CommentChar is empty.
+const DefaultCommentChar = "#"
+
+// Options controls how RunWithOptions selects and prints lines.
+type Options struct {
+	// Version selects only the IP addresses of the version, 4 or 6, before
+	// matching them. Zero selects both.
+	Version int
+	// StrictCIDR rejects the prefix patterns whose bits below the prefix
+	// length are set, e.g. 192.168.1.5/24, with a *PatternError wrapping
+	// ErrInvalidPattern. Otherwise, the bits are cleared.
+	StrictCIDR bool
+	// MapIPv4 matches an IPv4 address and its IPv4-mapped IPv6 address, e.g.
+	// 192.168.1.1 and ::ffff:192.168.1.1, as the same address: each of them
+	// matches the patterns of either form, e.g. 192.168.1.0/24 and
+	// ::ffff:192.168.1.0/120, and is excluded by the negated patterns of
+	// either form. The other IPv6 addresses and the networks in CIDR notation
+	// never match IPv4 patterns. The addresses are printed as they are, and
+	// Version still selects them by the form written.
+	MapIPv4 bool
+	// DecodeTransition matches a 6to4 address in 2002::/16 and a Teredo
+	// address in 2001::/32 as the IPv4 address embedded in it as well, e.g.
+	// 2002:c000:204::1 matches 192.0.2.0/24, like MapIPv4. The IPv4 address
+	// of the client is decoded from a Teredo address. See EmbeddedIPv4 for
+	// the layouts of the bits.
+	DecodeTransition bool
+	// Excludes are the patterns excluding the IP addresses matching them like
+	// the negated patterns, e.g. "10.0.0.0/8" for "!10.0.0.0/8". An IP
+	// address is selected when it matches any of the patterns and none of
+	// Excludes, or any IP address matching none of Excludes without other
+	// patterns. Invert inverts the selection after Excludes are applied.
+	Excludes []string
+	// Overlaps selects the lines of networks in CIDR notation that overlap
+	// with the patterns instead of the ones contained in them.
+	Overlaps bool
+	// Invert selects the IP addresses that match none of the patterns.
+	// Lines that are not IP addresses are dropped whether inverted or not.
+	Invert bool
+	// All selects the IP addresses matching every pattern instead of any of
+	// them, and none of the negated patterns, e.g. the addresses in
+	// 10.0.0.0/8 but not in 10.1.0.0/16 with "10.0.0.0/8" and
+	// "!10.1.0.0/16". Invert inverts the combined result, selecting the
+	// addresses missing any of the patterns or matching any negated one.
+	All bool
+	// Count prints the number of selected lines instead of the lines themselves.
+	// A line matching several patterns is counted once.
+	Count bool
+	// FilesWithMatches prints only the name of the input if any line is selected.
+	// Reading stops at the first selected line.
+	FilesWithMatches bool
+	// FilesWithoutMatches prints only the name of the input if no lines are selected.
+	// Reading stops at the first selected line.
+	FilesWithoutMatches bool
+	// Quiet prints nothing and stops reading at the first selected line.
+	Quiet bool
+	// LineNumber prefixes each line with its 1-based line number.
+	// Every input line is numbered, including the ones that are not IP addresses,
+	// and the numbering restarts for each file.
+	LineNumber bool
+	// Unique selects each IP address only once across all the inputs, comparing
+	// them in canonical form. Every selected address is kept in memory, which
+	// grows with the number of distinct addresses in the inputs.
+	Unique bool
+	// UniqueApprox is like Unique but keeps the selected addresses in a Bloom
+	// filter of a fixed size instead. A few addresses not selected before may
+	// be dropped as duplicates at the rate of UniqueFP, while a duplicate is
+	// never printed.
+	UniqueApprox bool
+	// UniqueFP is the false positive rate of UniqueApprox, between 0 and 1
+	// exclusive. Zero uses DefaultUniqueFP.
+	UniqueFP float64
+	// UniqueSize is the number of the distinct addresses UniqueApprox is
+	// sized for. More addresses raise the false positive rate over UniqueFP.
+	// The filter must fit in 1 GiB with UniqueFP, and its memory is
+	// allocated as the addresses are added. Zero uses DefaultUniqueSize.
+	UniqueSize int
+	// PerPatternLimit selects at most the number of lines for each of the
+	// patterns, e.g. to sample them. A selected line is attributed to every
+	// pattern it matches that has fewer lines, and is dropped if it has none,
+	// so a line matching several patterns is printed while any of them has
+	// room. The lines dropped by Unique are not attributed. Without patterns
+	// other than negated ones, e.g. with only Version or Excludes, the
+	// selected lines are limited as a whole. Lines selected by Invert match
+	// no patterns and are not limited. Zero means no limit.
+	PerPatternLimit int
+	// Sort prints the selected lines sorted by the big-endian byte values of
+	// their IP addresses, IPv4 before IPv6, after all the inputs are read.
+	// Every selected line is kept in memory until then.
+	Sort bool
+	// Normalize prints the selected IP addresses in canonical form instead of
+	// the original text, e.g. "2001:db8::1" for "2001:0db8:0000::0001".
+	Normalize bool
+	// ShowPattern appends the first of the patterns each line matches to the
+	// line in brackets, e.g. "10.222.200.200 [10.222.0.0/16]".
+	ShowPattern bool
+	// LPM shows the prefix pattern with the longest prefix length among the
+	// ones each IP address matches instead of the first one, like the
+	// routing tables, e.g. 10.1.0.0/16 rather than 10.0.0.0/8 for 10.1.2.3.
+	// It applies to the addresses, not to the networks in CIDR notation.
+	LPM bool
+	// Stats prints the number of the selected lines matching each of the
+	// patterns to eout after all the inputs are read, e.g. "10.0.0.0/8: 3".
+	// A line is counted for every pattern it matches, including the lines
+	// dropped by Unique.
+	Stats bool
+	// Classify appends the special-purpose range of the IP address of each
+	// line returned by Classify in parentheses, e.g. "10.0.0.1 (private)".
+	Classify bool
+	// ShowNetwork appends the subnet of each selected IP address in the
+	// prefix pattern with the longest prefix length it matches: the network
+	// address, the broadcast address and the host portion for IPv4, e.g.
+	// "10.1.2.3 network=10.0.0.0/8 broadcast=10.255.255.255 host=0.1.2.3",
+	// and the network address and the interface ID for IPv6, e.g.
+	// "2001:db8::1 network=2001:db8::/64 interface-id=::1". Nothing is
+	// appended to the addresses matching no prefix patterns and the networks.
+	// It applies to the lines and OnlyMatching, not to Output and Template.
+	ShowNetwork bool
+	// ShowMAC appends the MAC address embedded in the interface ID of each
+	// selected IPv6 address by SLAAC, e.g.
+	// "fe80::212:34ff:fe56:7890 mac=00:12:34:56:78:90". See MACFromEUI64.
+	// Nothing is appended to the other addresses. Like ShowNetwork, it
+	// applies to the lines and OnlyMatching.
+	ShowMAC bool
+	// Format prints the selected IP addresses instead of the lines in the
+	// format: "int" for the big-endian integer in decimal, which is 128-bit
+	// for IPv6, or "hex" for the same value in hexadecimal with "0x" and all
+	// the leading zeros. Like Normalize, it rewrites the addresses found by
+	// Extract in place. An empty string or "text" prints the lines.
+	Format string
+	// Output prints the selected IP addresses as JSON Lines with "json" or
+	// CSV with "csv" instead of the lines. Each record has "ip" and "version",
+	// and "line", "file", "pattern", "class" and "asn" when LineNumber, the
+	// file names, ShowPattern, Classify and ShowASN are printed respectively. Like
+	// OnlyMatching, each of the matching addresses found by Extract is
+	// printed. An empty string or "text" prints the lines.
+	//
+	// "ipset" prints the commands of ipset restore instead, adding the
+	// selected IP addresses to the hash:ip sets of IPSetName. Each set is
+	// created before its first entry, and the IPv6 one is suffixed with "-v6".
+	Output string
+	// IPSetName is the name of the sets of the ipset output. An empty name
+	// means DefaultIPSetName.
+	IPSetName string
+	// MatchedOut, UnmatchedOut and InvalidOut receive the input lines split
+	// by the result of matching them in a single pass: the selected lines,
+	// the other IP addresses, and the lines that are not IP addresses. The
+	// invalid lines go to UnmatchedOut if InvalidOut is nil. Every line is
+	// written as it is read regardless of the options deciding the output,
+	// e.g. Unique and Count, so the split is complete and disjoint. Quiet,
+	// FilesWithMatches and FilesWithoutMatches stop reading at the first
+	// selected line, though, and the lines after it are not written. Invert
+	// swaps the matched and unmatched IP addresses.
+	MatchedOut   io.Writer
+	UnmatchedOut io.Writer
+	InvalidOut   io.Writer
+	// InvertOut receives the IP addresses not selected, which Invert would
+	// select, like UnmatchedOut but never the lines that are not IP
+	// addresses, whether InvalidOut is set or not.
+	InvertOut io.Writer
+	// Template prints each selected IP address with the text/template instead
+	// of the lines. It is executed with the fields IP, the address as written
+	// in the input, Version, Line, File, Pattern and Text, the whole line, and
+	// has the functions canonical and classify taking an address. Like
+	// OnlyMatching, each of the matching addresses found by Extract is
+	// printed. It takes precedence over Output and Format. The pattern of
+	// each address is looked up only if the template uses the field Pattern.
+	Template string
+	// NoHeader omits the header row of the csv output, which is otherwise
+	// printed before the first record.
+	NoHeader bool
+	// Color highlights the matched IP addresses with ANSI escape sequences.
+	// Lines selected by Invert match nothing and are not highlighted.
+	Color bool
+	// Jobs is the number of goroutines parsing and matching lines.
+	// Lines are read and printed sequentially, so values less than 2 disable it.
+	Jobs int
+	// NoOrder prints the lines as soon as they are matched with Jobs regardless
+	// of their input order.
+	NoOrder bool
+	// Strict reports each line that is not an IP address to eout with its
+	// line number instead of the number of such lines at the end of the input,
+	// which is reported only when the lines are printed, not with Quiet,
+	// FilesWithMatches, FilesWithoutMatches and Count.
+	Strict bool
+	// AbortOnInvalid stops reading with an error wrapping ErrInvalidIP at the
+	// first line that is not an IP address.
+	AbortOnInvalid bool
+	// OnlyMatching prints the selected IP addresses in canonical form, or in
+	// Format, instead of the lines. With Extract, each of the matching addresses in a line is
+	// printed on its own line. The spaces around a whole-line address are
+	// ignored.
+	OnlyMatching bool
+	// LooseIPv4 also reads the lines of IPv4 addresses in the forms accepted
+	// by inet_aton, e.g. "127.1" and "0x7f000001". See ParseLooseIPv4. It does
+	// not apply to the addresses found by Extract.
+	LooseIPv4 bool
+	// Countries selects only the matched IP addresses in the countries of the
+	// ISO 3166-1 alpha-2 codes, e.g. "US" and "JP", looked up by GeoIP.
+	// The IP addresses without geo data, including networks, are selected
+	// only with NoCountry.
+	Countries []string
+	// GeoIP looks up the countries for Countries, which must be set with it.
+	GeoIP GeoIP
+	// ASNs selects only the matched IP addresses in the autonomous systems of
+	// the numbers, e.g. 13335, looked up by ASNLookup. Networks have no ASN.
+	ASNs []uint
+	// ShowASN appends the autonomous system number of the IP address of each
+	// line looked up by ASNLookup, e.g. "1.1.1.1 AS13335", or AS0 for no ASN
+	// data.
+	ShowASN bool
+	// ASNLookup looks up the autonomous systems for ASNs and ShowASN, which
+	// must be set with it.
+	ASNLookup ASNLookup
+	// PTRMatches selects only the matched IP addresses any of whose PTR
+	// records, without the trailing dot, matches the regular expression. Each
+	// IP address is looked up once over the network by Resolver with
+	// PTRTimeout, and the ones failed to look up are not selected. Up to 16
+	// lookups are made concurrently regardless of Jobs.
+	PTRMatches string
+	// PTRTimeout is the timeout of each PTR lookup. Zero means
+	// DefaultPTRTimeout.
+	PTRTimeout time.Duration
+	// Resolver looks up the PTR records for PTRMatches. Nil means
+	// net.DefaultResolver.
+	Resolver Resolver
+	// Extract matches the IP addresses embedded in each line, e.g. in access
+	// logs, instead of the whole line. A line is selected if any of them
+	// matches, and Unique and Sort use the first matching one. Normalize and
+	// Color rewrite the addresses in place. Lines without IP addresses are
+	// handled like the ones that are not IP addresses.
+	Extract bool
+	// Hosts matches the first field of each line of a hosts file, e.g.
+	// "127.0.0.1 localhost", and prints the whole line. Blank lines and
+	// comments starting with "#" are skipped silently, or printed with
+	// Passthrough. Extract takes precedence over Hosts.
+	Hosts bool
+	// SkipComments ignores the blank lines and the lines starting with
+	// CommentChar after spaces, which are neither reported nor counted as
+	// the lines that are not IP addresses. They are printed with Passthrough.
+	SkipComments bool
+	// CommentChar is the prefix of the comment lines for SkipComments, or
+	// DefaultCommentChar if empty.
+	CommentChar string
+	// Field matches the nth field of each line, counted from 1, e.g. a
+	// column of CSV, and prints the whole line. Zero matches the whole line.
+	// The lines with fewer fields are handled like the ones that are not IP
+	// addresses: skipped, or reported with Strict. Extract and Hosts take
+	// precedence over Field.
+	Field int
+	// Delimiter separates the fields for Field, or runs of whitespace if
+	// empty. Quoted fields of CSV are not supported, and the spaces around
+	// the field are ignored.
+	Delimiter string
+	// Passthrough prints the lines that are not IP addresses as they are
+	// instead of skipping them, whether Invert is set or not. They are not
+	// counted as selected lines, and printed before the sorted lines with Sort.
+	// It cannot be used with the structured Output, which the lines would
+	// break.
+	Passthrough bool
+	// Follow keeps reading the lines appended to the file searched by the
+	// root command like tail -F until the context is done, reopening it when
+	// it is truncated or replaced by rotation. The file is not decompressed
+	// and its lines are evaluated one by one regardless of Jobs.
+	Follow bool
+	// LineBuffered flushes the output after every line so that the lines
+	// are visible as soon as they are selected, e.g. in a pipeline.
+	// Otherwise, the output is buffered for throughput and flushed at the
+	// end. Follow implies it.
+	LineBuffered bool
+	// NullData splits the input on NUL instead of newline, e.g. the output of
+	// find -print0, so that the lines may contain newlines. The lines are
+	// numbered and counted in the same way.
+	NullData bool
+	// NullOutput terminates the output lines, including the names of the
+	// inputs and the counts, by NUL instead of newline.
+	NullOutput bool
+	// MaxLineLength is the maximum length of an input line in bytes.
+	// A longer line stops reading with an error.
+	MaxLineLength int
+	// Filename is the name of the input. An empty name stands for the standard input.
+	Filename string
+	// WithFilename prefixes each line and the count with Filename.
+	WithFilename bool
+}
+
+// RunSummary holds the numbers of the lines read by RunWithSummary, not to be
+// confused with the numbers of lines per pattern printed with Options.Stats.
+type RunSummary struct {
+	// Lines is the number of the lines read, which is the sum of Parsed and
+	// Skipped.
+	Lines int
+	// Parsed is the number of the lines that are IP addresses or networks.
+	Parsed int
+	// Skipped is the number of the lines that are not IP addresses, including
+	// the ones reported with Strict, passed through with Passthrough and
+	// ignored with SkipComments.
+	Skipped int
+	// Matched is the number of the selected lines, which RunWithOptions
+	// returns.
+	Matched int
+}
+
 func Run(in io.Reader, out, eout io.Writer, ps []string) error {
+	return RunContext(context.Background(), in, out, eout, ps)
+}
+
+// RunContext is like Run but stops reading and returns the error of ctx when
+// ctx is done.
+func RunContext(ctx context.Context, in io.Reader, out, eout io.Writer, ps []string) error {
+	_, err := RunWithSummary(ctx, in, out, eout, ps, Options{})
+	return err
+}
+
+// RunWithOptions prints the lines of in selected by the patterns and returns
+// the number of selected lines. Without patterns, every line that is an IP
+// address is selected.
+func RunWithOptions(in io.Reader, out, eout io.Writer, ps []string, opts Options) (int, error) {
+	summary, err := RunWithSummary(context.Background(), in, out, eout, ps, opts)
+	return summary.Matched, err
+}
+
+// RunWithSummary is like RunWithOptions but returns the numbers of the lines
+// read, parsed, skipped and selected. It stops reading and returns the error
+// of ctx when ctx is done, with the numbers of the lines read until then.
+func RunWithSummary(ctx context.Context, in io.Reader, out, eout io.Writer, ps []string, opts Options) (RunSummary, error) {
+	if err := checkFormat(opts.Format); err != nil {
+		return RunSummary{}, err
+	}
+	if err := checkOutput(opts.Output); err != nil {
+		return RunSummary{}, err
+	}
+
 	// load patterns
-	patterns := make([]Pattern, len(ps))
-	for i, p := range ps {
-		pattern, err := ParsePattern(p)
-		if err != nil {
-			return fmt.Errorf("invalid pattern: %s", p)
+	ps = append(slices.Clip(ps), negatePatterns(opts.Excludes)...)
+	if opts.StrictCIDR {
+		if err := checkStrictCIDR(ps); err != nil {
+			return RunSummary{}, err
 		}
-		patterns[i] = pattern
+	}
+	m, err := newMatcher(ps, opts)
+	if err != nil {
+		return RunSummary{}, err
 	}
 
-	// read input stream line by line
-	sc := bufio.NewScanner(in)
-	for sc.Scan() {
-		line := sc.Text()
-		// parse line
-		ip, err := ParseIp(line)
-		if err != nil {
-			continue
-		}
+	s, err := newSearcher(m, out, eout, opts)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	defer s.flushWriters()
+	if _, err := s.search(ctx, in, opts.Filename, opts.WithFilename); err != nil {
+		return s.summary, err
+	}
+	s.flush()
+	return s.summary, nil
+}
 
-		// match patterns
-		for _, pattern := range patterns {
-			if pattern.Match(ip) {
-				fmt.Fprintln(out, line)
-			}
+// found returns the number of the lines or files found in an input, which
+// decides the exit status.
+func found(opts Options, matched int) int {
+	// the input is listed when nothing matched
+	if opts.FilesWithoutMatches {
+		if matched == 0 {
+			return 1
 		}
+		return 0
 	}
+	return matched
+}
 
-	return nil
+// errNoMatch is returned by the root command when no lines are selected.
+var errNoMatch = errors.New("no lines selected")
+
+// checkMatched returns errNoMatch without printing it when nothing matched.
+func checkMatched(cmd *cobra.Command, matched int) error {
+	if matched > 0 {
+		return nil
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return errNoMatch
 }
 
+// Execute runs the root command and exits like grep: 0 when any line is
+// selected, 1 when none is selected and 2 on an error.
 func Execute() {
 	err := NewRootCmd().Execute()
-	if err != nil {
+	if errors.Is(err, errNoMatch) {
 		os.Exit(1)
 	}
+	if err != nil {
+		os.Exit(2)
+	}
 }
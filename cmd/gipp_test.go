@@ -0,0 +1,2487 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+var samplePatterns = []string{
+	"192.168.57.0/24",
+	"10.222.0.0/16",
+	"fe80::5400:0:0:0/72",
+}
+
+const sampleInput = `192.168.176.105
+192.168.207.29
+10.133.107.21
+172.22.6.67
+10.222.200.200
+10.223.254.126
+10.174.2.18
+172.25.172.33
+192.168.179.165
+192.168.57.163
+172.22.246.192
+10.113.99.252
+192.168.107.4
+192.168.57.4
+192.168.46.194
+fe80::3454:183e:39aa:9a3a
+fe80::bc89:45d2:38e0:f715
+fe80::3960:a43f:df0d:3f90
+fe80::2d9d:af52:5ce3:bf10
+fe80::9e50:ffc3:85b6:be65
+fe80::1a51:6f53:c20f:e2fb
+fe80::809c:cf3b:25a0:c3b4
+fe80::a837:14a4:1069:7ae4
+fe80::6800:b8a1:dc84:4b78
+fe80::d0da:cb6e:5125:ddff
+fe80::5474:3fa5:9fca:99f3
+fe80::6690:fb06:8824:fbf1
+fe80::f738:2998:45ea:97c4
+fe80::77bc:c97c:2f71:22b6
+fe80::4493:f163:e9c5:31bd`
+
+// writeFiles writes each content into a file in a temporary directory and
+// returns their paths.
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("input%d.txt", i+1))
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+// execute runs the root command with args and returns its standard output.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	outbuf := &bytes.Buffer{}
+	root := cmd.NewRootCmd()
+	root.SetArgs(args)
+	root.SetOut(outbuf)
+	root.SetErr(&bytes.Buffer{})
+	err := root.Execute()
+	return outbuf.String(), err
+}
+
+func TestRunWithOptionsInvert(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		input       string
+		expected    string
+	}{
+		{
+			description: "IPv4 Inverted",
+			patterns:    []string{"192.168.57.0/24", "10.222.0.0/16"},
+			input: `192.168.57.163
+10.222.200.200
+10.223.254.126
+172.22.6.67`,
+			expected: `10.223.254.126
+172.22.6.67
+`,
+		},
+		{
+			description: "IPv6 Inverted",
+			patterns:    []string{"fe80::5400:0:0:0/72"},
+			input: `fe80::5474:3fa5:9fca:99f3
+fe80::3454:183e:39aa:9a3a
+2001:db8::1`,
+			expected: `fe80::3454:183e:39aa:9a3a
+2001:db8::1
+`,
+		},
+		{
+			description: "Inverted with Other Version and Invalid Lines",
+			patterns:    []string{"10.0.0.0/8"},
+			input: `10.0.0.1
+hello
+
+fe80::1
+192.168.0.1`,
+			expected: `fe80::1
+192.168.0.1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(
+			strings.NewReader(tc.input),
+			outbuf,
+			eoutbuf,
+			tc.patterns,
+			cmd.Options{Invert: true},
+		)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsAll(t *testing.T) {
+	input := `10.0.0.1
+10.1.0.1
+10.1.2.3
+192.168.0.1
+10.1.0.0/24
+10.0.0.0/16`
+
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "All",
+			patterns:    []string{"10.0.0.0/8", "10.1.0.0/16"},
+			opts:        cmd.Options{All: true},
+			expected:    "10.1.0.1\n10.1.2.3\n10.1.0.0/24\n",
+		},
+		{
+			description: "Any",
+			patterns:    []string{"10.0.0.0/8", "10.1.0.0/16"},
+			expected:    "10.0.0.1\n10.1.0.1\n10.1.2.3\n10.1.0.0/24\n10.0.0.0/16\n",
+		},
+		{
+			description: "All Inverted",
+			patterns:    []string{"10.0.0.0/8", "10.1.0.0/16"},
+			opts:        cmd.Options{All: true, Invert: true},
+			expected:    "10.0.0.1\n192.168.0.1\n10.0.0.0/16\n",
+		},
+		{
+			description: "All with Negated Pattern",
+			patterns:    []string{"10.0.0.0/8", "!10.1.0.0/16"},
+			opts:        cmd.Options{All: true},
+			expected:    "10.0.0.1\n10.0.0.0/16\n",
+		},
+		{
+			description: "All Prefix and Suffix",
+			patterns:    []string{"10.0.0.0/8", "0.0.0.1/-8"},
+			opts:        cmd.Options{All: true},
+			expected:    "10.0.0.1\n10.1.0.1\n",
+		},
+		{
+			description: "All of Different Versions",
+			patterns:    []string{"10.0.0.0/8", "2001:db8::/32"},
+			opts:        cmd.Options{All: true},
+			expected:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdAll(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n10.1.0.1\n192.168.0.1\n")
+
+	out, err := execute(t, "--all", "-e", "10.0.0.0/8", "-e", "10.1.0.0/16", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "10.1.0.1\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
+
+func TestRunWithOptionsCount(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Count",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{Count: true},
+			input:       sampleInput,
+			expected:    "4\n",
+		},
+		{
+			description: "Inverted Count",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{Count: true, Invert: true},
+			input:       sampleInput,
+			expected:    "26\n",
+		},
+		{
+			description: "Count Line Matching Multiple Patterns Once",
+			patterns:    []string{"10.0.0.0/8", "10.222.0.0/16"},
+			opts:        cmd.Options{Count: true},
+			input:       sampleInput,
+			expected:    "5\n",
+		},
+		{
+			description: "Count with Filename",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{Count: true, Filename: "hitlist.txt", WithFilename: true},
+			input:       sampleInput,
+			expected:    "hitlist.txt:4\n",
+		},
+		{
+			description: "Count No Match",
+			patterns:    []string{"127.0.0.0/8"},
+			opts:        cmd.Options{Count: true},
+			input:       sampleInput,
+			expected:    "0\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdCountFiles(t *testing.T) {
+	paths := writeFiles(t, sampleInput, "10.222.0.1\n10.222.0.2\n")
+
+	out, err := execute(t, "-c", "-e", "10.222.0.0/16", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := paths[0] + ":1\n" + paths[1] + ":2\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	out, err = execute(t, "-c", "-e", "10.222.0.0/16", paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "2\n" {
+		t.Errorf("expected: %v, got: %v", "2\n", out)
+	}
+}
+
+func TestRootCmdStdinFile(t *testing.T) {
+	paths := writeFiles(t, "10.222.0.1\n10.222.0.2\n")
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{
+			description: "Count per File",
+			args:        []string{"-c", paths[0], "-"},
+			expected:    paths[0] + ":2\n(standard input):1\n",
+		},
+		{
+			description: "Files with Matches",
+			args:        []string{"-l", "-", paths[0]},
+			expected:    "(standard input)\n" + paths[0] + "\n",
+		},
+		{
+			description: "Files without Match",
+			args:        []string{"-L", "-e", "!10.222.0.3", paths[0], "-"},
+			expected:    "(standard input)\n",
+		},
+		{
+			description: "With Filename",
+			args:        []string{"-H", "-"},
+			expected:    "(standard input):10.222.0.3\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		root := cmd.NewRootCmd()
+		root.SetArgs(append([]string{"-e", "10.222.0.0/16"}, tc.args...))
+		root.SetIn(strings.NewReader("172.16.0.1\n10.222.0.3\n"))
+		root.SetOut(outbuf)
+		root.SetErr(&bytes.Buffer{})
+		if err := root.Execute(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunOverlappingPatterns(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		input       string
+		expected    string
+	}{
+		{
+			description: "IPv4 Overlapping Patterns",
+			patterns:    []string{"10.0.0.0/8", "10.222.0.0/16"},
+			input: `10.222.200.200
+10.1.1.1
+192.168.0.1`,
+			expected: `10.222.200.200
+10.1.1.1
+`,
+		},
+		{
+			description: "IPv6 Overlapping Patterns",
+			patterns:    []string{"fe80::/10", "fe80::5400:0:0:0/72", "::99f3/-16"},
+			input: `fe80::5474:3fa5:9fca:99f3
+fe80::3454:183e:39aa:9a3a`,
+			expected: `fe80::5474:3fa5:9fca:99f3
+fe80::3454:183e:39aa:9a3a
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		err := cmd.Run(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsLineNumber(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Line Number",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{LineNumber: true},
+			input:       sampleInput,
+			expected: `5:10.222.200.200
+10:192.168.57.163
+14:192.168.57.4
+26:fe80::5474:3fa5:9fca:99f3
+`,
+		},
+		{
+			description: "Line Number Counts Invalid Lines",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{LineNumber: true},
+			input: `# header
+10.0.0.1
+
+hello
+10.0.0.2`,
+			expected: `2:10.0.0.1
+5:10.0.0.2
+`,
+		},
+		{
+			description: "Inverted Line Number",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{LineNumber: true, Invert: true},
+			input: `10.0.0.1
+192.168.0.1`,
+			expected: `2:192.168.0.1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdLineNumberFiles(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n192.168.0.1\n", "192.168.0.2\n10.0.0.2\n")
+
+	out, err := execute(t, "-n", "-h", "-e", "10.0.0.0/8", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "1:10.0.0.1\n2:10.0.0.2\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
+
+func TestRunWithOptionsMatched(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    int
+	}{
+		{
+			description: "Matched",
+			patterns:    samplePatterns,
+			expected:    4,
+		},
+		{
+			description: "Not Matched",
+			patterns:    []string{"127.0.0.0/8"},
+			expected:    0,
+		},
+		{
+			description: "Inverted Matched",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{Invert: true},
+			expected:    26,
+		},
+		{
+			description: "Count Matched",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{Count: true},
+			expected:    4,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		matched, err := cmd.RunWithOptions(strings.NewReader(sampleInput), &bytes.Buffer{}, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if matched != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, matched)
+		}
+	}
+}
+
+func TestRootCmdNoMatch(t *testing.T) {
+	paths := writeFiles(t, sampleInput)
+
+	_, err := execute(t, "-e", "127.0.0.0/8", paths[0])
+	if err == nil || err.Error() != "no lines selected" {
+		t.Errorf("expected no lines selected error, got: %v", err)
+	}
+
+	_, err = execute(t, "-e", "invalid", paths[0])
+	if err == nil || err.Error() == "no lines selected" {
+		t.Errorf("expected invalid pattern error, got: %v", err)
+	}
+}
+
+// onceReader returns its data on the first Read and records any further Read.
+type onceReader struct {
+	data      string
+	read      bool
+	readAfter bool
+}
+
+func (r *onceReader) Read(p []byte) (int, error) {
+	if r.read {
+		r.readAfter = true
+		return 0, io.EOF
+	}
+	r.read = true
+	return copy(p, r.data), nil
+}
+
+func TestRunWithOptionsQuiet(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    int
+		readAfter   bool
+	}{
+		{
+			description: "Quiet Matched",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Quiet: true},
+			input:       "192.168.0.1\n10.0.0.1\n10.0.0.2\n",
+			expected:    1,
+			readAfter:   false,
+		},
+		{
+			description: "Quiet Not Matched",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Quiet: true},
+			input:       "192.168.0.1\n192.168.0.2\n",
+			expected:    0,
+			readAfter:   true,
+		},
+		{
+			description: "Quiet Count",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Quiet: true, Count: true},
+			input:       "10.0.0.1\n10.0.0.2\n",
+			expected:    1,
+			readAfter:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		in := &onceReader{data: tc.input}
+		outbuf := &bytes.Buffer{}
+		matched, err := cmd.RunWithOptions(in, outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if matched != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, matched)
+		}
+		if outbuf.Len() != 0 {
+			t.Errorf("expected no output, got: %v", outbuf.String())
+		}
+		if in.readAfter != tc.readAfter {
+			t.Errorf("expected read after the first match: %v, got: %v", tc.readAfter, in.readAfter)
+		}
+	}
+}
+
+func TestRootCmdFilesWithMatches(t *testing.T) {
+	paths := writeFiles(t,
+		"10.0.0.1\n10.0.0.2\n",
+		"192.168.0.1\n",
+		"hello\n192.168.0.2\n10.0.0.3\n",
+	)
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+		expectedErr error
+	}{
+		{
+			description: "Files with Matches",
+			args:        append([]string{"-l", "-e", "10.0.0.0/8"}, paths...),
+			expected:    paths[0] + "\n" + paths[2] + "\n",
+		},
+		{
+			description: "Files with Matches Single File",
+			args:        []string{"-l", "-e", "10.0.0.0/8", paths[0]},
+			expected:    paths[0] + "\n",
+		},
+		{
+			description: "Files with Matches No Match",
+			args:        append([]string{"-l", "-e", "172.16.0.0/12"}, paths...),
+			expected:    "",
+			expectedErr: fmt.Errorf("no lines selected"),
+		},
+		{
+			description: "Files without Matches",
+			args:        append([]string{"-L", "-e", "10.0.0.0/8"}, paths...),
+			expected:    paths[1] + "\n",
+		},
+		{
+			description: "Files without Matches All Matched",
+			args:        append([]string{"-L", "-e", "10.0.0.0/8,192.168.0.0/16"}, paths...),
+			expected:    "",
+			expectedErr: fmt.Errorf("no lines selected"),
+		},
+		{
+			description: "Files with Matches Inverted",
+			args:        append([]string{"-l", "-v", "-e", "10.0.0.0/8"}, paths...),
+			expected:    paths[1] + "\n" + paths[2] + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, tc.args...)
+		if fmt.Sprint(err) != fmt.Sprint(tc.expectedErr) {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
+
+func TestRunWithOptionsFilesWithMatchesStdin(t *testing.T) {
+	outbuf := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(sampleInput), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{FilesWithMatches: true})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if outbuf.String() != "(standard input)\n" {
+		t.Errorf("expected: %v, got: %v", "(standard input)\n", outbuf.String())
+	}
+}
+
+func TestRootCmdFilename(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n192.168.0.1\n", "192.168.0.2\n10.0.0.2\n")
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{
+			description: "Multiple Files",
+			args:        []string{"-e", "10.0.0.0/8", paths[0], paths[1]},
+			expected:    paths[0] + ":10.0.0.1\n" + paths[1] + ":10.0.0.2\n",
+		},
+		{
+			description: "Multiple Files with Line Number",
+			args:        []string{"-n", "-e", "10.0.0.0/8", paths[0], paths[1]},
+			expected:    paths[0] + ":1:10.0.0.1\n" + paths[1] + ":2:10.0.0.2\n",
+		},
+		{
+			description: "Multiple Files without Filename",
+			args:        []string{"-h", "-e", "10.0.0.0/8", paths[0], paths[1]},
+			expected:    "10.0.0.1\n10.0.0.2\n",
+		},
+		{
+			description: "Single File",
+			args:        []string{"-e", "10.0.0.0/8", paths[0]},
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "Single File with Filename",
+			args:        []string{"-H", "-e", "10.0.0.0/8", paths[0]},
+			expected:    paths[0] + ":10.0.0.1\n",
+		},
+		{
+			description: "Count without Filename",
+			args:        []string{"-c", "-h", "-e", "10.0.0.0/8", paths[0], paths[1]},
+			expected:    "1\n1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, tc.args...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
+
+func TestRunWithOptionsLongLine(t *testing.T) {
+	longLine := strings.Repeat("x", 100*1024)
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		input       string
+		expected    string
+		expectedErr string
+	}{
+		{
+			description: "Long Line within Default Limit",
+			input:       "10.0.0.1\n" + longLine + "\n10.0.0.2\n",
+			expected:    "10.0.0.1\n10.0.0.2\n",
+		},
+		{
+			description: "Long Line over Limit",
+			opts:        cmd.Options{MaxLineLength: 64 * 1024},
+			input:       "10.0.0.1\n" + longLine + "\n10.0.0.2\n",
+			expected:    "10.0.0.1\n",
+			expectedErr: "(standard input):2: line too long",
+		},
+		{
+			description: "Long Line over Limit with Filename",
+			opts:        cmd.Options{MaxLineLength: 1024, Filename: "input.txt"},
+			input:       longLine,
+			expected:    "",
+			expectedErr: "input.txt:1: line too long",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"}, tc.opts)
+		if tc.expectedErr == "" && err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr) {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+	}{
+		{
+			description: "Sequential",
+			opts:        cmd.Options{},
+		},
+		{
+			description: "Parallel",
+			opts:        cmd.Options{Jobs: 4},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		// the lines before the error are still read
+		in := io.MultiReader(strings.NewReader("10.0.0.1\n172.16.0.1\n"), iotest.ErrReader(errRead))
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(in, outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"}, tc.opts)
+		if !errors.Is(err, errRead) {
+			t.Errorf("expected error: %v, got: %v", errRead, err)
+		}
+		if outbuf.String() != "10.0.0.1\n" {
+			t.Errorf("expected: %v, got: %v", "10.0.0.1\n", outbuf.String())
+		}
+	}
+
+	err := cmd.Run(iotest.ErrReader(errRead), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"})
+	if !errors.Is(err, errRead) {
+		t.Errorf("expected error: %v, got: %v", errRead, err)
+	}
+}
+
+func TestRunWithOptionsNormalize(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Normalize IPv6",
+			patterns:    []string{"2001:db8::/32"},
+			opts:        cmd.Options{Normalize: true},
+			input: `2001:0db8:0000:0000:0000:0000:0000:0001
+2001:DB8:0:0:1:0:0:1
+2001:0db8:85a3:0000:0000:8a2e:0370:7334`,
+			expected: `2001:db8::1
+2001:db8::1:0:0:1
+2001:db8:85a3::8a2e:370:7334
+`,
+		},
+		{
+			description: "Normalize IPv4",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Normalize: true},
+			input:       "10.0.0.1\n",
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "Normalize with Line Number and Filename",
+			patterns:    []string{"2001:db8::/32"},
+			opts:        cmd.Options{Normalize: true, LineNumber: true, Filename: "input.txt", WithFilename: true},
+			input:       "10.0.0.1\n2001:0db8::0001\n",
+			expected:    "input.txt:2:2001:db8::1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsUnique(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Unique",
+			patterns:    []string{"10.0.0.0/8", "2001:db8::/32"},
+			opts:        cmd.Options{Unique: true},
+			input: `10.0.0.1
+10.0.0.2
+10.0.0.1
+2001:db8::1
+2001:0db8:0000::1
+2001:db8:0:0:0:0:0:1
+10.0.0.2`,
+			expected: `10.0.0.1
+10.0.0.2
+2001:db8::1
+`,
+		},
+		{
+			description: "Unique Count",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Unique: true, Count: true},
+			input:       "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expected:    "2\n",
+		},
+		{
+			description: "Unique Inverted",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Unique: true, Invert: true},
+			input:       "192.168.0.1\n10.0.0.1\n192.168.0.1\n",
+			expected:    "192.168.0.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdUniqueFiles(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n10.0.0.2\n", "10.0.0.2\n10.0.0.3\n10.0.0.1\n")
+
+	out, err := execute(t, "-u", "-h", "-e", "10.0.0.0/8", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "10.0.0.1\n10.0.0.2\n10.0.0.3\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
+
+func TestRunWithOptionsPerPatternLimit(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Per Pattern Limit",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+			opts:        cmd.Options{PerPatternLimit: 2},
+			input: `10.0.0.1
+192.168.0.1
+10.0.0.2
+10.0.0.3
+192.168.0.2
+192.168.0.3
+10.0.0.4`,
+			expected: `10.0.0.1
+192.168.0.1
+10.0.0.2
+192.168.0.2
+`,
+		},
+		{
+			description: "Per Pattern Limit of Overlapping Patterns",
+			patterns:    []string{"10.0.0.0/8", "10.1.0.0/16"},
+			opts:        cmd.Options{PerPatternLimit: 1},
+			input:       "10.1.0.1\n10.1.0.2\n10.0.0.1\n10.0.0.2\n",
+			// 10.1.0.1 fills both of the patterns
+			expected: "10.1.0.1\n",
+		},
+		{
+			description: "Per Pattern Limit of a Line Matching Several Patterns",
+			patterns:    []string{"10.0.0.0/8", "0.0.0.1/-8"},
+			opts:        cmd.Options{PerPatternLimit: 1},
+			input:       "10.0.0.2\n10.0.0.1\n192.168.0.1\n10.0.0.3\n",
+			// 10.0.0.1 is printed for the room of 0.0.0.1/-8
+			expected: "10.0.0.2\n10.0.0.1\n",
+		},
+		{
+			description: "Per Pattern Limit of a Pattern Given Twice",
+			patterns:    []string{"10.0.0.0/8", "10.0.0.0/8"},
+			opts:        cmd.Options{PerPatternLimit: 2},
+			input:       "10.0.0.1\n10.0.0.2\n10.0.0.3\n",
+			expected:    "10.0.0.1\n10.0.0.2\n",
+		},
+		{
+			description: "Per Pattern Limit Unique",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{PerPatternLimit: 2, Unique: true},
+			input:       "10.0.0.1\n10.0.0.1\n10.0.0.2\n10.0.0.3\n",
+			// the duplicate is not attributed
+			expected: "10.0.0.1\n10.0.0.2\n",
+		},
+		{
+			description: "Per Pattern Limit Count",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+			opts:        cmd.Options{PerPatternLimit: 1, Count: true},
+			input:       "10.0.0.1\n10.0.0.2\n192.168.0.1\n",
+			expected:    "2\n",
+		},
+		{
+			description: "Per Pattern Limit Extract",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+			opts:        cmd.Options{PerPatternLimit: 1, Extract: true},
+			input:       "from 10.0.0.1\nfrom 10.0.0.2 to 192.168.0.1\nfrom 10.0.0.3 to 192.168.0.2\n",
+			expected:    "from 10.0.0.1\nfrom 10.0.0.2 to 192.168.0.1\n",
+		},
+		{
+			description: "Per Pattern Limit Network",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{PerPatternLimit: 1},
+			input:       "10.1.0.0/16\n10.2.0.0/16\n",
+			expected:    "10.1.0.0/16\n",
+		},
+		{
+			description: "Per Pattern Limit Parallel",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{PerPatternLimit: 300, Jobs: 4, OnlyMatching: true},
+			input:       strings.Repeat("10.0.0.1\n", 1000),
+			expected:    strings.Repeat("10.0.0.1\n", 300),
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+
+	_, err := cmd.RunWithOptions(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{PerPatternLimit: -1})
+	if err == nil {
+		t.Errorf("expected an error of the negative limit")
+	}
+}
+
+func TestRootCmdPerPatternLimitFiles(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n192.168.0.1\n", "10.0.0.2\n192.168.0.2\n10.0.0.3\n")
+
+	// the lines are counted across the files
+	out, err := execute(t, "--per-pattern-limit", "1", "-h", "-e", "10.0.0.0/8", "-e", "192.168.0.0/16", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "10.0.0.1\n192.168.0.1\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	_, err = execute(t, "--per-pattern-limit", "1", "-v", "-e", "10.0.0.0/8", paths[0])
+	if err == nil {
+		t.Errorf("expected an error of --per-pattern-limit with -v")
+	}
+}
+
+func TestRootCmdPerPatternLimitWithoutPatterns(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n192.168.0.1\n10.0.0.2\n::1\n")
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{
+			description: "IPv4",
+			args:        []string{"-4", "--per-pattern-limit", "1"},
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "All IPs",
+			args:        []string{"--all-ips", "--per-pattern-limit", "2"},
+			expected:    "10.0.0.1\n192.168.0.1\n",
+		},
+		{
+			description: "Exclude",
+			args:        []string{"--exclude", "192.168.0.0/16", "--per-pattern-limit", "1"},
+			expected:    "10.0.0.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append(tc.args, paths[0])...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
+
+func TestRunWithOptionsSort(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "Sort IPv4",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Sort: true},
+			input: `10.0.0.10
+10.0.0.9
+10.1.0.0
+10.0.0.1
+10.0.0.100`,
+			expected: `10.0.0.1
+10.0.0.9
+10.0.0.10
+10.0.0.100
+10.1.0.0
+`,
+		},
+		{
+			description: "Sort Mixed Versions",
+			patterns:    []string{"10.0.0.0/8", "fe80::/10", "::ffff:0:0/96"},
+			opts:        cmd.Options{Sort: true},
+			input: `fe80::2
+::ffff:10.0.0.1
+10.0.0.2
+fe80::1
+10.0.0.1`,
+			expected: `10.0.0.1
+10.0.0.2
+::ffff:10.0.0.1
+fe80::1
+fe80::2
+`,
+		},
+		{
+			description: "Sort with Line Number Keeps Input Order of Same Addresses",
+			patterns:    []string{"10.0.0.0/8"},
+			opts:        cmd.Options{Sort: true, LineNumber: true},
+			input: `10.0.0.2
+10.0.0.1
+10.0.0.2`,
+			expected: `2:10.0.0.1
+1:10.0.0.2
+3:10.0.0.2
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdSortFiles(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.3\n10.0.0.1\n", "10.0.0.2\n")
+
+	out, err := execute(t, "--sort", "-h", "-e", "10.0.0.0/8", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "10.0.0.1\n10.0.0.2\n10.0.0.3\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	_, err = execute(t, "--sort", "-c", "-e", "10.0.0.0/8", paths[0])
+	if err == nil {
+		t.Errorf("expected error for --sort with -c")
+	}
+}
+
+// endlessReader returns IP address lines forever and calls onRead on every Read.
+type endlessReader struct {
+	onRead func()
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	r.onRead()
+	line := "10.0.0.1\n"
+	n := 0
+	for n+len(line) <= len(p) {
+		n += copy(p[n:], line)
+	}
+	return n, nil
+}
+
+func TestRunWithSummary(t *testing.T) {
+	input := `10.0.0.1
+hello
+192.168.0.1
+10.0.0.1
+
+# comment
+10.0.0.0/24
+fe80::1
+10.0.0.256`
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    cmd.RunSummary
+	}{
+		{
+			description: "Default",
+			opts:        cmd.Options{},
+			expected:    cmd.RunSummary{Lines: 9, Parsed: 5, Skipped: 4, Matched: 3},
+		},
+		{
+			description: "Inverted",
+			opts:        cmd.Options{Invert: true},
+			expected:    cmd.RunSummary{Lines: 9, Parsed: 5, Skipped: 4, Matched: 2},
+		},
+		{
+			description: "Unique",
+			opts:        cmd.Options{Unique: true},
+			expected:    cmd.RunSummary{Lines: 9, Parsed: 5, Skipped: 4, Matched: 2},
+		},
+		{
+			description: "Skip Comments and Passthrough",
+			opts:        cmd.Options{SkipComments: true, Passthrough: true},
+			expected:    cmd.RunSummary{Lines: 9, Parsed: 5, Skipped: 4, Matched: 3},
+		},
+		{
+			description: "Version",
+			opts:        cmd.Options{Version: 6},
+			expected:    cmd.RunSummary{Lines: 9, Parsed: 5, Skipped: 4, Matched: 0},
+		},
+		{
+			description: "Quiet",
+			opts:        cmd.Options{Quiet: true},
+			expected:    cmd.RunSummary{Lines: 1, Parsed: 1, Skipped: 0, Matched: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		summary, err := cmd.RunWithSummary(context.Background(), strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if summary != tc.expected {
+			t.Errorf("expected: %+v, got: %+v", tc.expected, summary)
+		}
+	}
+}
+
+func TestRunWithSummaryAbort(t *testing.T) {
+	summary, err := cmd.RunWithSummary(context.Background(), strings.NewReader("10.0.0.1\nhello\n10.0.0.2\n"), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{AbortOnInvalid: true})
+	if !errors.Is(err, cmd.ErrInvalidIP) {
+		t.Errorf("expected: %v, got: %v", cmd.ErrInvalidIP, err)
+	}
+	expected := cmd.RunSummary{Lines: 2, Parsed: 1, Skipped: 1, Matched: 1}
+	if summary != expected {
+		t.Errorf("expected: %+v, got: %+v", expected, summary)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel after some lines are read
+	reads := 0
+	in := &endlessReader{onRead: func() {
+		reads++
+		if reads == 3 {
+			cancel()
+		}
+	}}
+
+	done := make(chan error)
+	outbuf := &bytes.Buffer{}
+	go func() {
+		done <- cmd.RunContext(ctx, in, outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected error: %v, got: %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return after cancel")
+	}
+	if outbuf.Len() == 0 {
+		t.Errorf("expected output before cancel")
+	}
+}
+
+func TestRunContextBackground(t *testing.T) {
+	outbuf := &bytes.Buffer{}
+	err := cmd.RunContext(context.Background(), strings.NewReader(sampleInput), outbuf, &bytes.Buffer{}, samplePatterns)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `10.222.200.200
+192.168.57.163
+192.168.57.4
+fe80::5474:3fa5:9fca:99f3
+`
+	if outbuf.String() != expected {
+		t.Errorf("expected: %v, got: %v", expected, outbuf.String())
+	}
+}
+
+func TestRootCmdPatternFile(t *testing.T) {
+	paths := writeFiles(t, sampleInput, "# sample patterns\n192.168.57.0/24\n\n  10.222.0.0/16  \n", "fe80::5400:0:0:0/72\n")
+
+	out, err := execute(t, "-f", paths[1], "-f", paths[2], paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `10.222.200.200
+192.168.57.163
+192.168.57.4
+fe80::5474:3fa5:9fca:99f3
+`
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	out, err = execute(t, "-c", "-f", paths[1], "-e", "fe80::5400:0:0:0/72", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "4\n" {
+		t.Errorf("expected: %v, got: %v", "4\n", out)
+	}
+}
+
+func TestRunWithOptionsExcludes(t *testing.T) {
+	input := "10.0.0.1\n10.222.0.1\n192.168.0.1\n172.16.0.1\n"
+
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Include Only",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+			opts:        cmd.Options{},
+			expected:    "10.0.0.1\n10.222.0.1\n192.168.0.1\n",
+		},
+		{
+			description: "Exclude Only",
+			opts:        cmd.Options{Excludes: []string{"10.0.0.0/8"}},
+			expected:    "192.168.0.1\n172.16.0.1\n",
+		},
+		{
+			description: "Include and Exclude",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+			opts:        cmd.Options{Excludes: []string{"10.222.0.0/16", "192.168.0.0/16"}},
+			expected:    "10.0.0.1\n",
+		},
+		{
+			description: "Exclude with Negated Pattern",
+			patterns:    []string{"10.0.0.0/8", "!10.0.0.1"},
+			opts:        cmd.Options{Excludes: []string{"10.222.0.0/16"}},
+			expected:    "",
+		},
+		{
+			description: "Inverted",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+			opts:        cmd.Options{Excludes: []string{"10.222.0.0/16"}, Invert: true},
+			expected:    "10.222.0.1\n172.16.0.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdExclude(t *testing.T) {
+	paths := writeFiles(t, sampleInput)
+
+	out, err := execute(t, "-c", "--exclude", "192.168.0.0/16,fe80::/10", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "8\n" {
+		t.Errorf("expected: %v, got: %v", "8\n", out)
+	}
+
+	out, err = execute(t, "-e", "10.0.0.0/8", "--exclude", "10.0.0.0/9", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "10.133.107.21\n10.222.200.200\n10.223.254.126\n10.174.2.18\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	_, err = execute(t, "--exclude", "10.0.0.0/33", paths[0])
+	var perr *cmd.PatternError
+	if !errors.As(err, &perr) {
+		t.Errorf("expected a *cmd.PatternError, got: %v", err)
+	}
+}
+
+func TestRunWithOptionsSplit(t *testing.T) {
+	input := "10.0.0.1\nhello\n192.168.0.1\n10.0.0.1\n\nfe80::1\n10.0.0.2"
+
+	testCases := []struct {
+		description       string
+		opts              cmd.Options
+		invalidFile       bool
+		expected          string
+		expectedMatched   string
+		expectedUnmatched string
+		expectedInvalid   string
+	}{
+		{
+			description:       "Matched and Unmatched",
+			expected:          "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedMatched:   "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedUnmatched: "hello\n192.168.0.1\n\nfe80::1\n",
+		},
+		{
+			description:       "Invalid File",
+			invalidFile:       true,
+			expected:          "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedMatched:   "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedUnmatched: "192.168.0.1\nfe80::1\n",
+			expectedInvalid:   "hello\n\n",
+		},
+		{
+			description:       "Unique and Count",
+			opts:              cmd.Options{Unique: true, Count: true},
+			invalidFile:       true,
+			expected:          "2\n",
+			expectedMatched:   "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedUnmatched: "192.168.0.1\nfe80::1\n",
+			expectedInvalid:   "hello\n\n",
+		},
+		{
+			description:       "Inverted",
+			opts:              cmd.Options{Invert: true},
+			invalidFile:       true,
+			expected:          "192.168.0.1\nfe80::1\n",
+			expectedMatched:   "192.168.0.1\nfe80::1\n",
+			expectedUnmatched: "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedInvalid:   "hello\n\n",
+		},
+		{
+			description:       "Parallel",
+			opts:              cmd.Options{Jobs: 4},
+			invalidFile:       true,
+			expected:          "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedMatched:   "10.0.0.1\n10.0.0.1\n10.0.0.2\n",
+			expectedUnmatched: "192.168.0.1\nfe80::1\n",
+			expectedInvalid:   "hello\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		matched := &bytes.Buffer{}
+		unmatched := &bytes.Buffer{}
+		invalid := &bytes.Buffer{}
+		opts := tc.opts
+		opts.MatchedOut = matched
+		opts.UnmatchedOut = unmatched
+		if tc.invalidFile {
+			opts.InvalidOut = invalid
+		}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, []string{"10.0.0.0/8"}, opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		if matched.String() != tc.expectedMatched {
+			t.Errorf("expected: %v, got: %v", tc.expectedMatched, matched.String())
+		}
+		if unmatched.String() != tc.expectedUnmatched {
+			t.Errorf("expected: %v, got: %v", tc.expectedUnmatched, unmatched.String())
+		}
+		if invalid.String() != tc.expectedInvalid {
+			t.Errorf("expected: %v, got: %v", tc.expectedInvalid, invalid.String())
+		}
+	}
+}
+
+func TestRootCmdSplitFiles(t *testing.T) {
+	input := sampleInput + "\nhello\n"
+	paths := writeFiles(t, input)
+	dir := t.TempDir()
+	matchedPath := filepath.Join(dir, "matched.txt")
+	unmatchedPath := filepath.Join(dir, "unmatched.txt")
+	invalidPath := filepath.Join(dir, "invalid.txt")
+
+	_, err := execute(t, "-q", "--matched-file", matchedPath, "--unmatched-file", unmatchedPath, "--invalid-file", invalidPath, "-e", "10.0.0.0/8,fe80::/10", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// -q stops reading at the first selected line
+	expectedFiles := map[string]string{
+		matchedPath:   "10.133.107.21\n",
+		unmatchedPath: "192.168.176.105\n192.168.207.29\n",
+		invalidPath:   "",
+	}
+	for path, expected := range expectedFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected {
+			t.Errorf("expected: %v, got: %v", expected, string(b))
+		}
+	}
+
+	// every line goes to exactly one of the files
+	_, err = execute(t, "--matched-file", matchedPath, "--unmatched-file", unmatchedPath, "--invalid-file", invalidPath, "-e", "10.0.0.0/8,fe80::/10", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var lines []string
+	for _, path := range []string{matchedPath, unmatchedPath, invalidPath} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")...)
+	}
+	slices.Sort(lines)
+	expectedLines := strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+	slices.Sort(expectedLines)
+	if !slices.Equal(lines, expectedLines) {
+		t.Errorf("expected: %v, got: %v", expectedLines, lines)
+	}
+}
+
+func TestRootCmdInvertFile(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n172.16.0.1\nhello\n10.0.0.2\n\n192.168.0.1\n")
+	dir := t.TempDir()
+	invertPath := filepath.Join(dir, "invert.txt")
+	invalidPath := filepath.Join(dir, "invalid.txt")
+	unmatchedPath := filepath.Join(dir, "unmatched.txt")
+
+	testCases := []struct {
+		description       string
+		args              []string
+		expectedInvert    string
+		expectedInvalid   string
+		expectedUnmatched string
+	}{
+		{
+			description:    "Invert File",
+			args:           []string{"--invert-file", invertPath},
+			expectedInvert: "172.16.0.1\n192.168.0.1\n",
+		},
+		{
+			description:     "Invert File with Invalid File",
+			args:            []string{"--invert-file", invertPath, "--invalid-file", invalidPath},
+			expectedInvert:  "172.16.0.1\n192.168.0.1\n",
+			expectedInvalid: "hello\n\n",
+		},
+		{
+			description:       "Invert File with Unmatched File",
+			args:              []string{"--invert-file", invertPath, "--unmatched-file", unmatchedPath},
+			expectedInvert:    "172.16.0.1\n192.168.0.1\n",
+			expectedUnmatched: "172.16.0.1\nhello\n\n192.168.0.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		os.Remove(invalidPath)
+		os.Remove(unmatchedPath)
+		out, err := execute(t, append(append(tc.args, "-e", "10.0.0.0/8"), paths[0])...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		// the matches are still printed
+		if out != "10.0.0.1\n10.0.0.2\n" {
+			t.Errorf("expected: %v, got: %v", "10.0.0.1\n10.0.0.2\n", out)
+		}
+		b, err := os.ReadFile(invertPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.expectedInvert {
+			t.Errorf("expected: %v, got: %v", tc.expectedInvert, string(b))
+		}
+		b, _ = os.ReadFile(invalidPath)
+		if string(b) != tc.expectedInvalid {
+			t.Errorf("expected: %v, got: %v", tc.expectedInvalid, string(b))
+		}
+		b, _ = os.ReadFile(unmatchedPath)
+		if string(b) != tc.expectedUnmatched {
+			t.Errorf("expected: %v, got: %v", tc.expectedUnmatched, string(b))
+		}
+	}
+}
+
+func TestRunWithOptionsMapIPv4(t *testing.T) {
+	input := "192.168.1.1\n::ffff:192.168.1.2\n::ffff:c0a8:103\n192.168.2.1\n::192.168.1.4\n64:ff9b::192.168.1.5\n"
+
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Without Mapping",
+			patterns:    []string{"192.168.1.0/24"},
+			opts:        cmd.Options{},
+			expected:    "192.168.1.1\n",
+		},
+		{
+			description: "IPv4 Pattern",
+			patterns:    []string{"192.168.1.0/24"},
+			opts:        cmd.Options{MapIPv4: true},
+			expected:    "192.168.1.1\n::ffff:192.168.1.2\n::ffff:c0a8:103\n",
+		},
+		{
+			description: "IPv4-Mapped Pattern",
+			patterns:    []string{"::ffff:192.168.1.0/120"},
+			opts:        cmd.Options{MapIPv4: true},
+			expected:    "192.168.1.1\n::ffff:192.168.1.2\n::ffff:c0a8:103\n",
+		},
+		{
+			description: "Suffix",
+			patterns:    []string{"0.0.0.1/-8"},
+			opts:        cmd.Options{MapIPv4: true},
+			expected:    "192.168.1.1\n192.168.2.1\n",
+		},
+		{
+			description: "Negated Other Form",
+			patterns:    []string{"192.168.0.0/16", "!::ffff:192.168.1.2"},
+			opts:        cmd.Options{MapIPv4: true},
+			expected:    "192.168.1.1\n::ffff:c0a8:103\n192.168.2.1\n",
+		},
+		{
+			description: "Inverted",
+			patterns:    []string{"192.168.1.0/24"},
+			opts:        cmd.Options{MapIPv4: true, Invert: true},
+			expected:    "192.168.2.1\n::192.168.1.4\n64:ff9b::192.168.1.5\n",
+		},
+		{
+			description: "Version",
+			patterns:    []string{"192.168.1.0/24"},
+			opts:        cmd.Options{MapIPv4: true, Version: 6},
+			expected:    "::ffff:192.168.1.2\n::ffff:c0a8:103\n",
+		},
+		{
+			description: "Show Pattern",
+			patterns:    []string{"::ffff:192.168.0.0/112", "192.168.1.0/24"},
+			opts:        cmd.Options{MapIPv4: true, ShowPattern: true, Normalize: true},
+			expected:    "192.168.1.1 [::ffff:192.168.0.0/112]\n::ffff:192.168.1.2 [::ffff:192.168.0.0/112]\n::ffff:192.168.1.3 [::ffff:192.168.0.0/112]\n192.168.2.1 [::ffff:192.168.0.0/112]\n",
+		},
+		{
+			description: "Longest Prefix Match",
+			patterns:    []string{"::ffff:192.168.0.0/112", "192.168.1.0/24"},
+			opts:        cmd.Options{MapIPv4: true, ShowPattern: true, LPM: true},
+			expected:    "192.168.1.1 [192.168.1.0/24]\n::ffff:192.168.1.2 [192.168.1.0/24]\n::ffff:c0a8:103 [192.168.1.0/24]\n192.168.2.1 [::ffff:192.168.0.0/112]\n",
+		},
+		{
+			description: "Extract",
+			patterns:    []string{"192.168.1.0/24"},
+			opts:        cmd.Options{MapIPv4: true, Extract: true, OnlyMatching: true},
+			expected:    "192.168.1.1\n::ffff:192.168.1.2\n::ffff:192.168.1.3\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsShowPattern(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Show Pattern",
+			patterns:    samplePatterns,
+			opts:        cmd.Options{ShowPattern: true},
+			expected: `10.222.200.200 [10.222.0.0/16]
+192.168.57.163 [192.168.57.0/24]
+192.168.57.4 [192.168.57.0/24]
+fe80::5474:3fa5:9fca:99f3 [fe80::5400:0:0:0/72]
+`,
+		},
+		{
+			description: "First of Overlapping Patterns",
+			patterns:    []string{"10.222.0.0/16", "0.0.0.4/-8", "10.0.0.0/8"},
+			opts:        cmd.Options{ShowPattern: true},
+			expected: `10.133.107.21 [10.0.0.0/8]
+10.222.200.200 [10.222.0.0/16]
+10.223.254.126 [10.0.0.0/8]
+10.174.2.18 [10.0.0.0/8]
+10.113.99.252 [10.0.0.0/8]
+192.168.107.4 [0.0.0.4/-8]
+192.168.57.4 [0.0.0.4/-8]
+`,
+		},
+		{
+			description: "Longest Prefix Match",
+			patterns:    []string{"10.0.0.0/8", "0.0.0.4/-8", "10.222.0.0/16", "10.222.200.0/24"},
+			opts:        cmd.Options{ShowPattern: true, LPM: true},
+			expected: `10.133.107.21 [10.0.0.0/8]
+10.222.200.200 [10.222.200.0/24]
+10.223.254.126 [10.0.0.0/8]
+10.174.2.18 [10.0.0.0/8]
+10.113.99.252 [10.0.0.0/8]
+192.168.107.4 [0.0.0.4/-8]
+192.168.57.4 [0.0.0.4/-8]
+`,
+		},
+		{
+			description: "Longest Prefix Match in JSON",
+			patterns:    []string{"10.0.0.0/8", "10.222.0.0/16"},
+			opts:        cmd.Options{ShowPattern: true, Output: "json", LPM: true},
+			expected: `{"ip":"10.133.107.21","version":4,"pattern":"10.0.0.0/8"}
+{"ip":"10.222.200.200","version":4,"pattern":"10.222.0.0/16"}
+{"ip":"10.223.254.126","version":4,"pattern":"10.0.0.0/8"}
+{"ip":"10.174.2.18","version":4,"pattern":"10.0.0.0/8"}
+{"ip":"10.113.99.252","version":4,"pattern":"10.0.0.0/8"}
+`,
+		},
+		{
+			description: "Named Pattern with Line Number",
+			patterns:    []string{"172.22.0.0/16", "private", "10.0.0.0/8"},
+			opts:        cmd.Options{ShowPattern: true, LineNumber: true},
+			expected: `1:192.168.176.105 [private]
+2:192.168.207.29 [private]
+3:10.133.107.21 [private]
+4:172.22.6.67 [172.22.0.0/16]
+5:10.222.200.200 [private]
+6:10.223.254.126 [private]
+7:10.174.2.18 [private]
+8:172.25.172.33 [private]
+9:192.168.179.165 [private]
+10:192.168.57.163 [private]
+11:172.22.246.192 [172.22.0.0/16]
+12:10.113.99.252 [private]
+13:192.168.107.4 [private]
+14:192.168.57.4 [private]
+15:192.168.46.194 [private]
+`,
+		},
+		{
+			description: "Inverted",
+			patterns:    []string{"10.0.0.0/8", "192.168.0.0/16", "fe80::/10"},
+			opts:        cmd.Options{ShowPattern: true, Invert: true},
+			expected: `172.22.6.67
+172.25.172.33
+172.22.246.192
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(sampleInput), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsStats(t *testing.T) {
+	testCases := []struct {
+		description  string
+		patterns     []string
+		input        string
+		opts         cmd.Options
+		expected     string
+		expectedEout string
+	}{
+		{
+			description: "Sample",
+			patterns:    samplePatterns,
+			input:       sampleInput,
+			opts:        cmd.Options{Stats: true},
+			expected: `10.222.200.200
+192.168.57.163
+192.168.57.4
+fe80::5474:3fa5:9fca:99f3
+`,
+			expectedEout: `192.168.57.0/24: 2
+10.222.0.0/16: 1
+fe80::5400:0:0:0/72: 1
+`,
+		},
+		{
+			description: "Overlapping Patterns",
+			patterns:    []string{"203.0.113.0/24", "10.222.0.0/16", "private", "10.0.0.0/8"},
+			input:       sampleInput,
+			opts:        cmd.Options{Stats: true, Count: true},
+			expected:    "15\n",
+			expectedEout: `private: 15
+10.0.0.0/8: 5
+10.222.0.0/16: 1
+203.0.113.0/24: 0
+`,
+		},
+		{
+			description: "Unique",
+			patterns:    []string{"10.0.0.0/8", "10.222.0.0/16"},
+			input:       "10.222.0.1\n10.222.0.1\n10.1.0.1\n",
+			opts:        cmd.Options{Stats: true, Unique: true},
+			expected:    "10.222.0.1\n10.1.0.1\n",
+			expectedEout: `10.0.0.0/8: 3
+10.222.0.0/16: 2
+`,
+		},
+		{
+			description: "Extract",
+			patterns:    []string{"10.0.0.0/8", "10.222.0.0/16"},
+			input:       "from 10.222.0.1 to 10.222.0.2\nfrom 10.1.0.1 to 192.168.0.1\n",
+			opts:        cmd.Options{Stats: true, Extract: true},
+			expected:    "from 10.222.0.1 to 10.222.0.2\nfrom 10.1.0.1 to 192.168.0.1\n",
+			expectedEout: `10.0.0.0/8: 2
+10.222.0.0/16: 1
+`,
+		},
+		{
+			description: "Inverted",
+			patterns:    []string{"10.0.0.0/8"},
+			input:       "10.0.0.1\n192.168.0.1\n",
+			opts:        cmd.Options{Stats: true, Invert: true},
+			expected:    "192.168.0.1\n",
+			expectedEout: `10.0.0.0/8: 0
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, eoutbuf, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		if eoutbuf.String() != tc.expectedEout {
+			t.Errorf("expected: %v, got: %v", tc.expectedEout, eoutbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsStrict(t *testing.T) {
+	input := `10.0.0.1
+hello
+192.168.0.1
+
+10.0.0.256
+10.0.0.2`
+
+	testCases := []struct {
+		description  string
+		opts         cmd.Options
+		expected     string
+		expectedEout string
+		expectedErr  error
+	}{
+		{
+			description: "Lenient",
+			opts:        cmd.Options{},
+			expected: `10.0.0.1
+10.0.0.2
+`,
+			expectedEout: "(standard input): 3 lines skipped: not IP addresses\n",
+		},
+		{
+			description: "Strict",
+			opts:        cmd.Options{Strict: true, Filename: "input.txt"},
+			expected: `10.0.0.1
+10.0.0.2
+`,
+			expectedEout: `input.txt:2: invalid ip: hello
+input.txt:4: invalid ip: 
+input.txt:5: invalid ip: 10.0.0.256
+`,
+		},
+		{
+			description: "Abort on Invalid",
+			opts:        cmd.Options{AbortOnInvalid: true},
+			expected: `10.0.0.1
+`,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Abort on Invalid in Parallel",
+			opts:        cmd.Options{AbortOnInvalid: true, Jobs: 4},
+			expected: `10.0.0.1
+`,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Quiet",
+			opts:        cmd.Options{Quiet: true},
+		},
+		{
+			description: "Files with Matches",
+			opts:        cmd.Options{FilesWithMatches: true},
+			expected:    "(standard input)\n",
+		},
+		{
+			description: "Files without Match",
+			opts:        cmd.Options{FilesWithoutMatches: true},
+		},
+		{
+			description: "Count",
+			opts:        cmd.Options{Count: true},
+			expected:    "2\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, eoutbuf, []string{"10.0.0.0/8"}, tc.opts)
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("expected: %v, got: %v", tc.expectedErr, err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		if eoutbuf.String() != tc.expectedEout {
+			t.Errorf("expected: %v, got: %v", tc.expectedEout, eoutbuf.String())
+		}
+	}
+
+	// the error tells where the line is
+	_, err := cmd.RunWithOptions(strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{AbortOnInvalid: true})
+	expected := "(standard input):2: invalid ip: hello"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected: %v, got: %v", expected, err)
+	}
+}
+
+func TestRunWithOptionsSkipComments(t *testing.T) {
+	input := `# servers
+10.0.0.1
+
+  # the second one
+10.0.0.2
+192.168.0.1
+; old
+hello`
+
+	testCases := []struct {
+		description  string
+		opts         cmd.Options
+		expected     string
+		expectedEout string
+	}{
+		{
+			description:  "Without Skipping",
+			opts:         cmd.Options{},
+			expected:     "10.0.0.1\n10.0.0.2\n",
+			expectedEout: "(standard input): 5 lines skipped: not IP addresses\n",
+		},
+		{
+			description:  "Skip Comments",
+			opts:         cmd.Options{SkipComments: true},
+			expected:     "10.0.0.1\n10.0.0.2\n",
+			expectedEout: "(standard input): 2 lines skipped: not IP addresses\n",
+		},
+		{
+			description: "Count",
+			opts:        cmd.Options{SkipComments: true, Count: true},
+			expected:    "2\n",
+		},
+		{
+			description: "Inverted Count",
+			opts:        cmd.Options{SkipComments: true, Count: true, Invert: true},
+			expected:    "1\n",
+		},
+		{
+			description:  "Comment Char",
+			opts:         cmd.Options{SkipComments: true, CommentChar: ";"},
+			expected:     "10.0.0.1\n10.0.0.2\n",
+			expectedEout: "(standard input): 3 lines skipped: not IP addresses\n",
+		},
+		{
+			description:  "Strict",
+			opts:         cmd.Options{SkipComments: true, Strict: true},
+			expected:     "10.0.0.1\n10.0.0.2\n",
+			expectedEout: "(standard input):7: invalid ip: ; old\n(standard input):8: invalid ip: hello\n",
+		},
+		{
+			description: "Abort on Invalid",
+			opts:        cmd.Options{SkipComments: true, AbortOnInvalid: true},
+			expected:    "",
+		},
+		{
+			description: "Passthrough",
+			opts:        cmd.Options{SkipComments: true, Passthrough: true},
+			expected:    "# servers\n10.0.0.1\n\n  # the second one\n10.0.0.2\n; old\nhello\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, eoutbuf, []string{"10.0.0.0/8"}, tc.opts)
+		if tc.opts.AbortOnInvalid {
+			// the first invalid line that is not a comment
+			if err == nil || !strings.Contains(err.Error(), ":7:") {
+				t.Errorf("expected an error at line 7, got: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		if eoutbuf.String() != tc.expectedEout {
+			t.Errorf("expected: %v, got: %v", tc.expectedEout, eoutbuf.String())
+		}
+	}
+}
+
+func TestRootCmdCommentChar(t *testing.T) {
+	paths := writeFiles(t, "// servers\n10.0.0.1\n")
+
+	out, err := execute(t, "--skip-comments", "--comment-char", "//", "-e", "10.0.0.0/8", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "10.0.0.1\n" {
+		t.Errorf("expected: %v, got: %v", "10.0.0.1\n", out)
+	}
+
+	_, err = execute(t, "--comment-char", "//", "-e", "10.0.0.0/8", paths[0])
+	if err == nil || !strings.Contains(err.Error(), "--skip-comments") {
+		t.Errorf("expected an error of --skip-comments, got: %v", err)
+	}
+}
+
+func TestRunWithOptionsPassthrough(t *testing.T) {
+	input := `# access from
+10.0.0.1
+192.168.0.1
+-- 2 lines --
+10.0.0.2`
+
+	testCases := []struct {
+		description     string
+		opts            cmd.Options
+		expected        string
+		expectedMatched int
+	}{
+		{
+			description: "Passthrough",
+			opts:        cmd.Options{Passthrough: true},
+			expected: `# access from
+10.0.0.1
+-- 2 lines --
+10.0.0.2
+`,
+			expectedMatched: 2,
+		},
+		{
+			description: "Inverted Passthrough",
+			opts:        cmd.Options{Passthrough: true, Invert: true},
+			expected: `# access from
+192.168.0.1
+-- 2 lines --
+`,
+			expectedMatched: 1,
+		},
+		{
+			description: "Passthrough with Line Number",
+			opts:        cmd.Options{Passthrough: true, LineNumber: true, Filename: "input.txt", WithFilename: true},
+			expected: `input.txt:1:# access from
+input.txt:2:10.0.0.1
+input.txt:4:-- 2 lines --
+input.txt:5:10.0.0.2
+`,
+			expectedMatched: 2,
+		},
+		{
+			description:     "Passthrough with Count",
+			opts:            cmd.Options{Passthrough: true, Count: true},
+			expected:        "2\n",
+			expectedMatched: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		matched, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, eoutbuf, []string{"10.0.0.0/8"}, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if matched != tc.expectedMatched {
+			t.Errorf("expected: %v, got: %v", tc.expectedMatched, matched)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		// the lines are not skipped
+		if eoutbuf.String() != "" {
+			t.Errorf("expected: %v, got: %v", "", eoutbuf.String())
+		}
+	}
+
+	// the lines passed through would break the records
+	for _, output := range []string{"json", "csv", "ipset"} {
+		fmt.Println(output)
+		_, err := cmd.RunWithOptions(strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{Passthrough: true, Output: output})
+		if err == nil {
+			t.Errorf("expected an error of passthrough with the %s output", output)
+		}
+	}
+	_, err := cmd.RunWithOptions(strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, cmd.Options{Passthrough: true, Output: "text"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRootCmdPassthroughOutput(t *testing.T) {
+	paths := writeFiles(t, "hello\n10.0.0.1\n")
+
+	_, err := execute(t, "--passthrough", "--output", "json", "-e", "10.0.0.0/8", paths[0])
+	if err == nil || !strings.Contains(err.Error(), "passthrough") {
+		t.Errorf("expected an error of --passthrough with --output json, got: %v", err)
+	}
+}
+
+func TestRootCmdVersion(t *testing.T) {
+	paths := writeFiles(t, sampleInput+"\nhello\n::ffff:10.0.0.1")
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "IPv4 without Patterns",
+			args:        []string{"-4", "-c"},
+			expected:    "15\n",
+		},
+		{
+			description: "IPv6 without Patterns",
+			args:        []string{"-6", "-c"},
+			expected:    "16\n",
+		},
+		{
+			description: "IPv6 with Patterns",
+			args:        []string{"-6", "-e", "fe80::5400:0:0:0/72,10.0.0.0/8"},
+			expected:    "fe80::5474:3fa5:9fca:99f3\n",
+		},
+		{
+			description: "IPv4 Inverted",
+			args:        []string{"-4", "-v", "-e", "10.0.0.0/8,192.168.0.0/16"},
+			expected: `172.22.6.67
+172.25.172.33
+172.22.246.192
+`,
+		},
+		{
+			description: "IPv4 and IPv6",
+			args:        []string{"-4", "-6"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append(tc.args, paths[0])...)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
+
+func TestRootCmdAllIPs(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\nhello\nfe80::1\n10.0.0.256\n2001:db8::/32\n::1:::2\n192.168.0.1\n")
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "All IPs",
+			args:        []string{"--all-ips"},
+			expected:    "10.0.0.1\nfe80::1\n2001:db8::/32\n192.168.0.1\n",
+		},
+		{
+			description: "All IPv4",
+			args:        []string{"--all-ips", "-4"},
+			expected:    "10.0.0.1\n192.168.0.1\n",
+		},
+		{
+			description: "All IPv6",
+			args:        []string{"--all-ips", "-6", "-n"},
+			expected:    "3:fe80::1\n5:2001:db8::/32\n",
+		},
+		{
+			description: "All IPs with Exclude",
+			args:        []string{"--all-ips", "--exclude", "private"},
+			expected:    "fe80::1\n2001:db8::/32\n",
+		},
+		{
+			description: "All IPs with Patterns",
+			args:        []string{"--all-ips", "-e", "10.0.0.0/8"},
+			expectedErr: true,
+		},
+		{
+			description: "No Patterns",
+			args:        []string{},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append(tc.args, paths[0])...)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
+
+func TestRunWithOptionsNull(t *testing.T) {
+	input := "10.222.0.1\x00172.16.0.1\x00not\nan address\x0010.222.0.2"
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Null Data",
+			opts:        cmd.Options{NullData: true, LineNumber: true},
+			expected:    "1:10.222.0.1\n4:10.222.0.2\n",
+		},
+		{
+			description: "Null Data and Output",
+			opts:        cmd.Options{NullData: true, NullOutput: true},
+			expected:    "10.222.0.1\x0010.222.0.2\x00",
+		},
+		{
+			description: "Count",
+			opts:        cmd.Options{NullData: true, NullOutput: true, Count: true},
+			expected:    "2\x00",
+		},
+		{
+			description: "Passthrough",
+			opts:        cmd.Options{NullData: true, NullOutput: true, Passthrough: true, Invert: true},
+			expected:    "172.16.0.1\x00not\nan address\x00",
+		},
+		{
+			description: "Without Null Data",
+			opts:        cmd.Options{},
+			expected:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %q, got: %q", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdNullFiles(t *testing.T) {
+	paths := writeFiles(t, "10.222.0.1\x00", "172.16.0.1\x00")
+
+	out, err := execute(t, "-z", "-Z", "-l", "-e", "10.0.0.0/8", paths[0], paths[1])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := paths[0] + "\x00"; out != expected {
+		t.Errorf("expected: %q, got: %q", expected, out)
+	}
+}
+
+func TestRunWithOptionsCRLF(t *testing.T) {
+	input := "10.222.0.1\r\nnot an address\r\n172.16.0.1\r\n192.168.57.1\r"
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Lines",
+			opts:        cmd.Options{LineNumber: true},
+			expected:    "1:10.222.0.1\n4:192.168.57.1\n",
+		},
+		{
+			description: "Passthrough",
+			opts:        cmd.Options{Passthrough: true},
+			expected:    "10.222.0.1\nnot an address\n192.168.57.1\n",
+		},
+		{
+			description: "Extract",
+			opts:        cmd.Options{Extract: true, OnlyMatching: true},
+			expected:    "10.222.0.1\n192.168.57.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %q, got: %q", tc.expected, outbuf.String())
+		}
+	}
+}
+
+// writeRecorder records the data of each Write.
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestRunWithOptionsLineBuffered(t *testing.T) {
+	input := "10.222.0.1\n172.16.0.1\n10.222.0.2\n192.168.57.1\n"
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    []string
+	}{
+		{
+			description: "Line Buffered",
+			opts:        cmd.Options{LineBuffered: true},
+			expected:    []string{"10.222.0.1\n", "10.222.0.2\n", "192.168.57.1\n"},
+		},
+		{
+			description: "Line Buffered Count",
+			opts:        cmd.Options{LineBuffered: true, Count: true},
+			expected:    []string{"3\n"},
+		},
+		{
+			description: "Buffered",
+			opts:        cmd.Options{},
+			expected:    []string{"10.222.0.1\n10.222.0.2\n192.168.57.1\n"},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		w := &writeRecorder{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), w, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if strings.Join(w.writes, "|") != strings.Join(tc.expected, "|") {
+			t.Errorf("expected: %q, got: %q", tc.expected, w.writes)
+		}
+	}
+}
+
+func TestRunWithOptionsFlushOnError(t *testing.T) {
+	outbuf := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader("10.222.0.1\nnot an address\n"), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{AbortOnInvalid: true})
+	if !errors.Is(err, cmd.ErrInvalidIP) {
+		t.Errorf("expected: %v, got: %v", cmd.ErrInvalidIP, err)
+	}
+	// the lines selected before the error are printed
+	if expected := "10.222.0.1\n"; outbuf.String() != expected {
+		t.Errorf("expected: %v, got: %v", expected, outbuf.String())
+	}
+}
+
+func TestRunWithOptionsStrictCIDR(t *testing.T) {
+	input := "192.168.1.200\n192.168.2.1\n"
+
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+		expectedErr bool
+		// errIndex is the index of the pattern rejected.
+		errIndex int
+	}{
+		{
+			description: "Host Bits Masked",
+			patterns:    []string{"192.168.1.5/24"},
+			expected:    "192.168.1.200\n",
+		},
+		{
+			description: "Netmask Host Bits Masked",
+			patterns:    []string{"192.168.1.5/255.255.255.0"},
+			expected:    "192.168.1.200\n",
+		},
+		{
+			description: "Strict",
+			patterns:    []string{"192.168.1.0/24", "192.168.2.1", "0.0.0.1/-8", "private"},
+			opts:        cmd.Options{StrictCIDR: true},
+			expected:    "192.168.1.200\n192.168.2.1\n",
+		},
+		{
+			description: "Strict Host Bits",
+			patterns:    []string{"10.0.0.0/8", "192.168.1.5/24"},
+			opts:        cmd.Options{StrictCIDR: true},
+			expectedErr: true,
+			errIndex:    1,
+		},
+		{
+			description: "Strict Negated Host Bits",
+			patterns:    []string{"192.168.0.0/16", "!192.168.1.5/24"},
+			opts:        cmd.Options{StrictCIDR: true},
+			expectedErr: true,
+			errIndex:    1,
+		},
+		{
+			description: "Strict IPv6 Host Bits",
+			patterns:    []string{"2001:db8::1/32"},
+			opts:        cmd.Options{StrictCIDR: true},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if tc.expectedErr {
+			var patternErr *cmd.PatternError
+			if !errors.Is(err, cmd.ErrInvalidPattern) || !errors.As(err, &patternErr) || patternErr.Index != tc.errIndex {
+				t.Errorf("expected a pattern error of index %d, got: %v", tc.errIndex, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
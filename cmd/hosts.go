@@ -0,0 +1,30 @@
+package cmd
+
+import "bytes"
+
+// hostsAddress returns the byte offsets of the address in a line of a hosts
+// file, e.g. "127.0.0.1 localhost", which is the first field before the
+// host names. The second result is false for a blank or comment line.
+func hostsAddress(line []byte) (start, end int, ok bool) {
+	// a comment may follow the host names
+	if i := bytes.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	start = 0
+	for start < len(line) && isSpace(line[start]) {
+		start++
+	}
+	if start == len(line) {
+		return 0, 0, false
+	}
+	end = start
+	for end < len(line) && !isSpace(line[end]) {
+		end++
+	}
+	return start, end, true
+}
+
+// isSpace reports whether c is a whitespace separating the fields of a line.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f'
+}
@@ -0,0 +1,115 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRunWithOptionsHosts(t *testing.T) {
+	hosts, err := os.ReadFile("testdata/hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		description  string
+		patterns     []string
+		opts         cmd.Options
+		expected     string
+		expectedEout string
+	}{
+		{
+			description: "Hosts",
+			patterns:    []string{"10.0.0.0/8", "fe80::/10"},
+			opts:        cmd.Options{Hosts: true},
+			expected: `10.0.0.10	db.internal db  # primary
+10.0.0.11	cache.internal
+fe80::0001%eth0	router.lan
+`,
+			expectedEout: "(standard input): 1 lines skipped: not IP addresses\n",
+		},
+		{
+			description: "Loopback",
+			patterns:    []string{"loopback"},
+			opts:        cmd.Options{Hosts: true, LineNumber: true, ShowPattern: true},
+			expected: `2:127.0.0.1	localhost [loopback]
+3:::1		localhost ip6-localhost ip6-loopback [loopback]
+`,
+			expectedEout: "(standard input): 1 lines skipped: not IP addresses\n",
+		},
+		{
+			description: "Normalize",
+			patterns:    []string{"fe80::/10"},
+			opts:        cmd.Options{Hosts: true, Normalize: true},
+			expected:    "fe80::1%eth0\trouter.lan\n",
+		},
+		{
+			description: "Only Matching",
+			patterns:    []string{"private"},
+			opts:        cmd.Options{Hosts: true, OnlyMatching: true},
+			expected:    "10.0.0.10\n10.0.0.11\n192.168.1.5\n",
+		},
+		{
+			description: "Inverted",
+			patterns:    []string{"private", "fe80::/10"},
+			opts:        cmd.Options{Hosts: true, Invert: true},
+			expected: `127.0.0.1	localhost
+::1		localhost ip6-localhost ip6-loopback
+`,
+		},
+		{
+			description: "Passthrough",
+			patterns:    []string{"192.168.0.0/16"},
+			opts:        cmd.Options{Hosts: true, Passthrough: true},
+			expected: `# static table lookup for hostnames
+
+# the internal servers
+  192.168.1.5 printer.lan
+not-an-address	broken
+`,
+		},
+		{
+			description:  "Strict",
+			patterns:     []string{"192.168.0.0/16"},
+			opts:         cmd.Options{Hosts: true, Strict: true},
+			expected:     "  192.168.1.5 printer.lan\n",
+			expectedEout: "(standard input):10: invalid ip: not-an-address\tbroken\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		eoutbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(bytes.NewReader(hosts), outbuf, eoutbuf, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+		if tc.expectedEout != "" && eoutbuf.String() != tc.expectedEout {
+			t.Errorf("expected: %v, got: %v", tc.expectedEout, eoutbuf.String())
+		}
+	}
+}
+
+func TestRootCmdHosts(t *testing.T) {
+	out, err := execute(t, "--hosts", "-c", "-e", "private", "testdata/hosts")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out != "3\n" {
+		t.Errorf("expected: %v, got: %v", "3\n", out)
+	}
+
+	_, err = execute(t, "--hosts", "--extract", "-e", "private", "testdata/hosts")
+	if err == nil || !strings.Contains(err.Error(), "extract") {
+		t.Errorf("expected an error of --extract, got: %v", err)
+	}
+}
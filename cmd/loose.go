@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// ParseLooseIPv4 parses s as an IPv4 address in the forms accepted by
+// inet_aton, e.g. "127.1", "0x7f000001", "2130706433" and "0177.0.0.1".
+// Each of the 1 to 4 parts is decimal, hexadecimal with "0x" or octal with a
+// leading "0", and the last part fills the remaining bytes.
+func ParseLooseIPv4(s string) (IPAddress, error) {
+	return parseLooseIPv4Bytes([]byte(s))
+}
+
+func parseLooseIPv4Bytes(ip []byte) (IPAddress, error) {
+	// ドットで分割する
+	parts := bytes.Split(ip, []byte("."))
+	// 部分の数が1~4でない場合はエラー
+	if len(parts) > 4 {
+		return nil, ErrInvalidIP
+	}
+
+	var value uint32
+	for i, part := range parts {
+		n, err := parseLooseNumber(part)
+		if err != nil {
+			return nil, err
+		}
+		// 最後の部分は残りのバイトを埋める
+		if i == len(parts)-1 {
+			bits := 8 * (4 - i)
+			if bits < 32 && n >= 1<<bits {
+				return nil, ErrInvalidIP
+			}
+			value |= uint32(n)
+			break
+		}
+		// 最後以外の部分は1バイト
+		if n > 255 {
+			return nil, ErrInvalidIP
+		}
+		value |= uint32(n) << (8 * (3 - i))
+	}
+
+	var r IPv4Address
+	binary.BigEndian.PutUint32(r.IP[:], value)
+	return r, nil
+}
+
+// parseLooseNumber parses a 32-bit number in decimal, hexadecimal with "0x"
+// or octal with a leading "0".
+func parseLooseNumber(s []byte) (uint64, error) {
+	base := uint64(10)
+	switch {
+	case len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X'):
+		base = 16
+		s = s[2:]
+	case len(s) > 1 && s[0] == '0':
+		base = 8
+		s = s[1:]
+	}
+	// 数字がない場合はエラー
+	if len(s) == 0 {
+		return 0, ErrInvalidIP
+	}
+
+	var n uint64
+	for _, c := range s {
+		var d uint64
+		switch {
+		case '0' <= c && c <= '9':
+			d = uint64(c - '0')
+		case 'a' <= c && c <= 'f':
+			d = uint64(c-'a') + 10
+		case 'A' <= c && c <= 'F':
+			d = uint64(c-'A') + 10
+		default:
+			return 0, ErrInvalidIP
+		}
+		if d >= base {
+			return 0, ErrInvalidIP
+		}
+		n = n*base + d
+		// 32ビットを超える場合はエラー
+		if n > 0xffffffff {
+			return 0, ErrInvalidIP
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,96 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestParseLooseIPv4(t *testing.T) {
+	testCases := []struct {
+		description string
+		ipStr       string
+		expected    string
+		expectedErr error
+	}{
+		{description: "Dotted Decimal", ipStr: "192.168.0.1", expected: "192.168.0.1"},
+		{description: "Decimal", ipStr: "2130706433", expected: "127.0.0.1"},
+		{description: "Hexadecimal", ipStr: "0x7f000001", expected: "127.0.0.1"},
+		{description: "Upper Hexadecimal", ipStr: "0X7F000001", expected: "127.0.0.1"},
+		{description: "Octal", ipStr: "017700000001", expected: "127.0.0.1"},
+		{description: "Two Parts", ipStr: "127.1", expected: "127.0.0.1"},
+		{description: "Two Parts with Large Last", ipStr: "10.65535", expected: "10.0.255.255"},
+		{description: "Three Parts", ipStr: "192.168.257", expected: "192.168.1.1"},
+		{description: "Mixed Bases", ipStr: "0xc0.0250.0.1", expected: "192.168.0.1"},
+		{description: "Zero", ipStr: "0", expected: "0.0.0.0"},
+		{description: "Too Large Number", ipStr: "4294967296", expectedErr: cmd.ErrInvalidIP},
+		{description: "Too Large Last Part", ipStr: "127.16777216", expectedErr: cmd.ErrInvalidIP},
+		{description: "Too Large Part", ipStr: "256.1", expectedErr: cmd.ErrInvalidIP},
+		{description: "Invalid Octal", ipStr: "08.0.0.1", expectedErr: cmd.ErrInvalidIP},
+		{description: "Empty Hexadecimal", ipStr: "0x.1", expectedErr: cmd.ErrInvalidIP},
+		{description: "Empty Part", ipStr: "127..1", expectedErr: cmd.ErrInvalidIP},
+		{description: "Too Many Parts", ipStr: "1.2.3.4.5", expectedErr: cmd.ErrInvalidIP},
+		{description: "Sign", ipStr: "-1", expectedErr: cmd.ErrInvalidIP},
+		{description: "Empty", ipStr: "", expectedErr: cmd.ErrInvalidIP},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ip, err := cmd.ParseLooseIPv4(tc.ipStr)
+		if err != tc.expectedErr {
+			t.Errorf("expected: %v, got: %v", tc.expectedErr, err)
+		}
+		if err == nil && ip.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, ip)
+		}
+	}
+}
+
+func TestRunWithOptionsLooseIPv4(t *testing.T) {
+	input := `127.1
+0x7f000002
+2130706435
+0177.0.0.4
+127.0.0.5
+::1`
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Strict by Default",
+			opts:        cmd.Options{},
+			expected: `127.0.0.5
+::1
+`,
+		},
+		{
+			description: "Loose IPv4",
+			opts:        cmd.Options{LooseIPv4: true, Normalize: true},
+			expected: `127.0.0.1
+127.0.0.2
+127.0.0.3
+127.0.0.4
+127.0.0.5
+::1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, []string{"loopback"}, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -14,10 +16,18 @@ var (
 type IPAddress interface {
 	Bytes() []byte
 	Version() int
+	String() string
+	// Equal reports whether the IP address is the same as other. IP
+	// addresses of different versions are never equal.
+	Equal(other IPAddress) bool
 }
 
 type IPv6Address struct {
 	IP [16]byte
+	// Zone is the zone identifier following '%', e.g. "eth0" of "fe80::1%eth0".
+	// It consists of letters, digits, '.', '_' and '-' like interface names.
+	// It is not compared when matching.
+	Zone string
 }
 
 func (ip IPv6Address) Bytes() []byte {
@@ -28,6 +38,79 @@ func (ip IPv6Address) Version() int {
 	return 6
 }
 
+// Equal reports whether other is an IPv6 address with the same bytes and
+// zone.
+func (ip IPv6Address) Equal(other IPAddress) bool {
+	o, ok := other.(IPv6Address)
+	return ok && ip.IP == o.IP && ip.Zone == o.Zone
+}
+
+// String returns the canonical text representation of RFC 5952.
+func (ip IPv6Address) String() string {
+	// IPv4射影アドレスは末尾をIPv4アドレス表記にする
+	if isIPv4Mapped(ip) {
+		s := "::ffff:" + IPv4Address{IP: [4]byte(ip.IP[12:])}.String()
+		if ip.Zone != "" {
+			s += "%" + ip.Zone
+		}
+		return s
+	}
+
+	// 16ビットのブロックに分割する
+	var blocks [8]uint16
+	for i := range blocks {
+		blocks[i] = uint16(ip.IP[i*2])<<8 | uint16(ip.IP[i*2+1])
+	}
+
+	// 最も長い0の連続を探す (同じ長さの場合は先頭のものを使う)
+	zeroStart, zeroLen := -1, 0
+	for i := 0; i < len(blocks); {
+		if blocks[i] != 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(blocks) && blocks[j] == 0 {
+			j++
+		}
+		if j-i > zeroLen {
+			zeroStart, zeroLen = i, j-i
+		}
+		i = j
+	}
+	// 0のブロックが1つだけの場合は省略しない
+	if zeroLen < 2 {
+		zeroStart = -1
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(blocks); i++ {
+		if i == zeroStart {
+			sb.WriteString("::")
+			i += zeroLen - 1
+			continue
+		}
+		if i > 0 && i != zeroStart+zeroLen {
+			sb.WriteByte(':')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(blocks[i]), 16))
+	}
+	if ip.Zone != "" {
+		sb.WriteString("%" + ip.Zone)
+	}
+	return sb.String()
+}
+
+// isIPv4Mapped は ::ffff:0:0/96 のアドレスかどうかを返す
+func isIPv4Mapped(ip IPv6Address) bool {
+	for i := 0; i < 10; i++ {
+		if ip.IP[i] != 0 {
+			return false
+		}
+	}
+	return ip.IP[10] == 0xff && ip.IP[11] == 0xff
+}
+
 type IPv4Address struct {
 	IP [4]byte
 }
@@ -40,213 +123,317 @@ func (ip IPv4Address) Version() int {
 	return 4
 }
 
+// Equal reports whether other is an IPv4 address with the same bytes.
+func (ip IPv4Address) Equal(other IPAddress) bool {
+	o, ok := other.(IPv4Address)
+	return ok && ip.IP == o.IP
+}
+
+// String returns the dotted decimal notation.
+func (ip IPv4Address) String() string {
+	return strconv.Itoa(int(ip.IP[0])) + "." +
+		strconv.Itoa(int(ip.IP[1])) + "." +
+		strconv.Itoa(int(ip.IP[2])) + "." +
+		strconv.Itoa(int(ip.IP[3]))
+}
+
+// compareIP compares the IP addresses by their big-endian byte values.
+// IPv4 addresses are ordered before IPv6 addresses.
+func compareIP(a, b IPAddress) int {
+	if a.Version() != b.Version() {
+		return a.Version() - b.Version()
+	}
+	return bytes.Compare(a.Bytes(), b.Bytes())
+}
+
 func ParseIp(ip string) (IPAddress, error) {
+	return parseIPBytes([]byte(ip))
+}
+
+// parseIPBytes is like ParseIp but takes the bytes of a line so that it can
+// be parsed without allocating a string. IPv4 addresses and lines that are not
+// IP addresses are parsed without allocations.
+func parseIPBytes(ip []byte) (IPAddress, error) {
 	for i := 0; i < len(ip); i++ {
 		if ip[i] == '.' {
-			return parseIPv4(ip)
+			return parseIPv4Bytes(ip)
 		}
 		if ip[i] == ':' {
-			return parseIPv6(ip)
+			return parseIPv6(string(ip))
 		}
 	}
 	return nil, ErrInvalidIP
 }
 
-func parseIPv6(ip string) (IPAddress, error) {
-	var err error
-	// 略記を展開する
-	ip, err = extendIPv6(ip)
-	if err != nil {
-		return nil, err
-	}
-
-	// コロンで分割する
-	blocks := strings.Split(ip, ":")
-	// ブロックの数が8でない場合はエラー
-	if len(blocks) != 8 {
-		return nil, ErrInvalidIP
+// isZoneChar はゾーン識別子に使える文字 (英数字と '.', '_', '-') かどうかを返す
+func isZoneChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
 	}
+	return c == '.' || c == '_' || c == '-'
+}
 
-	// ブロックを16進数に変換する
-	var ipBytes [16]byte
-	for i := 0; i < len(blocks); i++ {
-		// ブロックが空の場合はエラー
-		if blocks[i] == "" {
+func parseIPv6(ip string) (IPAddress, error) {
+	// ゾーン識別子を取り出す
+	var zone string
+	if idx := strings.Index(ip, "%"); idx >= 0 {
+		zone = ip[idx+1:]
+		ip = ip[:idx]
+		// ゾーン識別子が空の場合や、インターフェース名に使えない文字を含む場合はエラー
+		if zone == "" {
 			return nil, ErrInvalidIP
 		}
-		// ブロックが4桁を超えている場合はエラー
-		if len(blocks[i]) > 4 {
-			return nil, ErrInvalidIP
+		for i := 0; i < len(zone); i++ {
+			if !isZoneChar(zone[i]) {
+				return nil, ErrInvalidIP
+			}
 		}
-		// ブロックを16進数に変換する
-		block, err := hexToBytes(blocks[i])
+	}
+
+	var ipBytes [16]byte
+	// コロン2つによる略記の前後に分ける
+	head, tail, compressed := strings.Cut(ip, "::")
+	if !compressed {
+		// 略記がない場合は8ブロックちょうど
+		n, err := parseIPv6Blocks(head, ipBytes[:], true)
 		if err != nil {
 			return nil, err
 		}
-		// ブロックを挿入する
-		copy(ipBytes[i*2:], block)
+		if n != len(ipBytes) {
+			return nil, ErrInvalidIP
+		}
+		return IPv6Address{IP: ipBytes, Zone: zone}, nil
 	}
 
-	return IPv6Address{IP: ipBytes}, nil
+	// コロン2つによる略記が複数ある場合はエラー
+	if strings.Contains(tail, "::") {
+		return nil, ErrInvalidIP
+	}
+	// コロン2つは1つ以上のブロックを表すので、前後は合わせて7ブロックまで
+	nh, err := parseIPv6Blocks(head, ipBytes[:14], false)
+	if err != nil {
+		return nil, err
+	}
+	var tailBytes [14]byte
+	nt, err := parseIPv6Blocks(tail, tailBytes[:], true)
+	if err != nil {
+		return nil, err
+	}
+	if nh+nt > len(tailBytes) {
+		return nil, ErrInvalidIP
+	}
+	// 後ろのブロックを末尾に詰め、間は0のままにする
+	copy(ipBytes[16-nt:], tailBytes[:nt])
+
+	return IPv6Address{IP: ipBytes, Zone: zone}, nil
 }
 
-// 略記を展開し、すべてのブロックを4桁にする
-func extendIPv6(ipv6 string) (string, error) {
-	// コロン2つによる略記が複数ある場合はエラー
-	if strings.Count(ipv6, "::") > 1 {
-		return "", ErrInvalidIP
-	}
-
-	// コロン2つによる略記を展開する
-	if strings.Contains(ipv6, "::") {
-		// コロン2つのみの場合
-		if ipv6 == "::" {
-			return "0000:0000:0000:0000:0000:0000:0000:0000", nil
-		}
-
-		// コロン2つが先頭でも末尾でもない場合
-		if !strings.HasPrefix(ipv6, "::") && !strings.HasSuffix(ipv6, "::") {
-			// コロン2つの位置を取得する
-			idx := strings.Index(ipv6, "::")
-			// コロン2つの位置で分割する
-			head := ipv6[:idx]
-			tail := ipv6[idx+1:]
-			// 全体のコロンの数を数える
-			colonCount := strings.Count(ipv6, ":")
-			// 追加するブロックの数 = 8 - 全体のコロンの数
-			addedBlockCount := 8 - colonCount
-			// 追加するブロックを作成する
-			addedBlock := strings.Repeat(":0000", addedBlockCount)
-			// 追加するブロックを挿入する
-			ipv6 = head + addedBlock + tail
-		}
-
-		// コロン2つが先頭にある場合
-		if strings.HasPrefix(ipv6, "::") {
-			// 先頭のコロン2つを削除する
-			ipv6 = ipv6[2:]
-			// 全体のコロンの数を数える
-			colonCount := strings.Count(ipv6, ":")
-			// 追加するブロックの数 = 8 - (全体のコロンの数 + 1)
-			addedBlockCount := 8 - (colonCount + 1)
-			// 追加するブロックを作成する
-			addedBlock := strings.Repeat("0000:", addedBlockCount)
-			// 追加するブロックを挿入する
-			ipv6 = addedBlock + ipv6
-		}
-
-		// コロン2つが末尾にある場合
-		if strings.HasSuffix(ipv6, "::") {
-			// 末尾のコロン2つを削除する
-			ipv6 = ipv6[:len(ipv6)-2]
-			// 全体のコロンの数を数える
-			colonCount := strings.Count(ipv6, ":")
-			// 追加するブロックの数 = 8 - (全体のコロンの数 + 1)
-			addedBlockCount := 8 - (colonCount + 1)
-			// 追加するブロックを作成する
-			addedBlock := strings.Repeat(":0000", addedBlockCount)
-			// 追加するブロックを挿入する
-			ipv6 = ipv6 + addedBlock
-		}
-	}
-
-	// すべてのブロックが4桁になるように0を追加する
-	blocks := strings.Split(ipv6, ":")
-	for i := 0; i < len(blocks); i++ {
-		// ブロックが4桁を超えている場合はエラー
-		if len(blocks[i]) > 4 {
-			return "", ErrInvalidIP
+// コロンで区切られたブロックを順に b に書き込み、書き込んだバイト数を返す
+// ipv4 が真の場合は末尾のIPv4アドレス表記を2ブロックとして読む (RFC 4291 2.5.5.2)
+func parseIPv6Blocks(s string, b []byte, ipv4 bool) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	n := 0
+	for {
+		block, rest, more := strings.Cut(s, ":")
+		if !more && ipv4 && strings.Contains(block, ".") {
+			v4, err := parseIPv4(block)
+			if err != nil {
+				return 0, err
+			}
+			if n+4 > len(b) {
+				return 0, ErrInvalidIP
+			}
+			return n + copy(b[n:], v4.Bytes()), nil
 		}
-		blocks[i] = strings.Repeat("0", 4-len(blocks[i])) + blocks[i]
+		// ブロックが多すぎる場合はエラー
+		if n+2 > len(b) {
+			return 0, ErrInvalidIP
+		}
+		v, err := hexToBytes(block)
+		if err != nil {
+			return 0, err
+		}
+		n += copy(b[n:], v[:])
+		if !more {
+			return n, nil
+		}
+		s = rest
 	}
-	return strings.Join(blocks, ":"), nil
 }
 
-// 16進数の文字列4桁をバイト列に変換する
-func hexToBytes(s string) ([]byte, error) {
-	if len(s) != 4 {
-		return nil, ErrInvalidIP
+const invalidHex = 0xFF
+
+// hexValues は ASCII 文字を16進数の値に変換する表で、16進数でない文字は
+// invalidHex になる
+var hexValues = func() [256]byte {
+	var t [256]byte
+	for i := range t {
+		t[i] = invalidHex
+	}
+	for c := '0'; c <= '9'; c++ {
+		t[c] = byte(c - '0')
+	}
+	for c := 'a'; c <= 'f'; c++ {
+		t[c] = byte(c-'a') + 10
+		t[c-'a'+'A'] = byte(c-'a') + 10
+	}
+	return t
+}()
+
+// 1〜4桁の16進数の文字列をバイト列に変換する
+func hexToBytes(s string) ([2]byte, error) {
+	// ブロックが空の場合や4桁を超えている場合はエラー
+	if len(s) == 0 || len(s) > 4 {
+		return [2]byte{}, ErrInvalidIP
 	}
 
-	var b [2]byte
+	var v uint16
+	var invalid byte
 	for i := 0; i < len(s); i++ {
-		var n byte
-		switch {
-		case '0' <= s[i] && s[i] <= '9':
-			n = s[i] - '0'
-		case 'a' <= s[i] && s[i] <= 'f':
-			n = s[i] - 'a' + 10
-		case 'A' <= s[i] && s[i] <= 'F':
-			n = s[i] - 'A' + 10
-		default:
-			return nil, ErrInvalidIP
-		}
-		if i%2 == 0 {
-			b[i/2] = n << 4
-		} else {
-			b[i/2] |= n
-		}
+		n := hexValues[s[i]]
+		// 16進数でなければ上位ビットが立つ
+		invalid |= n
+		v = v<<4 | uint16(n&0x0F)
+	}
+	if invalid&0xF0 != 0 {
+		return [2]byte{}, ErrInvalidIP
 	}
-	return b[:], nil
+	return [2]byte{byte(v >> 8), byte(v)}, nil
 }
 
 func parseIPv4(ip string) (IPAddress, error) {
-	// ドットで分割する
-	blocks := strings.Split(ip, ".")
-	// ブロックの数が4でない場合はエラー
-	if len(blocks) != 4 {
-		return nil, ErrInvalidIP
-	}
+	return parseIPv4Bytes([]byte(ip))
+}
 
-	// ブロックを10進数に変換する
+func parseIPv4Bytes(ip []byte) (IPAddress, error) {
+	// ドットで区切られたブロックを順に読む
 	var ipBytes [4]byte
-	for i := 0; i < len(blocks); i++ {
-		// ブロックが空の場合はエラー
-		if blocks[i] == "" {
-			return nil, ErrInvalidIP
+	pos := 0
+	for i := 0; i < len(ipBytes); i++ {
+		// ブロックの区切りまでを10進数に変換する
+		start := pos
+		block := 0
+		for pos < len(ip) && ip[pos] != '.' {
+			// 符号などの数字以外を含む場合はエラー
+			if ip[pos] < '0' || ip[pos] > '9' {
+				return nil, ErrInvalidIP
+			}
+			block = block*10 + int(ip[pos]-'0')
+			pos++
+			// ブロックが3桁を超えている場合はエラー
+			if pos-start > 3 {
+				return nil, ErrInvalidIP
+			}
 		}
-		// ブロックが3桁を超えている場合はエラー
-		if len(blocks[i]) > 3 {
+		// ブロックが空の場合はエラー
+		if pos == start {
 			return nil, ErrInvalidIP
 		}
-		// ブロックを10進数に変換する
-		block, err := strconv.Atoi(blocks[i])
-		if err != nil {
+		// 先頭が0の2桁以上のブロックは8進数とも読めるのでエラー
+		if pos-start > 1 && ip[start] == '0' {
 			return nil, ErrInvalidIP
 		}
 		// ブロックが0~255の範囲外の場合はエラー
-		if block < 0 || block > 255 {
+		if block > 255 {
 			return nil, ErrInvalidIP
 		}
 		// ブロックを挿入する
 		ipBytes[i] = byte(block)
+
+		// 最後のブロック以外はドットが続く
+		if i < len(ipBytes)-1 {
+			// ブロックの数が4より少ない場合はエラー
+			if pos == len(ip) {
+				return nil, ErrInvalidIP
+			}
+			pos++
+		}
+	}
+	// ブロックの数が4より多い場合はエラー
+	if pos != len(ip) {
+		return nil, ErrInvalidIP
 	}
 
 	return IPv4Address{IP: ipBytes}, nil
 }
 
-type Pattern struct {
+// Pattern selects IP addresses.
+type Pattern interface {
+	Match(ip IPAddress) bool
+	// String returns the pattern in the notation ParsePattern parses into the
+	// same pattern.
+	String() string
+}
+
+// MaskPattern matches IP addresses whose bits from MaskStart up to MaskEnd
+// are equal to the ones of IP. It is written as a prefix, a suffix or both,
+// e.g. 192.168.100.0/24, 0.0.0.1/-8 and ::abcd:01ff:fe00:0/-64/104.
+// ParsePattern clears the other bits of IP, e.g. of 192.168.1.5/24.
+type MaskPattern struct {
 	IP        IPAddress
 	MaskStart int
 	MaskEnd   int
 }
 
-func (p Pattern) Match(ip IPAddress) bool {
+func (p MaskPattern) Match(ip IPAddress) bool {
 	if ip.Version() != p.IP.Version() {
 		return false
 	}
+	// IPv4とIPv6で同じビット範囲の比較を行う
 	ipBytes := ip.Bytes()
+	pBytes := p.IP.Bytes()
 	for i := p.MaskStart; i < p.MaskEnd; i++ {
-		ipBytesBit := ipBytes[i/8] & (1 << (7 - i%8))
-		pBytesBit := p.IP.Bytes()[i/8] & (1 << (7 - i%8))
-		if ipBytesBit^pBytesBit > 0 {
+		if (ipBytes[i/8]^pBytes[i/8])&(1<<(7-i%8)) != 0 {
 			return false
 		}
 	}
 	return true
 }
 
+// String returns the pattern as a prefix, a suffix or both, e.g.
+// 192.168.100.0/24, 0.0.0.1/-8 and abcd:1ff:fe00::/-64/104.
+func (p MaskPattern) String() string {
+	bits := len(p.IP.Bytes()) * 8
+	if p.MaskStart == 0 {
+		return p.IP.String() + "/" + strconv.Itoa(p.MaskEnd)
+	}
+	s := p.IP.String() + "/-" + strconv.Itoa(bits-p.MaskStart)
+	if p.MaskEnd != bits {
+		s += "/" + strconv.Itoa(p.MaskEnd)
+	}
+	return s
+}
+
 func ParsePattern(s string) (Pattern, error) {
+	// 否定の場合
+	if strings.HasPrefix(s, "!") {
+		return parseNegatedPattern(s[1:])
+	}
+
+	// 名前付きの範囲の場合
+	if pattern, ok := parseNamedPattern(s); ok {
+		return pattern, nil
+	}
+
+	// MACアドレスから生成されたインターフェースIDの場合
+	if strings.HasPrefix(s, "mac:") {
+		return parseMACPattern(s[len("mac:"):])
+	}
+
+	// ワイルドカード指定の場合
+	if strings.Contains(s, "*") {
+		return parseWildcardPattern(s)
+	}
+
+	// 範囲指定の場合
+	if strings.Contains(s, "-") && !strings.Contains(s, "/") {
+		return parseRangePattern(s)
+	}
+
 	// IPアドレスの部分を取り出す
 	var ipPart string
 	if strings.Contains(s, "/") {
@@ -255,9 +442,13 @@ func ParsePattern(s string) (Pattern, error) {
 	} else {
 		ipPart = s
 	}
+	// IPアドレスとして解釈できる文字を含まない場合はパターンとして不正
+	if !strings.ContainsAny(ipPart, ".:") {
+		return nil, ErrInvalidPattern
+	}
 	ip, err := ParseIp(ipPart)
 	if err != nil {
-		return Pattern{}, err
+		return nil, err
 	}
 
 	// マスクの部分を取り出す
@@ -270,6 +461,15 @@ func ParsePattern(s string) (Pattern, error) {
 	}
 	// マスクを分割する
 	masks := strings.Split(maskPart, "/")
+	// マスクはプレフィックスとサフィックスの2つまで
+	if len(masks) > 3 {
+		return nil, ErrInvalidPattern
+	}
+
+	// ドット区切りのネットマスクの場合
+	if strings.Contains(maskPart, ".") {
+		return parseNetmaskPattern(ip, masks)
+	}
 
 	// マスクを適用する
 	mask := [16]byte{}
@@ -279,31 +479,409 @@ func ParsePattern(s string) (Pattern, error) {
 
 	maskStart := 0
 	maskEnd := len(ip.Bytes()) * 8
+	hasPrefix, hasSuffix := false, false
 	for i := 0; i < len(masks); i++ {
 		if masks[i] == "" {
 			continue
 		}
 		masklen, err := strconv.Atoi(masks[i])
 		if err != nil {
-			return Pattern{}, ErrInvalidPattern
+			return nil, ErrInvalidPattern
 		}
-		if masklen < -len(ip.Bytes())*8 || masklen > len(ip.Bytes())*8 || masklen == 0 {
-			return Pattern{}, ErrInvalidPattern
+		// 長さ0のプレフィックスはすべてのアドレスに一致するが、長さ0のサフィックスは不正
+		if masklen < -len(ip.Bytes())*8 || masklen > len(ip.Bytes())*8 || masklen == 0 && masks[i] != "0" {
+			return nil, ErrInvalidPattern
 		}
 
+		// プレフィックスとサフィックスは順不同だが、それぞれ1つまで
 		// Prefix指定の場合
-		if masklen > 0 {
+		if masklen >= 0 {
+			if hasPrefix {
+				return nil, ErrInvalidPattern
+			}
+			hasPrefix = true
 			maskEnd = masklen
 		}
 		// Suffix指定の場合
 		if masklen < 0 {
+			if hasSuffix {
+				return nil, ErrInvalidPattern
+			}
+			hasSuffix = true
 			maskStart = len(ip.Bytes())*8 + masklen
 		}
 	}
+	// 両方を指定した場合、範囲が空になるものは不正
+	if hasPrefix && hasSuffix && maskStart >= maskEnd {
+		return nil, ErrInvalidPattern
+	}
 
-	return Pattern{
-		IP:        ip,
+	// マスクの外のビットは0にする
+	return MaskPattern{
+		IP:        maskIP(ip, maskStart, maskEnd),
 		MaskEnd:   maskEnd,
 		MaskStart: maskStart,
 	}, nil
 }
+
+// PatternError records the pattern that failed to parse.
+type PatternError struct {
+	// Index is the index of the pattern in the given patterns.
+	Index   int
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid pattern: %s (index %d)", e.Pattern, e.Index)
+}
+
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+// Matcher matches IP addresses against a set of patterns parsed once.
+type Matcher struct {
+	patterns []sourcedPattern
+	// negations are the patterns negated with "!", which exclude the IP
+	// addresses matching them.
+	negations []Pattern
+
+	// prefixes hold the prefix patterns of IPv4 and IPv6 in tries, and
+	// others are the indexes of the rest of the patterns matched one by one.
+	prefixes4 *prefixTrie
+	prefixes6 *prefixTrie
+	others    []int
+
+	// all matches the IP addresses matching every pattern instead of any of
+	// them. See Options.All.
+	all bool
+	// mapIPv4 matches an IPv4 address and its IPv4-mapped IPv6 address as the
+	// same address. See Options.MapIPv4.
+	mapIPv4 bool
+	// decodeTransition matches a 6to4 or Teredo address as its embedded IPv4
+	// address as well. See Options.DecodeTransition.
+	decodeTransition bool
+}
+
+// sourcedPattern is a parsed pattern with the text it was parsed from.
+type sourcedPattern struct {
+	Pattern Pattern
+	// Source is the pattern as given to NewMatcher.
+	Source string
+}
+
+// NewMatcher parses the patterns. The returned error is a *PatternError
+// identifying the first pattern that failed to parse.
+func NewMatcher(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for i, p := range patterns {
+		pattern, err := ParsePattern(p)
+		if err != nil {
+			return nil, &PatternError{Index: i, Pattern: p, Err: err}
+		}
+		if negated, ok := pattern.(NegatedPattern); ok {
+			m.negations = append(m.negations, negated.Pattern)
+			continue
+		}
+		m.add(pattern, p)
+	}
+	return m, nil
+}
+
+// newMatcher is like NewMatcher but matches the IP addresses as Options.All,
+// MapIPv4 and DecodeTransition of opts tell, which are fixed at construction
+// so that they never leak into another search.
+func newMatcher(patterns []string, opts Options) (*Matcher, error) {
+	m, err := NewMatcher(patterns)
+	if err != nil {
+		return nil, err
+	}
+	m.all = opts.All
+	m.mapIPv4 = opts.MapIPv4
+	m.decodeTransition = opts.DecodeTransition
+	return m, nil
+}
+
+// add adds the pattern, putting a prefix pattern into the trie.
+func (m *Matcher) add(pattern Pattern, source string) {
+	index := len(m.patterns)
+	m.patterns = append(m.patterns, sourcedPattern{Pattern: pattern, Source: source})
+
+	p, ok := pattern.(MaskPattern)
+	if !ok || p.MaskStart != 0 {
+		m.others = append(m.others, index)
+		return
+	}
+	trie := &m.prefixes4
+	if p.IP.Version() == 6 {
+		trie = &m.prefixes6
+	}
+	if *trie == nil {
+		*trie = &prefixTrie{}
+	}
+	(*trie).insert(p.IP.Bytes(), p.MaskEnd, index)
+}
+
+// Match reports whether ip matches any of the patterns and none of the
+// negated patterns. Without patterns other than negated ones, any IP address
+// not matching the negated patterns matches.
+func (m *Matcher) Match(ip IPAddress) bool {
+	if m.negated(ip) {
+		return false
+	}
+	if len(m.patterns) == 0 {
+		return true
+	}
+	return m.lookup(ip, false) >= 0
+}
+
+// MatchSource is like Match but also returns the first of the given patterns
+// that ip matches. The pattern is empty when ip matches without patterns
+// other than negated ones.
+func (m *Matcher) MatchSource(ip IPAddress) (string, bool) {
+	if m.negated(ip) {
+		return "", false
+	}
+	if len(m.patterns) == 0 {
+		return "", true
+	}
+	index := m.lookup(ip, true)
+	if index < 0 {
+		return "", false
+	}
+	return m.patterns[index].Source, true
+}
+
+// MatchLongest is like MatchSource but returns the prefix pattern with the
+// longest prefix length among the ones ip matches, like the routing tables.
+// The first of the other patterns is returned only if no prefix patterns
+// match.
+func (m *Matcher) MatchLongest(ip IPAddress) (string, bool) {
+	if m.negated(ip) {
+		return "", false
+	}
+	if len(m.patterns) == 0 {
+		return "", true
+	}
+	alt := m.mapped(ip)
+	index := m.longest(ip)
+	if alt != nil {
+		// the prefixes of both versions are compared in IPv6
+		i := m.longest(alt)
+		if i >= 0 && (index < 0 || m.mappedLength(i) > m.mappedLength(index) || m.mappedLength(i) == m.mappedLength(index) && i < index) {
+			index = i
+		}
+	}
+	if index >= 0 {
+		return m.patterns[index].Source, true
+	}
+	for _, index := range m.others {
+		if m.patterns[index].Pattern.Match(ip) || alt != nil && m.patterns[index].Pattern.Match(alt) {
+			return m.patterns[index].Source, true
+		}
+	}
+	return "", false
+}
+
+// longest returns the index of the prefix pattern with the longest prefix
+// length that ip matches, or -1 if none.
+func (m *Matcher) longest(ip IPAddress) int {
+	trie := m.prefixes4
+	if ip.Version() == 6 {
+		trie = m.prefixes6
+	}
+	if trie == nil {
+		return -1
+	}
+	return trie.longest(ip.Bytes())
+}
+
+// mappedLength returns the prefix length of the prefix pattern at index as
+// an IPv6 prefix, where an IPv4 prefix is mapped to ::ffff:0:0/96.
+func (m *Matcher) mappedLength(index int) int {
+	p := m.patterns[index].Pattern.(MaskPattern)
+	if p.IP.Version() == 4 {
+		return p.MaskEnd + 96
+	}
+	return p.MaskEnd
+}
+
+// MatchNetwork reports whether every address of the network matches any of
+// the patterns and none matches the negated patterns. If overlaps is set, it
+// reports whether any address does instead. A network is compared with each
+// of the patterns, so a network spanning several patterns is not contained.
+func (m *Matcher) MatchNetwork(n Network, overlaps bool) bool {
+	_, matched := m.matchNetwork(n, overlaps)
+	return matched
+}
+
+// matchNetwork is like MatchNetwork but also returns the first of the given
+// patterns that the network matches.
+func (m *Matcher) matchNetwork(n Network, overlaps bool) (string, bool) {
+	match, exclude := containsNetwork, overlapsNetwork
+	if overlaps {
+		match, exclude = overlapsNetwork, containsNetwork
+	}
+	for _, pattern := range m.negations {
+		if exclude(pattern, n) {
+			return "", false
+		}
+	}
+	// every pattern must match with all
+	if m.all {
+		for _, pattern := range m.patterns {
+			if !match(pattern.Pattern, n) {
+				return "", false
+			}
+		}
+	}
+	if len(m.patterns) == 0 {
+		return "", true
+	}
+	for _, pattern := range m.patterns {
+		if match(pattern.Pattern, n) {
+			return pattern.Source, true
+		}
+	}
+	return "", false
+}
+
+// MatchAll returns all the given patterns that ip matches in the order
+// given, or nil if ip matches any of the negated patterns. Unlike Match, it
+// compares ip with each of the patterns.
+func (m *Matcher) MatchAll(ip IPAddress) []string {
+	return m.matchAll(ip, false)
+}
+
+// matchAll is like MatchAll but also matches a network like matchNetwork.
+func (m *Matcher) matchAll(ip IPAddress, overlaps bool) []string {
+	match := func(p Pattern) bool { return p.Match(ip) }
+	if n, ok := ip.(Network); ok {
+		contains, exclude := containsNetwork, overlapsNetwork
+		if overlaps {
+			contains, exclude = overlapsNetwork, containsNetwork
+		}
+		for _, pattern := range m.negations {
+			if exclude(pattern, n) {
+				return nil
+			}
+		}
+		if m.all {
+			for _, pattern := range m.patterns {
+				if !contains(pattern.Pattern, n) {
+					return nil
+				}
+			}
+		}
+		match = func(p Pattern) bool { return contains(p, n) }
+	} else if m.negated(ip) {
+		return nil
+	} else if alt := m.mapped(ip); alt != nil {
+		match = func(p Pattern) bool { return p.Match(ip) || p.Match(alt) }
+	}
+	var sources []string
+	for _, pattern := range m.patterns {
+		if match(pattern.Pattern) {
+			sources = append(sources, pattern.Source)
+		}
+	}
+	return sources
+}
+
+// negated reports whether ip matches any of the negated patterns, or with
+// all, misses any of the other patterns.
+func (m *Matcher) negated(ip IPAddress) bool {
+	alt := m.mapped(ip)
+	for _, pattern := range m.negations {
+		if pattern.Match(ip) || alt != nil && pattern.Match(alt) {
+			return true
+		}
+	}
+	if m.all {
+		for _, pattern := range m.patterns {
+			if !pattern.Pattern.Match(ip) && (alt == nil || !pattern.Pattern.Match(alt)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mapped returns the other form of ip to be matched as well: the
+// IPv4-mapped IPv6 address of an IPv4 address and vice versa with mapIPv4,
+// and the IPv4 address embedded in a 6to4 or Teredo address with
+// decodeTransition. It returns nil for the other IP addresses and networks.
+func (m *Matcher) mapped(ip IPAddress) IPAddress {
+	switch ip := ip.(type) {
+	case IPv4Address:
+		if !m.mapIPv4 {
+			return nil
+		}
+		v6 := IPv6Address{}
+		v6.IP[10], v6.IP[11] = 0xff, 0xff
+		copy(v6.IP[12:], ip.IP[:])
+		return v6
+	case IPv6Address:
+		if m.mapIPv4 && isIPv4Mapped(ip) {
+			return IPv4Address{IP: [4]byte(ip.IP[12:])}
+		}
+		if m.decodeTransition {
+			if v4, ok := EmbeddedIPv4(ip); ok {
+				return v4
+			}
+		}
+	}
+	return nil
+}
+
+// lookup returns the index of a pattern that ip matches, or -1 if none.
+// If first is set, it returns the smallest one. The other form of ip returned
+// by mapped is looked up as well.
+func (m *Matcher) lookup(ip IPAddress, first bool) int {
+	found := m.lookupForm(ip, first)
+	if found >= 0 && !first {
+		return found
+	}
+	if alt := m.mapped(ip); alt != nil {
+		if index := m.lookupForm(alt, first); index >= 0 && (found < 0 || index < found) {
+			found = index
+		}
+	}
+	return found
+}
+
+// lookupForm is like lookup but looks up ip only.
+func (m *Matcher) lookupForm(ip IPAddress, first bool) int {
+	found := -1
+	trie := m.prefixes4
+	if ip.Version() == 6 {
+		trie = m.prefixes6
+	}
+	if trie != nil {
+		found = trie.match(ip.Bytes(), first)
+	}
+	if found >= 0 && !first {
+		return found
+	}
+	// the others are in the order of the patterns
+	for _, index := range m.others {
+		if found >= 0 && index > found {
+			break
+		}
+		if m.patterns[index].Pattern.Match(ip) {
+			return index
+		}
+	}
+	return found
+}
+
+// MatchString parses s as an IP address and reports whether it matches any
+// of the patterns.
+func (m *Matcher) MatchString(s string) (bool, error) {
+	ip, err := ParseIp(s)
+	if err != nil {
+		return false, err
+	}
+	return m.Match(ip), nil
+}
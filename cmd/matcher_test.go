@@ -2,6 +2,7 @@ package cmd_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -92,18 +93,174 @@ func TestParseIp(t *testing.T) {
 			expectedIP:  nil,
 			expectedErr: cmd.ErrInvalidIP,
 		},
+		{
+			description: "Double Colon for No Blocks",
+			ipStr:       "1:2:3:4:5:6:7::8",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Too Many Colons around Double Colon",
+			ipStr:       ":0:0:0:::0:0:0:",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Empty Leading Block",
+			ipStr:       ":1:2:3:4:5:6:7",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Empty Trailing Block",
+			ipStr:       "1:2:3:4:5:6:7:",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Triple Colon",
+			ipStr:       "1:::2",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
 		{
 			description: "Not IPv6 Address",
 			ipStr:       "202222:::1:12321:::1:1:21:1:1:4",
 			expectedIP:  nil,
 			expectedErr: cmd.ErrInvalidIP,
 		},
+		{
+			description: "IPv4-Mapped IPv6 Address",
+			ipStr:       "::ffff:192.168.1.1",
+			expectedIP: cmd.IPv6Address{IP: [16]byte{
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0xff, 0xff, 0xc0, 0xa8, 0x01, 0x01,
+			}},
+			expectedErr: nil,
+		},
+		{
+			description: "Not Compressed IPv4-Mapped IPv6 Address",
+			ipStr:       "0:0:0:0:0:ffff:10.0.0.255",
+			expectedIP: cmd.IPv6Address{IP: [16]byte{
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0xff, 0xff, 0x0a, 0x00, 0x00, 0xff,
+			}},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv4-Embedded IPv6 Address",
+			ipStr:       "2001:db8::192.168.1.1",
+			expectedIP: cmd.IPv6Address{IP: [16]byte{
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0xc0, 0xa8, 0x01, 0x01,
+			}},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv4-Mapped IPv6 Address with Invalid IPv4",
+			ipStr:       "::ffff:192.168.1.256",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv4 Address Not in Last Group",
+			ipStr:       "::192.168.1.1:ffff",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Too Long IPv4-Mapped IPv6 Address",
+			ipStr:       "0:0:0:0:0:0:ffff:10.0.0.255",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv6 Address with Zone",
+			ipStr:       "fe80::1%eth0",
+			expectedIP: cmd.IPv6Address{IP: [16]byte{
+				0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			}, Zone: "eth0"},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv6 Address with Numeric Zone",
+			ipStr:       "fe80::1%25",
+			expectedIP: cmd.IPv6Address{IP: [16]byte{
+				0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			}, Zone: "25"},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv6 Address with Empty Zone",
+			ipStr:       "fe80::1%",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv6 Address with Interface Name Zone",
+			ipStr:       "fe80::1%br-lan.10_a",
+			expectedIP: cmd.IPv6Address{IP: [16]byte{
+				0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			}, Zone: "br-lan.10_a"},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv6 Address with Zone Followed by Text",
+			ipStr:       "fe80::1%eth0 some text",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv6 Address with Punctuation in Zone",
+			ipStr:       "fe80::1%eth0,",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv6 Address with Two Zones",
+			ipStr:       "fe80::1%eth0%eth1",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv4 Address with Zone",
+			ipStr:       "192.168.0.1%eth0",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
 		{
 			description: "IPv4 Address",
 			ipStr:       "192.168.0.1",
 			expectedIP:  cmd.IPv4Address{IP: [4]byte{192, 168, 0, 1}},
 			expectedErr: nil,
 		},
+		{
+			description: "IPv4 Address with Zero Octets",
+			ipStr:       "10.0.0.0",
+			expectedIP:  cmd.IPv4Address{IP: [4]byte{10, 0, 0, 0}},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv4 Address with Leading Zero",
+			ipStr:       "192.168.0.010",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv4 Address with Double Zero",
+			ipStr:       "192.168.00.1",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv4 Address with Sign",
+			ipStr:       "192.168.+1.1",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
 		{
 			description: "Too Large IPv4 Address",
 			ipStr:       "192.168.0.256",
@@ -128,6 +285,24 @@ func TestParseIp(t *testing.T) {
 			expectedIP:  nil,
 			expectedErr: cmd.ErrInvalidIP,
 		},
+		{
+			description: "IPv4 Address with Trailing Dot",
+			ipStr:       "10.0.0.1.",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv4 Address with Four Digit Octet",
+			ipStr:       "1000.0.0.1",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Empty String",
+			ipStr:       "",
+			expectedIP:  nil,
+			expectedErr: cmd.ErrInvalidIP,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -152,7 +327,7 @@ func TestParseIPPattern(t *testing.T) {
 		{
 			description: "IPv6 No Masks Pattern",
 			pattern:     "2001:db8::abcd:01ff:fe00:0",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP: cmd.IPv6Address{IP: [16]byte{
 					0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
 					0xab, 0xcd, 0x01, 0xff, 0xfe, 0x00, 0x00, 0x00,
@@ -162,10 +337,30 @@ func TestParseIPPattern(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			description: "IPv4 Prefix Pattern with Host Bits",
+			pattern:     "192.168.1.5/24",
+			expectedPattern: cmd.MaskPattern{
+				IP:        cmd.IPv4Address{IP: [4]byte{192, 168, 1, 0}},
+				MaskEnd:   24,
+				MaskStart: 0,
+			},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv4 Suffix Pattern with Network Bits",
+			pattern:     "10.0.0.1/-8",
+			expectedPattern: cmd.MaskPattern{
+				IP:        cmd.IPv4Address{IP: [4]byte{0, 0, 0, 1}},
+				MaskEnd:   32,
+				MaskStart: 24,
+			},
+			expectedErr: nil,
+		},
 		{
 			description: "IPv6 Prefix Pattern",
 			pattern:     "fe80::/10",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP: cmd.IPv6Address{IP: [16]byte{
 					0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
@@ -178,7 +373,7 @@ func TestParseIPPattern(t *testing.T) {
 		{
 			description: "IPv6 Suffix Pattern",
 			pattern:     "::100/-9",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP: cmd.IPv6Address{IP: [16]byte{
 					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00,
@@ -191,7 +386,7 @@ func TestParseIPPattern(t *testing.T) {
 		{
 			description: "IPv6 Prefix and Suffix Pattern",
 			pattern:     "::abcd:01ff:fe00:0/-64/104",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP: cmd.IPv6Address{IP: [16]byte{
 					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 					0xab, 0xcd, 0x01, 0xff, 0xfe, 0x00, 0x00, 0x00,
@@ -202,29 +397,105 @@ func TestParseIPPattern(t *testing.T) {
 			expectedErr: nil,
 		},
 		{
-			description: "IPv6 Invalid Pattern",
-			pattern:     "::abcd:01ff:fe00:0/-64/129",
-			expectedPattern: cmd.Pattern{
-				IP:        nil,
-				MaskEnd:   0,
-				MaskStart: 0,
+			description: "IPv6 Prefix and Suffix Pattern in Reverse Order",
+			pattern:     "::abcd:01ff:fe00:0/104/-64",
+			expectedPattern: cmd.MaskPattern{
+				IP: cmd.IPv6Address{IP: [16]byte{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0xab, 0xcd, 0x01, 0xff, 0xfe, 0x00, 0x00, 0x00,
+				}},
+				MaskEnd:   104,
+				MaskStart: 64,
 			},
-			expectedErr: cmd.ErrInvalidPattern,
+			expectedErr: nil,
 		},
 		{
-			description: "IPv6 Invalid Pattern",
-			pattern:     "::abcd:01ff:fe00:0/-129",
-			expectedPattern: cmd.Pattern{
-				IP:        nil,
-				MaskEnd:   0,
-				MaskStart: 0,
+			description: "IPv6 Prefix and Suffix Pattern Overlapping by One Bit",
+			pattern:     "::8000:0:0:0/-64/65",
+			expectedPattern: cmd.MaskPattern{
+				IP: cmd.IPv6Address{IP: [16]byte{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				}},
+				MaskEnd:   65,
+				MaskStart: 64,
 			},
-			expectedErr: cmd.ErrInvalidPattern,
+			expectedErr: nil,
+		},
+		{
+			description:     "IPv6 Prefix before Suffix",
+			pattern:         "::abcd:01ff:fe00:0/-64/24",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Prefix Ending at Suffix",
+			pattern:         "::abcd:01ff:fe00:0/64/-64",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Two Prefixes",
+			pattern:         "::abcd:01ff:fe00:0/96/104",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Two Suffixes",
+			pattern:         "::abcd:01ff:fe00:0/-64/-32",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Three Masks",
+			pattern:         "::abcd:01ff:fe00:0/-64/104/-64",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Suffix after Prefix",
+			pattern:         "::abcd:01ff:fe00:0/-64/32",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv4 Prefix before Suffix",
+			pattern:         "0.0.100.0/-16/8",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv4 Prefix Ending at Suffix",
+			pattern:         "0.0.100.0/-16/16",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description: "IPv4 Prefix and Suffix Pattern Overlapping by One Bit",
+			pattern:     "0.0.128.0/17/-16",
+			expectedPattern: cmd.MaskPattern{
+				IP:        cmd.IPv4Address{IP: [4]byte{0, 0, 128, 0}},
+				MaskEnd:   17,
+				MaskStart: 16,
+			},
+			expectedErr: nil,
+		},
+		{
+			description:     "IPv6 Invalid Pattern",
+			pattern:         "::abcd:01ff:fe00:0/-64/129",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Invalid Pattern",
+			pattern:         "::abcd:01ff:fe00:0/-129",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
 		},
 		{
 			description: "IPv4 No Masks Pattern",
 			pattern:     "192.168.1.100",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP:        cmd.IPv4Address{IP: [4]byte{192, 168, 1, 100}},
 				MaskEnd:   32,
 				MaskStart: 0,
@@ -234,7 +505,7 @@ func TestParseIPPattern(t *testing.T) {
 		{
 			description: "IPv4 Prefix Pattern",
 			pattern:     "192.168.1.0/24",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP:        cmd.IPv4Address{IP: [4]byte{192, 168, 1, 0}},
 				MaskEnd:   24,
 				MaskStart: 0,
@@ -244,7 +515,7 @@ func TestParseIPPattern(t *testing.T) {
 		{
 			description: "IPv4 Suffix Pattern",
 			pattern:     "0.0.0.1/-8",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP:        cmd.IPv4Address{IP: [4]byte{0, 0, 0, 1}},
 				MaskEnd:   32,
 				MaskStart: 24,
@@ -254,7 +525,7 @@ func TestParseIPPattern(t *testing.T) {
 		{
 			description: "IPv4 Prefix and Suffix Pattern",
 			pattern:     "0.0.100.0/-16/24",
-			expectedPattern: cmd.Pattern{
+			expectedPattern: cmd.MaskPattern{
 				IP:        cmd.IPv4Address{IP: [4]byte{0, 0, 100, 0}},
 				MaskEnd:   24,
 				MaskStart: 16,
@@ -262,14 +533,223 @@ func TestParseIPPattern(t *testing.T) {
 			expectedErr: nil,
 		},
 		{
-			description: "IPv4 Invalid Pattern",
-			pattern:     "192.168.1.0/-33",
-			expectedPattern: cmd.Pattern{
-				IP:        nil,
-				MaskEnd:   0,
+			description:     "IPv4 Invalid Pattern",
+			pattern:         "192.168.1.0/-33",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Not IP Pattern",
+			pattern:         "hello",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Empty Pattern",
+			pattern:         "",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Not IP Pattern with Mask",
+			pattern:         "hello/24",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Invalid IP Pattern",
+			pattern:         "192.168.1.256/24",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidIP,
+		},
+		{
+			description: "IPv4 Range Pattern",
+			pattern:     "192.168.1.10-192.168.1.50",
+			expectedPattern: cmd.RangePattern{
+				Start: cmd.IPv4Address{IP: [4]byte{192, 168, 1, 10}},
+				End:   cmd.IPv4Address{IP: [4]byte{192, 168, 1, 50}},
+			},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv6 Range Pattern",
+			pattern:     "2001:db8::1-2001:db8::1:0",
+			expectedPattern: cmd.RangePattern{
+				Start: cmd.IPv6Address{IP: [16]byte{
+					0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+				}},
+				End: cmd.IPv6Address{IP: [16]byte{
+					0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00,
+				}},
+			},
+			expectedErr: nil,
+		},
+		{
+			description: "Single Address Range Pattern",
+			pattern:     "10.0.0.1-10.0.0.1",
+			expectedPattern: cmd.RangePattern{
+				Start: cmd.IPv4Address{IP: [4]byte{10, 0, 0, 1}},
+				End:   cmd.IPv4Address{IP: [4]byte{10, 0, 0, 1}},
+			},
+			expectedErr: nil,
+		},
+		{
+			description:     "Reversed Range Pattern",
+			pattern:         "192.168.1.50-192.168.1.10",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Mixed Versions Range Pattern",
+			pattern:         "10.0.0.1-::1",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Invalid Endpoint Range Pattern",
+			pattern:         "10.0.0.1-10.0.0.256",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Too Many Endpoints Range Pattern",
+			pattern:         "10.0.0.1-10.0.0.2-10.0.0.3",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description: "IPv4 Wildcard Pattern",
+			pattern:     "192.168.*.5",
+			expectedPattern: cmd.WildcardPattern{
+				IP:   cmd.IPv4Address{IP: [4]byte{192, 168, 0, 5}},
+				Mask: []byte{0xff, 0xff, 0x00, 0xff},
+			},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv6 Wildcard Pattern",
+			pattern:     "2001:db8:*::1",
+			expectedPattern: cmd.WildcardPattern{
+				IP: cmd.IPv6Address{IP: [16]byte{
+					0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+				}},
+				Mask: []byte{
+					0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0xff, 0xff,
+					0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv6 Wildcard Pattern after Double Colon",
+			pattern:     "fe80::*:1",
+			expectedPattern: cmd.WildcardPattern{
+				IP: cmd.IPv6Address{IP: [16]byte{
+					0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+				}},
+				Mask: []byte{
+					0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+					0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0xff, 0xff,
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			description:     "Malformed IPv4 Wildcard Pattern",
+			pattern:         "192.168.1*2.5",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Malformed IPv6 Wildcard Pattern",
+			pattern:         "2001:db8:a*::1",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Wildcard Pattern with Mask",
+			pattern:         "192.168.*.0/24",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Too Short IPv4 Wildcard Pattern",
+			pattern:         "192.168.*",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description: "IPv4 Netmask Pattern",
+			pattern:     "192.168.1.0/255.255.255.0",
+			expectedPattern: cmd.MaskPattern{
+				IP:        cmd.IPv4Address{IP: [4]byte{192, 168, 1, 0}},
+				MaskEnd:   24,
+				MaskStart: 0,
+			},
+			expectedErr: nil,
+		},
+		{
+			description: "IPv4 Netmask Pattern in Middle of Octet",
+			pattern:     "10.1.16.0/255.255.240.0",
+			expectedPattern: cmd.MaskPattern{
+				IP:        cmd.IPv4Address{IP: [4]byte{10, 1, 16, 0}},
+				MaskEnd:   20,
 				MaskStart: 0,
 			},
-			expectedErr: cmd.ErrInvalidPattern,
+			expectedErr: nil,
+		},
+		{
+			description: "IPv4 Non-Contiguous Netmask Pattern",
+			pattern:     "192.168.0.5/255.255.0.255",
+			expectedPattern: cmd.WildcardPattern{
+				IP:   cmd.IPv4Address{IP: [4]byte{192, 168, 0, 5}},
+				Mask: []byte{0xff, 0xff, 0x00, 0xff},
+			},
+			expectedErr: nil,
+		},
+		{
+			description:     "IPv4 Invalid Netmask Pattern",
+			pattern:         "192.168.1.0/255.255.255.256",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv4 Netmask Pattern with Suffix",
+			pattern:         "192.168.1.0/-8/255.255.255.0",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "IPv6 Netmask Pattern",
+			pattern:         "2001:db8::/255.255.255.0",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description: "Negated Pattern",
+			pattern:     "!10.0.0.0/8",
+			expectedPattern: cmd.NegatedPattern{Pattern: cmd.MaskPattern{
+				IP:        cmd.IPv4Address{IP: [4]byte{10, 0, 0, 0}},
+				MaskEnd:   8,
+				MaskStart: 0,
+			}},
+			expectedErr: nil,
+		},
+		{
+			description:     "Double Negated Pattern",
+			pattern:         "!!10.0.0.0/8",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
+		},
+		{
+			description:     "Negated Invalid Pattern",
+			pattern:         "!hello",
+			expectedPattern: nil,
+			expectedErr:     cmd.ErrInvalidPattern,
 		},
 	}
 
@@ -353,7 +833,19 @@ func TestIPPatternMatch(t *testing.T) {
 			expected:    false,
 		},
 		{
-			description: "IPv4 No Masks Pattern",
+			description: "IPv6 Address with Zone",
+			pattern:     "fe80::/64",
+			ip:          "fe80::1%eth0",
+			expected:    true,
+		},
+		{
+			description: "IPv6 Pattern with Zone",
+			pattern:     "fe80::1%eth0",
+			ip:          "fe80::1%eth1",
+			expected:    true,
+		},
+		{
+			description: "IPv4 No Masks Pattern",
 			pattern:     "192.168.100.1",
 			ip:          "192.168.100.1",
 			expected:    true,
@@ -376,6 +868,30 @@ func TestIPPatternMatch(t *testing.T) {
 			ip:          "192.168.100.101",
 			expected:    true,
 		},
+		{
+			description: "IPv4 /-8 Pattern and Other Last Octet",
+			pattern:     "0.0.0.101/-8",
+			ip:          "192.168.100.102",
+			expected:    false,
+		},
+		{
+			description: "IPv4 /-16/24 Pattern",
+			pattern:     "0.0.100.0/-16/24",
+			ip:          "10.20.100.7",
+			expected:    true,
+		},
+		{
+			description: "IPv4 /-16/24 Pattern and Other Third Octet",
+			pattern:     "0.0.100.0/-16/24",
+			ip:          "10.20.101.7",
+			expected:    false,
+		},
+		{
+			description: "IPv4 /-16/24 Pattern and IPv6 Address",
+			pattern:     "0.0.100.0/-16/24",
+			ip:          "::a14:6407",
+			expected:    false,
+		},
 		{
 			description: "IPv4 No Masks and No Match Pattern",
 			pattern:     "192.168.100.1",
@@ -400,6 +916,138 @@ func TestIPPatternMatch(t *testing.T) {
 			ip:          "10.0.0.1",
 			expected:    false,
 		},
+		{
+			description: "IPv4 Range Pattern",
+			pattern:     "192.168.1.10-192.168.1.50",
+			ip:          "192.168.1.30",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Range Pattern Lower Boundary",
+			pattern:     "192.168.1.10-192.168.1.50",
+			ip:          "192.168.1.10",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Range Pattern Upper Boundary",
+			pattern:     "192.168.1.10-192.168.1.50",
+			ip:          "192.168.1.50",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Range Pattern Below",
+			pattern:     "192.168.1.10-192.168.1.50",
+			ip:          "192.168.1.9",
+			expected:    false,
+		},
+		{
+			description: "IPv4 Range Pattern Above",
+			pattern:     "192.168.1.10-192.168.1.50",
+			ip:          "192.168.1.51",
+			expected:    false,
+		},
+		{
+			description: "IPv4 Range Pattern across Octets",
+			pattern:     "10.0.0.200-10.0.1.20",
+			ip:          "10.0.1.5",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Range Pattern and IPv6 Address",
+			pattern:     "0.0.0.0-255.255.255.255",
+			ip:          "::1",
+			expected:    false,
+		},
+		{
+			description: "IPv6 Range Pattern",
+			pattern:     "2001:db8::1-2001:db8::1:0",
+			ip:          "2001:db8::ffff",
+			expected:    true,
+		},
+		{
+			description: "IPv6 Range Pattern Upper Boundary",
+			pattern:     "2001:db8::1-2001:db8::1:0",
+			ip:          "2001:db8::1:0",
+			expected:    true,
+		},
+		{
+			description: "IPv6 Range Pattern Below",
+			pattern:     "2001:db8::1-2001:db8::1:0",
+			ip:          "2001:db8::",
+			expected:    false,
+		},
+		{
+			description: "IPv6 Range Pattern Above",
+			pattern:     "2001:db8::1-2001:db8::1:0",
+			ip:          "2001:db8::1:1",
+			expected:    false,
+		},
+		{
+			description: "IPv4 Wildcard Pattern",
+			pattern:     "192.168.*.5",
+			ip:          "192.168.100.5",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Wildcard Pattern and No Match",
+			pattern:     "192.168.*.5",
+			ip:          "192.168.100.6",
+			expected:    false,
+		},
+		{
+			description: "IPv4 Multiple Wildcards Pattern",
+			pattern:     "10.*.*.1",
+			ip:          "10.20.30.1",
+			expected:    true,
+		},
+		{
+			description: "IPv4 All Wildcards Pattern",
+			pattern:     "*.*.*.*",
+			ip:          "203.0.113.1",
+			expected:    true,
+		},
+		{
+			description: "IPv6 Wildcard Pattern",
+			pattern:     "2001:db8:*::1",
+			ip:          "2001:db8:abcd::1",
+			expected:    true,
+		},
+		{
+			description: "IPv6 Wildcard Pattern and No Match",
+			pattern:     "2001:db8:*::1",
+			ip:          "2001:db8:abcd:1::1",
+			expected:    false,
+		},
+		{
+			description: "IPv6 Trailing Wildcard Pattern",
+			pattern:     "fe80::*",
+			ip:          "fe80::abcd",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Wildcard Pattern and IPv6 Address",
+			pattern:     "*.*.*.*",
+			ip:          "::1",
+			expected:    false,
+		},
+		{
+			description: "IPv4 Netmask Pattern",
+			pattern:     "192.168.1.0/255.255.255.0",
+			ip:          "192.168.1.200",
+			expected:    true,
+		},
+		{
+			description: "IPv4 Netmask Pattern and No Match",
+			pattern:     "10.1.16.0/255.255.240.0",
+			ip:          "10.1.32.1",
+			expected:    false,
+		},
+		{
+			description: "IPv4 Non-Contiguous Netmask Pattern",
+			pattern:     "192.168.0.5/255.255.0.255",
+			ip:          "192.168.77.5",
+			expected:    true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -486,3 +1134,1038 @@ fe80::5474:3fa5:9fca:99f3
 	}
 
 }
+
+func TestMaskPatternBitRange(t *testing.T) {
+	v4 := func(b ...byte) cmd.IPAddress { return cmd.IPv4Address{IP: [4]byte(b)} }
+	v6 := func(s string) cmd.IPAddress { return mustParseIp(t, s) }
+
+	testCases := []struct {
+		description string
+		pattern     cmd.MaskPattern
+		ip          cmd.IPAddress
+		expected    bool
+	}{
+		{description: "IPv4 Prefix", pattern: cmd.MaskPattern{IP: v4(10, 1, 0, 0), MaskStart: 0, MaskEnd: 16}, ip: v4(10, 1, 200, 3), expected: true},
+		{description: "IPv4 Prefix Mismatch", pattern: cmd.MaskPattern{IP: v4(10, 1, 0, 0), MaskStart: 0, MaskEnd: 16}, ip: v4(10, 2, 200, 3), expected: false},
+		{description: "IPv4 Suffix", pattern: cmd.MaskPattern{IP: v4(0, 0, 0, 101), MaskStart: 24, MaskEnd: 32}, ip: v4(192, 168, 100, 101), expected: true},
+		{description: "IPv4 Suffix Mismatch", pattern: cmd.MaskPattern{IP: v4(0, 0, 0, 101), MaskStart: 24, MaskEnd: 32}, ip: v4(192, 168, 100, 100), expected: false},
+		{description: "IPv4 Interior", pattern: cmd.MaskPattern{IP: v4(0, 0, 100, 0), MaskStart: 16, MaskEnd: 24}, ip: v4(1, 2, 100, 4), expected: true},
+		{description: "IPv4 Interior Mismatch", pattern: cmd.MaskPattern{IP: v4(0, 0, 100, 0), MaskStart: 16, MaskEnd: 24}, ip: v4(1, 2, 101, 4), expected: false},
+		{description: "IPv4 Unaligned Bits", pattern: cmd.MaskPattern{IP: v4(0, 0, 0x0f, 0), MaskStart: 20, MaskEnd: 28}, ip: v4(0, 0, 0xaf, 0x0f), expected: true},
+		{description: "IPv4 Unaligned Bits Mismatch", pattern: cmd.MaskPattern{IP: v4(0, 0, 0x0f, 0), MaskStart: 20, MaskEnd: 28}, ip: v4(0, 0, 0xaf, 0x1f), expected: false},
+		{description: "IPv4 Empty Range", pattern: cmd.MaskPattern{IP: v4(0, 0, 0, 0), MaskStart: 16, MaskEnd: 16}, ip: v4(1, 2, 3, 4), expected: true},
+		{description: "IPv6 Prefix", pattern: cmd.MaskPattern{IP: v6("2001:db8::"), MaskStart: 0, MaskEnd: 32}, ip: v6("2001:db8::1"), expected: true},
+		{description: "IPv6 Prefix Mismatch", pattern: cmd.MaskPattern{IP: v6("2001:db8::"), MaskStart: 0, MaskEnd: 32}, ip: v6("2001:db9::1"), expected: false},
+		{description: "IPv6 Suffix", pattern: cmd.MaskPattern{IP: v6("::1"), MaskStart: 64, MaskEnd: 128}, ip: v6("2001:db8::1"), expected: true},
+		{description: "IPv6 Suffix Mismatch", pattern: cmd.MaskPattern{IP: v6("::1"), MaskStart: 64, MaskEnd: 128}, ip: v6("2001:db8::2"), expected: false},
+		{description: "IPv6 Interior", pattern: cmd.MaskPattern{IP: v6("::abcd:1ff:fe00:0"), MaskStart: 64, MaskEnd: 104}, ip: v6("fe80::abcd:1ff:fe12:3456"), expected: true},
+		{description: "IPv6 Interior Mismatch", pattern: cmd.MaskPattern{IP: v6("::abcd:1ff:fe00:0"), MaskStart: 64, MaskEnd: 104}, ip: v6("fe80::abcd:1ff:fd12:3456"), expected: false},
+		{description: "Other Version", pattern: cmd.MaskPattern{IP: v4(0, 0, 0, 0), MaskStart: 0, MaskEnd: 0}, ip: v6("::"), expected: false},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		if tc.pattern.Match(tc.ip) != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, tc.pattern.Match(tc.ip))
+		}
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	testCases := []struct {
+		description   string
+		patterns      []string
+		expectedIndex int
+		expectedErr   error
+	}{
+		{
+			description: "Valid Patterns",
+			patterns:    []string{"192.168.57.0/24", "10.222.0.0/16", "fe80::5400:0:0:0/72"},
+			expectedErr: nil,
+		},
+		{
+			description:   "Invalid Pattern",
+			patterns:      []string{"192.168.57.0/24", "hello", "10.0.0.0/33"},
+			expectedIndex: 1,
+			expectedErr:   cmd.ErrInvalidPattern,
+		},
+		{
+			description:   "Invalid IP in Pattern",
+			patterns:      []string{"192.168.57.0/24", "10.222.0.0/16", "fe80::5400::0:0:0/72"},
+			expectedIndex: 2,
+			expectedErr:   cmd.ErrInvalidIP,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		m, err := cmd.NewMatcher(tc.patterns)
+		if tc.expectedErr == nil {
+			if err != nil || m == nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		var perr *cmd.PatternError
+		if !errors.As(err, &perr) {
+			t.Errorf("expected pattern error, got: %v", err)
+			continue
+		}
+		if perr.Index != tc.expectedIndex {
+			t.Errorf("expected index: %v, got: %v", tc.expectedIndex, perr.Index)
+		}
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+	}
+}
+
+func TestMatcherMatchString(t *testing.T) {
+	m, err := cmd.NewMatcher([]string{"192.168.57.0/24", "10.222.0.0/16", "fe80::5400:0:0:0/72", "::1/-16"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		ip          string
+		expected    bool
+		expectedErr error
+	}{
+		{description: "IPv4 First Pattern", ip: "192.168.57.163", expected: true},
+		{description: "IPv4 Second Pattern", ip: "10.222.200.200", expected: true},
+		{description: "IPv4 No Match", ip: "10.223.254.126", expected: false},
+		{description: "IPv6 Prefix Pattern", ip: "fe80::5474:3fa5:9fca:99f3", expected: true},
+		{description: "IPv6 Suffix Pattern", ip: "2001:db8::1", expected: true},
+		{description: "IPv6 No Match", ip: "fe80::3454:183e:39aa:9a3a", expected: false},
+		{description: "Invalid IP", ip: "hello", expected: false, expectedErr: cmd.ErrInvalidIP},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		matched, err := m.MatchString(tc.ip)
+		if err != tc.expectedErr {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if matched != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, matched)
+		}
+		if err != nil {
+			continue
+		}
+		ip, _ := cmd.ParseIp(tc.ip)
+		if m.Match(ip) != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, m.Match(ip))
+		}
+	}
+}
+
+func TestIPAddressString(t *testing.T) {
+	testCases := []struct {
+		description string
+		ip          cmd.IPAddress
+		expected    string
+	}{
+		{
+			description: "IPv4 Address",
+			ip:          cmd.IPv4Address{IP: [4]byte{192, 168, 0, 1}},
+			expected:    "192.168.0.1",
+		},
+		{
+			description: "IPv4 Zero Address",
+			ip:          cmd.IPv4Address{IP: [4]byte{0, 0, 0, 0}},
+			expected:    "0.0.0.0",
+		},
+		{
+			description: "IPv6 Unspecified Address",
+			ip:          cmd.IPv6Address{},
+			expected:    "::",
+		},
+		{
+			description: "IPv6 Loopback Address",
+			ip: cmd.IPv6Address{IP: [16]byte{
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			}},
+			expected: "::1",
+		},
+		{
+			description: "IPv6 Trailing Zeros",
+			ip: cmd.IPv6Address{IP: [16]byte{
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			}},
+			expected: "2001:db8::",
+		},
+		{
+			description: "IPv6 Leading Zeros in Blocks",
+			ip: cmd.IPv6Address{IP: [16]byte{
+				0x20, 0x01, 0x0d, 0xb8, 0x85, 0xa3, 0x00, 0x00,
+				0x00, 0x00, 0x8a, 0x2e, 0x03, 0x70, 0x73, 0x34,
+			}},
+			expected: "2001:db8:85a3::8a2e:370:7334",
+		},
+		{
+			description: "IPv6 Single Zero Block",
+			ip: cmd.IPv6Address{IP: [16]byte{
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x01, 0x00, 0x01, 0x00, 0x01, 0x00, 0x01,
+			}},
+			expected: "2001:db8:0:1:1:1:1:1",
+		},
+		{
+			description: "IPv6 Longest Zero Run",
+			ip: cmd.IPv6Address{IP: [16]byte{
+				0x20, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			}},
+			expected: "2001:0:0:1::1",
+		},
+		{
+			description: "IPv6 First of Equal Zero Runs",
+			ip: cmd.IPv6Address{IP: [16]byte{
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			}},
+			expected: "2001:db8::1:0:0:1",
+		},
+		{
+			description: "IPv6 Uppercase Input",
+			ip:          mustParseIp(t, "FE80::ABCD:01FF:FE00:0"),
+			expected:    "fe80::abcd:1ff:fe00:0",
+		},
+		{
+			description: "IPv4-Mapped IPv6 Address",
+			ip:          mustParseIp(t, "::ffff:c0a8:101"),
+			expected:    "::ffff:192.168.1.1",
+		},
+		{
+			description: "IPv6 Address with Zone",
+			ip:          mustParseIp(t, "fe80:0::1%eth0"),
+			expected:    "fe80::1%eth0",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		if tc.ip.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, tc.ip.String())
+		}
+	}
+}
+
+func mustParseIp(t testing.TB, s string) cmd.IPAddress {
+	t.Helper()
+	ip, err := cmd.ParseIp(s)
+	if err != nil {
+		t.Fatalf("parse ip: unexpected error: %v", err)
+	}
+	return ip
+}
+
+func TestMatcherNegation(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		ip          string
+		expected    bool
+	}{
+		{
+			description: "Negation Only",
+			patterns:    []string{"!10.0.0.0/8"},
+			ip:          "192.168.0.1",
+			expected:    true,
+		},
+		{
+			description: "Negation Only and Negated Address",
+			patterns:    []string{"!10.0.0.0/8"},
+			ip:          "10.0.0.1",
+			expected:    false,
+		},
+		{
+			description: "Negation Only and Other Version",
+			patterns:    []string{"!10.0.0.0/8"},
+			ip:          "::1",
+			expected:    true,
+		},
+		{
+			description: "Positive and Negation",
+			patterns:    []string{"10.0.0.0/8", "!10.222.0.0/16"},
+			ip:          "10.1.0.1",
+			expected:    true,
+		},
+		{
+			description: "Positive and Negated Address",
+			patterns:    []string{"10.0.0.0/8", "!10.222.0.0/16"},
+			ip:          "10.222.0.1",
+			expected:    false,
+		},
+		{
+			description: "Negation Precedes Other Positive",
+			patterns:    []string{"10.222.0.0/16", "10.0.0.0/8", "!10.222.0.0/16"},
+			ip:          "10.222.0.1",
+			expected:    false,
+		},
+		{
+			description: "Neither Positive nor Negated",
+			patterns:    []string{"10.0.0.0/8", "!10.222.0.0/16"},
+			ip:          "192.168.0.1",
+			expected:    false,
+		},
+		{
+			description: "Negated Range",
+			patterns:    []string{"fe80::/10", "!fe80::1-fe80::ff"},
+			ip:          "fe80::100",
+			expected:    true,
+		},
+		{
+			description: "Negated Named Pattern",
+			patterns:    []string{"!private"},
+			ip:          "172.16.0.1",
+			expected:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		m, err := cmd.NewMatcher(tc.patterns)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		matched, err := m.MatchString(tc.ip)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if matched != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, matched)
+		}
+	}
+}
+
+func TestMatcherMatchSource(t *testing.T) {
+	testCases := []struct {
+		description     string
+		patterns        []string
+		ip              string
+		expectedSource  string
+		expectedMatched bool
+	}{
+		{
+			description:     "Prefix",
+			patterns:        []string{"192.168.0.0/16", "10.0.0.0/8"},
+			ip:              "10.0.0.5",
+			expectedSource:  "10.0.0.0/8",
+			expectedMatched: true,
+		},
+		{
+			description:     "Shorter Prefix First",
+			patterns:        []string{"10.0.0.0/8", "10.0.0.0/16"},
+			ip:              "10.0.0.5",
+			expectedSource:  "10.0.0.0/8",
+			expectedMatched: true,
+		},
+		{
+			description:     "Longer Prefix First",
+			patterns:        []string{"10.0.0.0/16", "10.0.0.0/8"},
+			ip:              "10.0.0.5",
+			expectedSource:  "10.0.0.0/16",
+			expectedMatched: true,
+		},
+		{
+			description:     "Suffix before Prefix",
+			patterns:        []string{"0.0.0.5/-8", "10.0.0.0/8"},
+			ip:              "10.0.0.5",
+			expectedSource:  "0.0.0.5/-8",
+			expectedMatched: true,
+		},
+		{
+			description:     "Prefix before Suffix",
+			patterns:        []string{"10.0.0.0/8", "0.0.0.5/-8"},
+			ip:              "10.0.0.5",
+			expectedSource:  "10.0.0.0/8",
+			expectedMatched: true,
+		},
+		{
+			description:     "No Match",
+			patterns:        []string{"10.0.0.0/8"},
+			ip:              "192.168.0.1",
+			expectedSource:  "",
+			expectedMatched: false,
+		},
+		{
+			description:     "Negated Address",
+			patterns:        []string{"10.0.0.0/8", "!10.0.0.5"},
+			ip:              "10.0.0.5",
+			expectedSource:  "",
+			expectedMatched: false,
+		},
+		{
+			description:     "Negation Only",
+			patterns:        []string{"!10.0.0.5"},
+			ip:              "10.0.0.6",
+			expectedSource:  "",
+			expectedMatched: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		m, err := cmd.NewMatcher(tc.patterns)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		source, matched := m.MatchSource(mustParseIp(t, tc.ip))
+		if source != tc.expectedSource || matched != tc.expectedMatched {
+			t.Errorf("expected: %v %v, got: %v %v", tc.expectedSource, tc.expectedMatched, source, matched)
+		}
+	}
+}
+
+func TestMatcherMatchLongest(t *testing.T) {
+	nested := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}
+
+	testCases := []struct {
+		description     string
+		patterns        []string
+		ip              string
+		expectedSource  string
+		expectedMatched bool
+	}{
+		{
+			description:     "Longest",
+			patterns:        nested,
+			ip:              "10.1.2.3",
+			expectedSource:  "10.1.2.0/24",
+			expectedMatched: true,
+		},
+		{
+			description:     "Middle",
+			patterns:        nested,
+			ip:              "10.1.9.9",
+			expectedSource:  "10.1.0.0/16",
+			expectedMatched: true,
+		},
+		{
+			description:     "Shortest",
+			patterns:        nested,
+			ip:              "10.9.9.9",
+			expectedSource:  "10.0.0.0/8",
+			expectedMatched: true,
+		},
+		{
+			description:     "Longest First",
+			patterns:        []string{"10.1.2.0/24", "10.1.0.0/16", "10.0.0.0/8"},
+			ip:              "10.1.2.3",
+			expectedSource:  "10.1.2.0/24",
+			expectedMatched: true,
+		},
+		{
+			description:     "Same Prefix",
+			patterns:        []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.0.0/16"},
+			ip:              "10.1.2.3",
+			expectedSource:  "10.1.0.0/16",
+			expectedMatched: true,
+		},
+		{
+			description:     "IPv6",
+			patterns:        []string{"fe80::/10", "fe80::5400:0:0:0/72"},
+			ip:              "fe80::5474:3fa5:9fca:99f3",
+			expectedSource:  "fe80::5400:0:0:0/72",
+			expectedMatched: true,
+		},
+		{
+			description:     "Prefix over Suffix",
+			patterns:        []string{"0.0.0.3/-8", "10.0.0.0/8"},
+			ip:              "10.1.2.3",
+			expectedSource:  "10.0.0.0/8",
+			expectedMatched: true,
+		},
+		{
+			description:     "Suffix without Prefix",
+			patterns:        []string{"0.0.0.3/-8", "192.168.0.0/16"},
+			ip:              "10.1.2.3",
+			expectedSource:  "0.0.0.3/-8",
+			expectedMatched: true,
+		},
+		{
+			description:     "No Match",
+			patterns:        nested,
+			ip:              "192.168.0.1",
+			expectedSource:  "",
+			expectedMatched: false,
+		},
+		{
+			description:     "Negated Address",
+			patterns:        append([]string{"!10.1.2.3"}, nested...),
+			ip:              "10.1.2.3",
+			expectedSource:  "",
+			expectedMatched: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		m, err := cmd.NewMatcher(tc.patterns)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		source, matched := m.MatchLongest(mustParseIp(t, tc.ip))
+		if source != tc.expectedSource || matched != tc.expectedMatched {
+			t.Errorf("expected: %v %v, got: %v %v", tc.expectedSource, tc.expectedMatched, source, matched)
+		}
+	}
+}
+
+func TestMatcherMatchAll(t *testing.T) {
+	m, err := cmd.NewMatcher([]string{"10.0.0.0/8", "0.0.0.1/-8", "192.168.0.0/16", "10.0.0.0/24", "!10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		description string
+		ip          string
+		expected    []string
+	}{
+		{description: "All", ip: "10.0.0.1", expected: []string{"10.0.0.0/8", "0.0.0.1/-8", "10.0.0.0/24"}},
+		{description: "One", ip: "192.168.0.5", expected: []string{"192.168.0.0/16"}},
+		{description: "None", ip: "172.16.0.2", expected: nil},
+		{description: "Negated", ip: "10.0.0.2", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		sources := m.MatchAll(mustParseIp(t, tc.ip))
+		if !reflect.DeepEqual(sources, tc.expected) {
+			t.Errorf("expected: %v, got: %v", tc.expected, sources)
+		}
+	}
+}
+
+func TestPatternString(t *testing.T) {
+	testCases := []struct {
+		description string
+		pattern     string
+		expected    string
+	}{
+		{description: "IPv4 Prefix", pattern: "192.168.100.0/24", expected: "192.168.100.0/24"},
+		{description: "IPv4 Prefix with Host Bits", pattern: "192.168.100.5/24", expected: "192.168.100.0/24"},
+		{description: "IPv4 Address", pattern: "10.0.0.1", expected: "10.0.0.1/32"},
+		{description: "IPv4 Zero Prefix", pattern: "0.0.0.0/0", expected: "0.0.0.0/0"},
+		{description: "IPv4 Suffix", pattern: "0.0.0.1/-8", expected: "0.0.0.1/-8"},
+		{description: "IPv4 Suffix and Prefix", pattern: "1.2.3.4/24/-16", expected: "0.0.3.0/-16/24"},
+		{description: "IPv6 Prefix", pattern: "2001:0db8::/32", expected: "2001:db8::/32"},
+		{description: "IPv6 Suffix", pattern: "::abcd:01ff:fe00:0/-64", expected: "::abcd:1ff:fe00:0/-64"},
+		{description: "IPv6 Suffix and Prefix", pattern: "::abcd:01ff:fe00:0/-64/104", expected: "::abcd:1ff:fe00:0/-64/104"},
+		{description: "IPv4-Mapped Prefix", pattern: "::ffff:192.168.0.0/112", expected: "::ffff:192.168.0.0/112"},
+		{description: "Netmask", pattern: "192.168.1.0/255.255.255.0", expected: "192.168.1.0/24"},
+		{description: "Octet Netmask", pattern: "10.1.2.3/255.0.255.0", expected: "10.*.2.*"},
+		{description: "Non-Contiguous Netmask", pattern: "10.1.2.3/255.0.255.240", expected: "10.0.2.0/255.0.255.240"},
+		{description: "IPv4 Range", pattern: "192.168.1.10-192.168.1.50", expected: "192.168.1.10-192.168.1.50"},
+		{description: "IPv6 Range", pattern: "2001:db8::1-2001:db8::ff", expected: "2001:db8::1-2001:db8::ff"},
+		{description: "IPv4 Wildcard", pattern: "192.168.*.5", expected: "192.168.*.5"},
+		{description: "IPv4 Wildcards", pattern: "*.*.0.*", expected: "*.*.0.*"},
+		{description: "IPv6 Wildcard", pattern: "2001:db8:*::1", expected: "2001:db8:*::1"},
+		{description: "IPv6 Wildcard without Zeros", pattern: "*:1:2:3:4:5:6:*", expected: "*:1:2:3:4:5:6:*"},
+		{description: "IPv6 Leading Zeros", pattern: "::*", expected: "::*"},
+		{description: "Named", pattern: "private", expected: "private"},
+		{description: "Named Documentation", pattern: "documentation", expected: "documentation"},
+		{description: "Negated", pattern: "!10.0.0.0/8", expected: "!10.0.0.0/8"},
+		{description: "Negated Named", pattern: "!loopback", expected: "!loopback"},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		pattern, err := cmd.ParsePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if pattern.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, pattern.String())
+		}
+		// the string parses into the same pattern
+		reparsed, err := cmd.ParsePattern(pattern.String())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if !reflect.DeepEqual(reparsed, pattern) {
+			t.Errorf("expected: %v, got: %v", pattern, reparsed)
+		}
+	}
+}
+
+func TestPatternStringRoundTrip(t *testing.T) {
+	patterns := []string{
+		// MaskPattern
+		"192.168.100.0/24", "0.0.0.1/-8", "::abcd:01ff:fe00:0/-64/104", "10.0.0.1", "fe80::%eth0/10",
+		"192.168.1.0/255.255.255.0", "mac:00:12:34:56:78:90/-64",
+		// WildcardPattern
+		"192.168.*.5", "10.1.2.3/255.0.255.240", "2001:db8:*::1",
+		// RangePattern
+		"192.168.1.10-192.168.1.50", "2001:db8::1-2001:db8::ff",
+		// CompositePattern
+		"private", "loopback", "multicast", "link-local", "documentation", "bogon",
+		// NegatedPattern
+		"!10.0.0.0/8", "!192.168.*.5", "!10.0.0.1-10.0.0.9", "!bogon",
+	}
+
+	for _, s := range patterns {
+		fmt.Println(s)
+		pattern, err := cmd.ParsePattern(s)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		reparsed, err := cmd.ParsePattern(pattern.String())
+		if err != nil {
+			t.Errorf("unexpected error of %q: %v", pattern.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(reparsed, pattern) {
+			t.Errorf("expected: %v, got: %v", pattern, reparsed)
+		}
+		// the string is the same every time
+		if reparsed.String() != pattern.String() {
+			t.Errorf("expected: %v, got: %v", pattern.String(), reparsed.String())
+		}
+	}
+}
+
+func TestIPAddressEqual(t *testing.T) {
+	testCases := []struct {
+		description string
+		a           string
+		b           string
+		expected    bool
+	}{
+		{description: "IPv4 Equal", a: "192.168.0.1", b: "192.168.0.1", expected: true},
+		{description: "IPv4 Unequal", a: "192.168.0.1", b: "192.168.0.2", expected: false},
+		{description: "IPv6 Equal", a: "2001:db8::1", b: "2001:0db8:0:0::0001", expected: true},
+		{description: "IPv6 Unequal", a: "2001:db8::1", b: "2001:db8::2", expected: false},
+		{description: "IPv6 Same Zone", a: "fe80::1%eth0", b: "fe80::1%eth0", expected: true},
+		{description: "IPv6 Other Zone", a: "fe80::1%eth0", b: "fe80::1%eth1", expected: false},
+		{description: "IPv6 without Zone", a: "fe80::1%eth0", b: "fe80::1", expected: false},
+		{description: "IPv4 and IPv4-Mapped", a: "192.168.0.1", b: "::ffff:192.168.0.1", expected: false},
+		{description: "IPv4 and IPv6 of Same Bytes", a: "0.0.0.0", b: "::", expected: false},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		a := mustParseIp(t, tc.a)
+		b := mustParseIp(t, tc.b)
+		if a.Equal(b) != tc.expected || b.Equal(a) != tc.expected {
+			t.Errorf("expected: %v, got: %v %v", tc.expected, a.Equal(b), b.Equal(a))
+		}
+	}
+
+	n, err := cmd.ParseNetwork("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		other    string
+		expected bool
+	}{
+		{other: "10.0.0.0/8", expected: true},
+		{other: "10.0.0.0/16", expected: false},
+		{other: "10.0.0.1/8", expected: false},
+	} {
+		other, err := cmd.ParseNetwork(tc.other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.Equal(other) != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, n.Equal(other))
+		}
+	}
+	if n.Equal(mustParseIp(t, "10.0.0.0")) {
+		t.Errorf("expected a network unequal to an address")
+	}
+}
+
+func TestIPAddressArithmetic(t *testing.T) {
+	type arithmetic interface {
+		Next() cmd.IPAddress
+		Prev() cmd.IPAddress
+		Add(n int) cmd.IPAddress
+	}
+
+	testCases := []struct {
+		description  string
+		ip           string
+		n            int
+		expectedNext string
+		expectedPrev string
+		expectedAdd  string
+	}{
+		{
+			description:  "IPv4 Address",
+			ip:           "192.168.0.1",
+			n:            10,
+			expectedNext: "192.168.0.2",
+			expectedPrev: "192.168.0.0",
+			expectedAdd:  "192.168.0.11",
+		},
+		{
+			description:  "IPv4 Carry and Borrow",
+			ip:           "10.0.255.255",
+			n:            -65536,
+			expectedNext: "10.1.0.0",
+			expectedPrev: "10.0.255.254",
+			expectedAdd:  "9.255.255.255",
+		},
+		{
+			description:  "IPv4 Top",
+			ip:           "255.255.255.255",
+			n:            2,
+			expectedNext: "0.0.0.0",
+			expectedPrev: "255.255.255.254",
+			expectedAdd:  "0.0.0.1",
+		},
+		{
+			description:  "IPv4 Bottom",
+			ip:           "0.0.0.0",
+			n:            -2,
+			expectedNext: "0.0.0.1",
+			expectedPrev: "255.255.255.255",
+			expectedAdd:  "255.255.255.254",
+		},
+		{
+			description:  "IPv6 Carry across Halves",
+			ip:           "2001:db8:0:0:ffff:ffff:ffff:ffff",
+			n:            0x10001,
+			expectedNext: "2001:db8:0:1::",
+			expectedPrev: "2001:db8::ffff:ffff:ffff:fffe",
+			expectedAdd:  "2001:db8:0:1::1:0",
+		},
+		{
+			description:  "IPv6 Borrow across Halves",
+			ip:           "2001:db8:0:1::",
+			n:            -1 << 32,
+			expectedNext: "2001:db8:0:1::1",
+			expectedPrev: "2001:db8::ffff:ffff:ffff:ffff",
+			expectedAdd:  "2001:db8::ffff:ffff:0:0",
+		},
+		{
+			description:  "IPv6 Top",
+			ip:           "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			n:            1,
+			expectedNext: "::",
+			expectedPrev: "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe",
+			expectedAdd:  "::",
+		},
+		{
+			description:  "IPv6 Bottom with Zone",
+			ip:           "::%eth0",
+			n:            -1,
+			expectedNext: "::1%eth0",
+			expectedPrev: "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff%eth0",
+			expectedAdd:  "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff%eth0",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ip := mustParseIp(t, tc.ip).(arithmetic)
+		if next := ip.Next().String(); next != tc.expectedNext {
+			t.Errorf("next: expected: %v, got: %v", tc.expectedNext, next)
+		}
+		if prev := ip.Prev().String(); prev != tc.expectedPrev {
+			t.Errorf("prev: expected: %v, got: %v", tc.expectedPrev, prev)
+		}
+		if added := ip.Add(tc.n).String(); added != tc.expectedAdd {
+			t.Errorf("add: expected: %v, got: %v", tc.expectedAdd, added)
+		}
+	}
+}
+
+// fuzzSeeds are the inputs of the parsers taken from the test cases above.
+var fuzzSeeds = []string{
+	"2001:0db8:85a3:0000:0000:8a2e:0370:7334",
+	"2001:db8::8a2e:370:7334",
+	"::1",
+	"::",
+	"1::",
+	"::ffff:192.168.0.1",
+	"fe80::1%eth0",
+	"192.168.0.1",
+	"0.0.0.0",
+	"255.255.255.255",
+	"256.0.0.1",
+	"192.168.0",
+	"192.168.00.1",
+	"1:2:3:4:5:6:7:8:9",
+	":::",
+	"1::2::3",
+	"",
+	"192.168.100.0/24",
+	"0.0.0.1/-8",
+	"::abcd:01ff:fe00:0/-64/104",
+	"192.168.0.0/255.255.0.0",
+	"192.168.1.10-192.168.1.50",
+	"192.168.*.5",
+	"2001:db8:*::1",
+	"!10.0.0.0/8",
+	"mac:00:12:34:56:78:90/-64",
+	"private",
+	"10.0.0.0/33",
+	"10.0.0.0/0",
+	"::/-129",
+}
+
+func FuzzParseIp(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		ip, err := cmd.ParseIp(s)
+		if err != nil {
+			return
+		}
+		// the canonical form is parsed to the same address
+		again, err := cmd.ParseIp(ip.String())
+		if err != nil {
+			t.Fatalf("%q: failed to parse the canonical form %q: %v", s, ip.String(), err)
+		}
+		if !reflect.DeepEqual(again, ip) {
+			t.Fatalf("%q: expected: %v, got: %v", s, ip, again)
+		}
+	})
+}
+
+func FuzzParsePattern(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	ips := []cmd.IPAddress{
+		cmd.IPv4Address{IP: [4]byte{10, 0, 0, 1}},
+		cmd.IPv6Address{IP: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}},
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		pattern, err := cmd.ParsePattern(s)
+		if err != nil {
+			if pattern != nil {
+				t.Fatalf("%q: expected no pattern with the error: %v", s, err)
+			}
+			return
+		}
+		// matching never panics whatever the version
+		for _, ip := range ips {
+			pattern.Match(ip)
+		}
+		// the string parses into the same pattern
+		reparsed, err := cmd.ParsePattern(pattern.String())
+		if err != nil {
+			t.Fatalf("%q: unexpected error of %q: %v", s, pattern.String(), err)
+		}
+		if !reflect.DeepEqual(reparsed, pattern) {
+			t.Fatalf("%q: expected: %v, got: %v", s, pattern, reparsed)
+		}
+	})
+}
+
+func TestParsePatternHostBits(t *testing.T) {
+	testCases := []struct {
+		description string
+		pattern     string
+		network     string
+	}{
+		{description: "IPv4 Prefix", pattern: "192.168.1.5/24", network: "192.168.1.0/24"},
+		{description: "IPv6 Prefix", pattern: "2001:db8::dead:beef/32", network: "2001:db8::/32"},
+		{description: "IPv4 Netmask", pattern: "192.168.1.5/255.255.255.0", network: "192.168.1.0/24"},
+		{description: "IPv4 Non-Contiguous Netmask", pattern: "10.1.2.3/255.0.255.0", network: "10.0.2.0/255.0.255.0"},
+		{description: "IPv4 Suffix", pattern: "10.0.0.1/-8", network: "0.0.0.1/-8"},
+		{description: "IPv6 Prefix and Suffix", pattern: "ffff::abcd:01ff:fe00:1/-64/104", network: "::abcd:01ff:fe00:0/-64/104"},
+		{description: "Negated", pattern: "!192.168.1.5/24", network: "!192.168.1.0/24"},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		pattern, err := cmd.ParsePattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		network, err := cmd.ParsePattern(tc.network)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(pattern, network) {
+			t.Errorf("expected: %v, got: %v", network, pattern)
+		}
+		if fmt.Sprint(pattern) != fmt.Sprint(network) {
+			t.Errorf("expected: %v, got: %v", fmt.Sprint(network), fmt.Sprint(pattern))
+		}
+	}
+}
+
+func TestParsePatternZeroLength(t *testing.T) {
+	testCases := []struct {
+		description string
+		pattern     string
+		ips         []string
+		others      []string
+		expectedErr error
+	}{
+		{
+			description: "IPv4 Match All",
+			pattern:     "0.0.0.0/0",
+			ips:         []string{"0.0.0.0", "10.1.2.3", "203.0.113.9", "255.255.255.255"},
+			others:      []string{"::", "2001:db8::1", "::ffff:10.1.2.3"},
+		},
+		{
+			description: "IPv4 Match All Netmask",
+			pattern:     "0.0.0.0/0.0.0.0",
+			ips:         []string{"0.0.0.0", "192.168.0.1", "255.255.255.255"},
+			others:      []string{"::1"},
+		},
+		{
+			description: "IPv6 Match All",
+			pattern:     "::/0",
+			ips:         []string{"::", "::1", "2001:db8::1", "fe80::1%eth0", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+			others:      []string{"0.0.0.0", "10.1.2.3"},
+		},
+		{
+			description: "IPv4 Too Long Prefix",
+			pattern:     "0.0.0.0/33",
+			expectedErr: cmd.ErrInvalidPattern,
+		},
+		{
+			description: "IPv6 Too Long Prefix",
+			pattern:     "::/129",
+			expectedErr: cmd.ErrInvalidPattern,
+		},
+		{
+			description: "IPv6 Too Long Suffix",
+			pattern:     "::/-129",
+			expectedErr: cmd.ErrInvalidPattern,
+		},
+		{
+			description: "Zero Length Suffix",
+			pattern:     "::/-0",
+			expectedErr: cmd.ErrInvalidPattern,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		pattern, err := cmd.ParsePattern(tc.pattern)
+		if err != tc.expectedErr {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		m, err := cmd.NewMatcher([]string{tc.pattern})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, s := range tc.ips {
+			ip := mustParseIp(t, s)
+			if !pattern.Match(ip) || !m.Match(ip) {
+				t.Errorf("expected %v to match %v", tc.pattern, s)
+			}
+		}
+		for _, s := range tc.others {
+			ip := mustParseIp(t, s)
+			if pattern.Match(ip) || m.Match(ip) {
+				t.Errorf("expected %v not to match %v", tc.pattern, s)
+			}
+		}
+	}
+}
+
+// benchmarkParseIp reports the time and the allocations parsing each of the
+// IP addresses in turn.
+func benchmarkParseIp(b *testing.B, ips []string) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.ParseIp(ips[i%len(ips)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseIPv4(b *testing.B) {
+	benchmarkParseIp(b, []string{"192.168.57.1", "10.222.0.1", "8.8.8.8", "255.255.255.255"})
+}
+
+func BenchmarkParseIPv6(b *testing.B) {
+	b.Run("Compressed", func(b *testing.B) {
+		benchmarkParseIp(b, []string{"fe80::5400:4ff:fe6f:1d4b", "2001:db8::1", "::1", "ff02::1:ff00:0"})
+	})
+	b.Run("Uncompressed", func(b *testing.B) {
+		benchmarkParseIp(b, []string{"fe80:0000:0000:0000:5400:04ff:fe6f:1d4b", "2001:0db8:0000:0000:0000:0000:0000:0001"})
+	})
+	b.Run("Hex Letters", func(b *testing.B) {
+		benchmarkParseIp(b, []string{"fedc:ba98:abcd:ef01:ffff:eeee:dddd:cccc", "FEDC:BA98:ABCD:EF01:FFFF:EEEE:DDDD:CCCC"})
+	})
+	b.Run("Embedded IPv4", func(b *testing.B) {
+		benchmarkParseIp(b, []string{"::ffff:192.168.57.1", "64:ff9b::10.222.0.1"})
+	})
+}
+
+func BenchmarkParsePattern(b *testing.B) {
+	patterns := []string{
+		"192.168.57.0/24",
+		"0.0.0.1/-8",
+		"fe80::/10",
+		"::abcd:01ff:fe00:0/-64/104",
+		"192.168.1.10-192.168.1.50",
+		"192.168.*.5",
+		"fe80::*:1",
+		"private",
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmd.ParsePattern(patterns[i%len(patterns)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	benchmarks := []struct {
+		description string
+		pattern     string
+		ips         []string
+	}{
+		{"IPv4 Prefix 8", "10.0.0.0/8", []string{"10.222.0.1", "192.168.57.1"}},
+		{"IPv4 Prefix 24", "192.168.57.0/24", []string{"192.168.57.1", "192.168.58.1"}},
+		{"IPv4 Suffix 8", "0.0.0.1/-8", []string{"10.222.0.1", "10.222.0.2"}},
+		{"IPv6 Prefix 10", "fe80::/10", []string{"fe80::1", "2001:db8::1"}},
+		{"IPv6 Prefix 72", "fe80::5400:0:0:0/72", []string{"fe80::5400:4ff:fe6f:1d4b", "fe80::5500:4ff:fe6f:1d4b"}},
+		{"IPv6 Suffix 64 and Prefix 104", "::abcd:01ff:fe00:0/-64/104", []string{"::abcd:1ff:fe00:1", "::abcd:2ff:fe00:1"}},
+	}
+
+	for _, bm := range benchmarks {
+		pattern, err := cmd.ParsePattern(bm.pattern)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m, err := cmd.NewMatcher([]string{bm.pattern})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ips := make([]cmd.IPAddress, len(bm.ips))
+		for i, s := range bm.ips {
+			ips[i] = mustParseIp(b, s)
+		}
+
+		b.Run(bm.description+"/Pattern", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pattern.Match(ips[i%len(ips)])
+			}
+		})
+		b.Run(bm.description+"/Matcher", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.Match(ips[i%len(ips)])
+			}
+		})
+	}
+}
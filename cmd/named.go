@@ -0,0 +1,70 @@
+package cmd
+
+// namedPatterns are the special-purpose ranges of both IPv4 and IPv6 which
+// can be used as patterns by their names.
+var namedPatterns = map[string][]string{
+	// RFC 1918, RFC 4193
+	"private": {"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+	// RFC 1122, RFC 4291
+	"loopback": {"127.0.0.0/8", "::1/128"},
+	// RFC 5771, RFC 4291
+	"multicast": {"224.0.0.0/4", "ff00::/8"},
+	// RFC 3927, RFC 4291
+	"link-local": {"169.254.0.0/16", "fe80::/10"},
+	// RFC 5737, RFC 3849, RFC 9637
+	"documentation": {"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32", "3fff::/20"},
+	// the IANA IPv4 and IPv6 Special-Purpose Address Registries (RFC 6890)
+	// not globally reachable, and the multicast and the reserved ranges
+	"bogon": {
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.0.0.0/24", "192.0.2.0/24", "192.168.0.0/16", "198.18.0.0/15",
+		"198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "::ffff:0:0/96", "64:ff9b:1::/48", "100::/64", "2001:2::/48",
+		"2001:10::/28", "2001:db8::/32", "3fff::/20", "fc00::/7", "fe80::/10", "fec0::/10", "ff00::/8",
+	},
+}
+
+// parseNamedPattern returns the pattern for the name of special-purpose
+// ranges. The second result is false if the name is unknown.
+func parseNamedPattern(name string) (Pattern, bool) {
+	ps, ok := namedPatterns[name]
+	if !ok {
+		return nil, false
+	}
+	patterns := make([]Pattern, len(ps))
+	for i, p := range ps {
+		parsed, err := ParsePattern(p)
+		if err != nil {
+			panic("invalid named pattern: " + p)
+		}
+		patterns[i] = parsed
+	}
+	return CompositePattern{Name: name, Patterns: patterns}, true
+}
+
+// classes are the special-purpose ranges used by Classify in order.
+var classes = []string{"loopback", "private", "link-local", "multicast", "documentation"}
+
+// classPatterns are the patterns of classes.
+var classPatterns = func() []Pattern {
+	patterns := make([]Pattern, len(classes))
+	for i, class := range classes {
+		patterns[i], _ = parseNamedPattern(class)
+	}
+	return patterns
+}()
+
+// Classify returns the special-purpose range of ip: "loopback", "private",
+// "link-local", "multicast" or "documentation", or "global" if none. An IPv4-mapped IPv6 address is classified by its IPv4
+// address.
+func Classify(ip IPAddress) string {
+	if v6, ok := ip.(IPv6Address); ok && isIPv4Mapped(v6) {
+		ip = IPv4Address{IP: [4]byte(v6.IP[12:])}
+	}
+	for i, pattern := range classPatterns {
+		if pattern.Match(ip) {
+			return classes[i]
+		}
+	}
+	return "global"
+}
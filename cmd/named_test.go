@@ -0,0 +1,176 @@
+package cmd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestNamedPatternMatch(t *testing.T) {
+	testCases := []struct {
+		description string
+		pattern     string
+		ip          string
+		expected    bool
+	}{
+		{description: "Private 10/8", pattern: "private", ip: "10.1.2.3", expected: true},
+		{description: "Private 172.16/12", pattern: "private", ip: "172.31.255.255", expected: true},
+		{description: "Private 192.168/16", pattern: "private", ip: "192.168.0.1", expected: true},
+		{description: "Private ULA", pattern: "private", ip: "fd12:3456::1", expected: true},
+		{description: "Private Public IPv4", pattern: "private", ip: "172.32.0.1", expected: false},
+		{description: "Private Public IPv4 Resolver", pattern: "private", ip: "8.8.8.8", expected: false},
+		{description: "Private Public IPv6", pattern: "private", ip: "2606:4700::1111", expected: false},
+		{description: "Loopback IPv4", pattern: "loopback", ip: "127.0.0.1", expected: true},
+		{description: "Loopback IPv6", pattern: "loopback", ip: "::1", expected: true},
+		{description: "Loopback Not", pattern: "loopback", ip: "::2", expected: false},
+		{description: "Multicast IPv4", pattern: "multicast", ip: "239.255.255.250", expected: true},
+		{description: "Multicast IPv6", pattern: "multicast", ip: "ff02::fb", expected: true},
+		{description: "Multicast Not", pattern: "multicast", ip: "240.0.0.1", expected: false},
+		{description: "Link-Local IPv4", pattern: "link-local", ip: "169.254.10.20", expected: true},
+		{description: "Link-Local IPv6", pattern: "link-local", ip: "fe80::1", expected: true},
+		{description: "Link-Local Not", pattern: "link-local", ip: "fec0::1", expected: false},
+		{description: "Documentation IPv4", pattern: "documentation", ip: "198.51.100.7", expected: true},
+		{description: "Documentation IPv6", pattern: "documentation", ip: "2001:db8::1", expected: true},
+		{description: "Documentation Not", pattern: "documentation", ip: "192.0.3.1", expected: false},
+		{description: "Bogon Private", pattern: "bogon", ip: "192.168.0.1", expected: true},
+		{description: "Bogon Shared", pattern: "bogon", ip: "100.64.0.1", expected: true},
+		{description: "Bogon This Network", pattern: "bogon", ip: "0.1.2.3", expected: true},
+		{description: "Bogon Documentation IPv4", pattern: "bogon", ip: "198.51.100.7", expected: true},
+		{description: "Bogon Benchmarking", pattern: "bogon", ip: "198.19.0.1", expected: true},
+		{description: "Bogon Reserved", pattern: "bogon", ip: "240.0.0.1", expected: true},
+		{description: "Bogon Broadcast", pattern: "bogon", ip: "255.255.255.255", expected: true},
+		{description: "Bogon Multicast IPv4", pattern: "bogon", ip: "239.255.255.250", expected: true},
+		{description: "Bogon Loopback IPv6", pattern: "bogon", ip: "::1", expected: true},
+		{description: "Bogon IPv4-Mapped", pattern: "bogon", ip: "::ffff:8.8.8.8", expected: true},
+		{description: "Bogon Discard", pattern: "bogon", ip: "100::1", expected: true},
+		{description: "Bogon Documentation IPv6", pattern: "bogon", ip: "2001:db8::1", expected: true},
+		{description: "Bogon ULA", pattern: "bogon", ip: "fd12:3456::1", expected: true},
+		{description: "Bogon Site-Local", pattern: "bogon", ip: "fec0::1", expected: true},
+		{description: "Bogon Public IPv4", pattern: "bogon", ip: "8.8.8.8", expected: false},
+		{description: "Bogon Public IPv4 Next to Shared", pattern: "bogon", ip: "100.128.0.1", expected: false},
+		{description: "Bogon Public IPv4 Next to Benchmarking", pattern: "bogon", ip: "198.20.0.1", expected: false},
+		{description: "Bogon Public IPv6", pattern: "bogon", ip: "2606:4700::1111", expected: false},
+		{description: "Bogon 6to4", pattern: "bogon", ip: "2002:c000:204::1", expected: false},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		pattern, err := cmd.ParsePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("parse pattern: unexpected error: %v", err)
+			continue
+		}
+		ip, err := cmd.ParseIp(tc.ip)
+		if err != nil {
+			t.Errorf("parse ip: unexpected error: %v", err)
+			continue
+		}
+		if pattern.Match(ip) != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, pattern.Match(ip))
+		}
+	}
+}
+
+func TestRootCmdPrivate(t *testing.T) {
+	paths := writeFiles(t, sampleInput+"\n8.8.8.8\nfd00::1\n")
+
+	out, err := execute(t, "--private", "-c", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// 15 IPv4 addresses of the sample and the ULA
+	if out != "16\n" {
+		t.Errorf("expected: %v, got: %v", "16\n", out)
+	}
+}
+
+func TestRootCmdBogon(t *testing.T) {
+	paths := writeFiles(t, "8.8.8.8\n10.0.0.1\n192.0.2.1\n240.0.0.1\n2606:4700::1111\nfe80::1\n")
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+	}{
+		{
+			description: "Bogon",
+			args:        []string{"--bogon"},
+			expected:    "10.0.0.1\n192.0.2.1\n240.0.0.1\nfe80::1\n",
+		},
+		{
+			description: "No Bogon",
+			args:        []string{"--no-bogon"},
+			expected:    "8.8.8.8\n2606:4700::1111\n",
+		},
+		{
+			description: "No Bogon with Pattern",
+			args:        []string{"--no-bogon", "-e", "0.0.0.0/0"},
+			expected:    "8.8.8.8\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append(tc.args, paths[0])...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+
+	_, err := execute(t, "--bogon", "--no-bogon", paths[0])
+	if err == nil {
+		t.Errorf("expected an error of --bogon with --no-bogon")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		ip       string
+		expected string
+	}{
+		{ip: "8.8.8.8", expected: "global"},
+		{ip: "2606:4700::1111", expected: "global"},
+		{ip: "10.1.2.3", expected: "private"},
+		{ip: "fd00::1", expected: "private"},
+		{ip: "127.0.0.1", expected: "loopback"},
+		{ip: "::1", expected: "loopback"},
+		{ip: "169.254.0.1", expected: "link-local"},
+		{ip: "fe80::1%eth0", expected: "link-local"},
+		{ip: "224.0.0.251", expected: "multicast"},
+		{ip: "ff02::1", expected: "multicast"},
+		{ip: "203.0.113.9", expected: "documentation"},
+		{ip: "2001:db8::1", expected: "documentation"},
+		// IPv4-mapped IPv6 addresses are classified by the IPv4 addresses
+		{ip: "::ffff:192.168.0.1", expected: "private"},
+		{ip: "::ffff:127.0.0.1", expected: "loopback"},
+		{ip: "::ffff:8.8.8.8", expected: "global"},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.ip)
+		class := cmd.Classify(mustParseIp(t, tc.ip))
+		if class != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, class)
+		}
+	}
+}
+
+func TestRootCmdClassify(t *testing.T) {
+	paths := writeFiles(t, "8.8.8.8\n10.0.0.1\nfe80::1\n")
+
+	out, err := execute(t, "--classify", "-n", "-e", "0.0.0.0/1,::/1,fe80::/10", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `1:8.8.8.8 (global)
+2:10.0.0.1 (private)
+3:fe80::1 (link-local)
+`
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import "net/netip"
+
+// FromNetip returns the IP address of a, or nil if a is the zero Addr. The
+// family of a is preserved: an IPv4-mapped IPv6 address such as
+// ::ffff:192.0.2.1 is an IPv6Address. Call a.Unmap first for an IPv4Address.
+func FromNetip(a netip.Addr) IPAddress {
+	switch {
+	case a.Is4():
+		return IPv4Address{IP: a.As4()}
+	case a.Is6():
+		return IPv6Address{IP: a.As16(), Zone: a.Zone()}
+	}
+	return nil
+}
+
+// Netip returns the IP address as a netip.Addr of IPv4.
+func (ip IPv4Address) Netip() netip.Addr {
+	return netip.AddrFrom4(ip.IP)
+}
+
+// Netip returns the IP address as a netip.Addr of IPv6 with the zone. An
+// IPv4-mapped IPv6 address stays IPv6.
+func (ip IPv6Address) Netip() netip.Addr {
+	return netip.AddrFrom16(ip.IP).WithZone(ip.Zone)
+}
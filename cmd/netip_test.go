@@ -0,0 +1,49 @@
+package cmd_test
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestNetip(t *testing.T) {
+	testCases := []struct {
+		description     string
+		s               string
+		expectedVersion int
+	}{
+		{description: "IPv4", s: "192.0.2.1", expectedVersion: 4},
+		{description: "IPv4 Zero", s: "0.0.0.0", expectedVersion: 4},
+		{description: "IPv4 Broadcast", s: "255.255.255.255", expectedVersion: 4},
+		{description: "IPv6", s: "2001:db8::1", expectedVersion: 6},
+		{description: "IPv6 Unspecified", s: "::", expectedVersion: 6},
+		{description: "IPv6 with Zone", s: "fe80::1%eth0", expectedVersion: 6},
+		{description: "IPv4-Mapped", s: "::ffff:192.0.2.1", expectedVersion: 6},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ip := mustParseIp(t, tc.s)
+		a := ip.(interface{ Netip() netip.Addr }).Netip()
+		if a != netip.MustParseAddr(tc.s) {
+			t.Errorf("expected: %v, got: %v", netip.MustParseAddr(tc.s), a)
+		}
+
+		// the family is preserved both ways
+		converted := cmd.FromNetip(a)
+		if converted.Version() != tc.expectedVersion || !converted.Equal(ip) {
+			t.Errorf("expected: %v, got: %v", ip, converted)
+		}
+		if converted.String() != tc.s {
+			t.Errorf("expected: %v, got: %v", tc.s, converted.String())
+		}
+	}
+}
+
+func TestFromNetipZero(t *testing.T) {
+	if ip := cmd.FromNetip(netip.Addr{}); ip != nil {
+		t.Errorf("expected: nil, got: %v", ip)
+	}
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Network is an IP network in CIDR notation, e.g. 192.168.0.0/24, read as an
+// input line. It implements IPAddress so that it can be sorted and printed
+// like an address, and String returns it in CIDR notation.
+type Network struct {
+	// IP is the address as written, whose host bits may be set.
+	IP IPAddress
+	// Bits is the prefix length.
+	Bits int
+}
+
+func (n Network) Bytes() []byte {
+	return n.IP.Bytes()
+}
+
+func (n Network) Version() int {
+	return n.IP.Version()
+}
+
+func (n Network) String() string {
+	return n.IP.String() + "/" + strconv.Itoa(n.Bits)
+}
+
+// Equal reports whether other is a network with the same address as written
+// and prefix length, so 10.0.0.1/8 is not equal to 10.0.0.0/8.
+func (n Network) Equal(other IPAddress) bool {
+	o, ok := other.(Network)
+	return ok && n.IP.Equal(o.IP) && n.Bits == o.Bits
+}
+
+// ParseNetwork parses s in CIDR notation.
+func ParseNetwork(s string) (Network, error) {
+	ipPart, bitsPart, ok := strings.Cut(s, "/")
+	if !ok {
+		return Network{}, ErrInvalidIP
+	}
+	ip, err := ParseIp(ipPart)
+	if err != nil {
+		return Network{}, err
+	}
+	// 符号や先頭の0を含むプレフィックス長はエラー
+	if bitsPart == "" || strings.Trim(bitsPart, "0123456789") != "" || len(bitsPart) > 1 && bitsPart[0] == '0' {
+		return Network{}, ErrInvalidIP
+	}
+	bits, err := strconv.Atoi(bitsPart)
+	if err != nil || bits > len(ip.Bytes())*8 {
+		return Network{}, ErrInvalidIP
+	}
+	return Network{IP: ip, Bits: bits}, nil
+}
+
+// ParseCIDR parses s in the standard CIDR notation like net.ParseCIDR and
+// returns the network address, whose host bits are cleared, and the prefix
+// length. Unlike ParsePattern, the suffixes and the other extensions are
+// not accepted, nor is a zone.
+func ParseCIDR(s string) (IPAddress, int, error) {
+	n, err := ParseNetwork(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if v6, ok := n.IP.(IPv6Address); ok && v6.Zone != "" {
+		return nil, 0, ErrInvalidIP
+	}
+	return n.First(), n.Bits, nil
+}
+
+// mask returns the netmask of the network.
+func (n Network) mask() []byte {
+	return prefixMask(len(n.Bytes()), 0, n.Bits)
+}
+
+// First returns the first address of the network.
+func (n Network) First() IPAddress {
+	b := n.Bytes()
+	mask := n.mask()
+	first := make([]byte, len(b))
+	for i := range b {
+		first[i] = b[i] & mask[i]
+	}
+	return ipFromBytes(first)
+}
+
+// Last returns the last address of the network.
+func (n Network) Last() IPAddress {
+	b := n.Bytes()
+	mask := n.mask()
+	last := make([]byte, len(b))
+	for i := range b {
+		last[i] = b[i] | ^mask[i]
+	}
+	return ipFromBytes(last)
+}
+
+// ipFromBytes returns the IP address of the 4 or 16 bytes.
+func ipFromBytes(b []byte) IPAddress {
+	if len(b) == 4 {
+		return IPv4Address{IP: [4]byte(b)}
+	}
+	return IPv6Address{IP: [16]byte(b)}
+}
+
+// maskIP returns ip with the bits other than the ones from start up to end
+// cleared. The zone of an IPv6 address is kept.
+func maskIP(ip IPAddress, start, end int) IPAddress {
+	return maskIPWith(ip, prefixMask(len(ip.Bytes()), start, end))
+}
+
+// maskIPWith returns ip with the bits not set in mask cleared. The mask need
+// not be contiguous. The zone of an IPv6 address is kept.
+func maskIPWith(ip IPAddress, mask []byte) IPAddress {
+	b := ip.Bytes()
+	masked := make([]byte, len(b))
+	for i := range b {
+		masked[i] = b[i] & mask[i]
+	}
+	if v6, ok := ip.(IPv6Address); ok {
+		v6.IP = [16]byte(masked)
+		return v6
+	}
+	return ipFromBytes(masked)
+}
+
+// prefixMask returns the mask of size bytes whose bits from start up to end are set.
+func prefixMask(size, start, end int) []byte {
+	mask := make([]byte, size)
+	for i := start; i < end; i++ {
+		mask[i/8] |= 1 << (7 - i%8)
+	}
+	return mask
+}
+
+// fixedBits returns the bits fixed by the pattern and their values, which an
+// address matches if the bits of the address equal them.
+func fixedBits(p Pattern) (value, mask []byte, ok bool) {
+	switch p := p.(type) {
+	case MaskPattern:
+		return p.IP.Bytes(), prefixMask(len(p.IP.Bytes()), p.MaskStart, p.MaskEnd), true
+	case WildcardPattern:
+		return p.IP.Bytes(), p.Mask, true
+	}
+	return nil, nil, false
+}
+
+// containsNetwork reports whether every address of the network matches the pattern.
+// A pattern other than the ones of this package contains only a single address.
+func containsNetwork(p Pattern, n Network) bool {
+	switch p := p.(type) {
+	case RangePattern:
+		return p.Start.Version() == n.Version() &&
+			compareIP(p.Start, n.First()) <= 0 && compareIP(n.Last(), p.End) <= 0
+	case CompositePattern:
+		// a network spanning several patterns is not contained
+		for _, pattern := range p.Patterns {
+			if containsNetwork(pattern, n) {
+				return true
+			}
+		}
+		return false
+	case NegatedPattern:
+		return !overlapsNetwork(p.Pattern, n)
+	}
+
+	if value, mask, ok := fixedBits(p); ok {
+		if len(value) != len(n.Bytes()) {
+			return false
+		}
+		// the fixed bits must be in the prefix and equal to it
+		b := n.Bytes()
+		netmask := n.mask()
+		for i := range mask {
+			if mask[i]&^netmask[i] != 0 || (b[i]^value[i])&mask[i] != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return n.Bits == len(n.Bytes())*8 && p.Match(n.IP)
+}
+
+// overlapsNetwork reports whether any address of the network matches the pattern.
+// A pattern other than the ones of this package overlaps with the network if
+// its first or last address matches.
+func overlapsNetwork(p Pattern, n Network) bool {
+	switch p := p.(type) {
+	case RangePattern:
+		return p.Start.Version() == n.Version() &&
+			compareIP(p.Start, n.Last()) <= 0 && compareIP(n.First(), p.End) <= 0
+	case CompositePattern:
+		for _, pattern := range p.Patterns {
+			if overlapsNetwork(pattern, n) {
+				return true
+			}
+		}
+		return false
+	case NegatedPattern:
+		return !containsNetwork(p.Pattern, n)
+	}
+
+	if value, mask, ok := fixedBits(p); ok {
+		if len(value) != len(n.Bytes()) {
+			return false
+		}
+		// the fixed bits in the prefix must be equal to it
+		b := n.Bytes()
+		netmask := n.mask()
+		for i := range mask {
+			if (b[i]^value[i])&mask[i]&netmask[i] != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return p.Match(n.First()) || p.Match(n.Last())
+}
+
+// prefixNetwork returns the network of a prefix pattern, e.g. 10.0.0.0/8.
+func prefixNetwork(p Pattern) (Network, bool) {
+	mp, ok := p.(MaskPattern)
+	if !ok || mp.MaskStart != 0 {
+		return Network{}, false
+	}
+	return Network{IP: mp.IP, Bits: mp.MaskEnd}, true
+}
+
+// Contains reports whether every address matching inner matches outer, e.g.
+// 10.0.0.0/8 contains 10.1.0.0/16. inner must be a prefix pattern, while outer
+// may be any pattern parsed by ParsePattern. It reports false otherwise and
+// for patterns of different versions.
+func Contains(outer, inner Pattern) bool {
+	n, ok := prefixNetwork(inner)
+	if !ok {
+		return false
+	}
+	return containsNetwork(outer, n)
+}
+
+// Overlaps reports whether any address matches both a and b. Either of them
+// must be a prefix pattern, while the other may be any pattern parsed by
+// ParsePattern. It reports false otherwise and for patterns of different
+// versions.
+func Overlaps(a, b Pattern) bool {
+	if n, ok := prefixNetwork(b); ok {
+		return overlapsNetwork(a, n)
+	}
+	if n, ok := prefixNetwork(a); ok {
+		return overlapsNetwork(b, n)
+	}
+	return false
+}
@@ -0,0 +1,409 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestParseNetwork(t *testing.T) {
+	testCases := []struct {
+		description   string
+		s             string
+		expectedFirst string
+		expectedLast  string
+		expectedErr   error
+	}{
+		{
+			description:   "IPv4 Network",
+			s:             "192.168.0.0/24",
+			expectedFirst: "192.168.0.0",
+			expectedLast:  "192.168.0.255",
+		},
+		{
+			description:   "IPv4 Network with Host Bits",
+			s:             "10.1.2.3/12",
+			expectedFirst: "10.0.0.0",
+			expectedLast:  "10.15.255.255",
+		},
+		{
+			description:   "IPv4 Host",
+			s:             "10.1.2.3/32",
+			expectedFirst: "10.1.2.3",
+			expectedLast:  "10.1.2.3",
+		},
+		{
+			description:   "IPv6 Network",
+			s:             "2001:db8::/33",
+			expectedFirst: "2001:db8::",
+			expectedLast:  "2001:db8:7fff:ffff:ffff:ffff:ffff:ffff",
+		},
+		{
+			description:   "Whole IPv6 Space",
+			s:             "::/0",
+			expectedFirst: "::",
+			expectedLast:  "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+		},
+		{
+			description: "Too Long Prefix",
+			s:           "10.0.0.0/33",
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Negative Prefix",
+			s:           "10.0.0.0/-8",
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Leading Zero Prefix",
+			s:           "10.0.0.0/08",
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Empty Prefix",
+			s:           "10.0.0.0/",
+			expectedErr: cmd.ErrInvalidIP,
+		},
+		{
+			description: "Invalid Address",
+			s:           "10.0.0/8",
+			expectedErr: cmd.ErrInvalidIP,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		n, err := cmd.ParseNetwork(tc.s)
+		if err != tc.expectedErr {
+			t.Errorf("expected: %v, got: %v", tc.expectedErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		if n.First().String() != tc.expectedFirst || n.Last().String() != tc.expectedLast {
+			t.Errorf("expected: %v-%v, got: %v-%v", tc.expectedFirst, tc.expectedLast, n.First(), n.Last())
+		}
+	}
+}
+
+func TestParseCIDR(t *testing.T) {
+	testCases := []struct {
+		description  string
+		s            string
+		expectedIP   string
+		expectedBits int
+		expectedErr  error
+	}{
+		{description: "IPv4", s: "192.0.2.1/24", expectedIP: "192.0.2.0", expectedBits: 24},
+		{description: "IPv4 Host", s: "192.0.2.1/32", expectedIP: "192.0.2.1", expectedBits: 32},
+		{description: "IPv4 Zero", s: "0.0.0.0/0", expectedIP: "0.0.0.0", expectedBits: 0},
+		{description: "IPv6", s: "2001:db8:a0b:12f0::1/32", expectedIP: "2001:db8::", expectedBits: 32},
+		{description: "IPv6 Host", s: "2001:db8::1/128", expectedIP: "2001:db8::1", expectedBits: 128},
+		{description: "IPv6 Zero", s: "::/0", expectedIP: "::", expectedBits: 0},
+		{description: "IPv4 Too Long", s: "192.0.2.1/33", expectedErr: cmd.ErrInvalidIP},
+		{description: "IPv6 Too Long", s: "2001:db8::1/129", expectedErr: cmd.ErrInvalidIP},
+		{description: "No Prefix", s: "192.0.2.1", expectedErr: cmd.ErrInvalidIP},
+		{description: "Suffix", s: "0.0.0.1/-8", expectedErr: cmd.ErrInvalidIP},
+		{description: "Netmask", s: "192.0.2.0/255.255.255.0", expectedErr: cmd.ErrInvalidIP},
+		{description: "Zone", s: "fe80::1%eth0/64", expectedErr: cmd.ErrInvalidIP},
+		{description: "Invalid Address", s: "192.0.2/24", expectedErr: cmd.ErrInvalidIP},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ip, bits, err := cmd.ParseCIDR(tc.s)
+		if err != tc.expectedErr {
+			t.Errorf("expected: %v, got: %v", tc.expectedErr, err)
+		}
+		// the same as net.ParseCIDR
+		_, ipnet, netErr := net.ParseCIDR(tc.s)
+		if (err == nil) != (netErr == nil) {
+			t.Errorf("expected the same result as net.ParseCIDR: %v, got: %v", netErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		ones, _ := ipnet.Mask.Size()
+		if ip.String() != tc.expectedIP || bits != tc.expectedBits || ip.String() != ipnet.IP.String() || bits != ones {
+			t.Errorf("expected: %v/%v, got: %v/%v", tc.expectedIP, tc.expectedBits, ip, bits)
+		}
+	}
+}
+
+func TestMatcherMatchNetwork(t *testing.T) {
+	testCases := []struct {
+		description      string
+		patterns         []string
+		network          string
+		expectedContains bool
+		expectedOverlaps bool
+	}{
+		{
+			description:      "Nested",
+			patterns:         []string{"10.0.0.0/8"},
+			network:          "10.1.0.0/16",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Same",
+			patterns:         []string{"10.0.0.0/8"},
+			network:          "10.0.0.0/8",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Larger",
+			patterns:         []string{"10.1.0.0/16"},
+			network:          "10.0.0.0/8",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Disjoint",
+			patterns:         []string{"10.0.0.0/8"},
+			network:          "192.168.0.0/16",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+		{
+			description:      "Other Version",
+			patterns:         []string{"::/1"},
+			network:          "10.0.0.0/8",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+		{
+			description:      "Suffix Outside Prefix",
+			patterns:         []string{"0.0.0.1/-8"},
+			network:          "10.0.0.0/24",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Suffix in Prefix",
+			patterns:         []string{"0.0.0.1/-8"},
+			network:          "10.0.0.1/32",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Range Containing",
+			patterns:         []string{"10.0.0.0-10.0.1.255"},
+			network:          "10.0.1.0/24",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Range Overlapping",
+			patterns:         []string{"10.0.0.128-10.0.1.255"},
+			network:          "10.0.0.0/24",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Range Disjoint",
+			patterns:         []string{"10.0.1.0-10.0.1.255"},
+			network:          "10.0.0.0/24",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+		{
+			description:      "Wildcard",
+			patterns:         []string{"10.*.*.*"},
+			network:          "10.1.0.0/16",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Named Pattern",
+			patterns:         []string{"private"},
+			network:          "172.16.0.0/12",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Negated Subnet",
+			patterns:         []string{"10.0.0.0/8", "!10.1.0.0/16"},
+			network:          "10.0.0.0/15",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Negated Network",
+			patterns:         []string{"10.0.0.0/8", "!10.0.0.0/15"},
+			network:          "10.1.0.0/16",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		m, err := cmd.NewMatcher(tc.patterns)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		n, err := cmd.ParseNetwork(tc.network)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if contains := m.MatchNetwork(n, false); contains != tc.expectedContains {
+			t.Errorf("contains: expected: %v, got: %v", tc.expectedContains, contains)
+		}
+		if overlaps := m.MatchNetwork(n, true); overlaps != tc.expectedOverlaps {
+			t.Errorf("overlaps: expected: %v, got: %v", tc.expectedOverlaps, overlaps)
+		}
+	}
+}
+
+func TestRunWithOptionsNetworks(t *testing.T) {
+	input := `10.0.0.0/8
+10.1.0.0/16
+10.1.2.3
+192.168.0.0/16
+0.0.0.0/0
+2001:db8::/48`
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Contained",
+			opts:        cmd.Options{},
+			expected: `10.1.0.0/16
+10.1.2.3
+`,
+		},
+		{
+			description: "Overlaps",
+			opts:        cmd.Options{Overlaps: true},
+			expected: `10.0.0.0/8
+10.1.0.0/16
+10.1.2.3
+0.0.0.0/0
+`,
+		},
+		{
+			description: "Inverted",
+			opts:        cmd.Options{Invert: true},
+			expected: `10.0.0.0/8
+192.168.0.0/16
+0.0.0.0/0
+2001:db8::/48
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, []string{"10.0.0.0/12"}, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestContainsOverlaps(t *testing.T) {
+	testCases := []struct {
+		description      string
+		a                string
+		b                string
+		expectedContains bool
+		expectedOverlaps bool
+	}{
+		{
+			description:      "Nested",
+			a:                "10.0.0.0/8",
+			b:                "10.1.0.0/16",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Reversed Nested",
+			a:                "10.1.0.0/16",
+			b:                "10.0.0.0/8",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Same",
+			a:                "2001:db8::/32",
+			b:                "2001:db8::/32",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Disjoint",
+			a:                "10.0.0.0/8",
+			b:                "172.16.0.0/12",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+		{
+			description:      "Mixed Versions",
+			a:                "::/1",
+			b:                "10.0.0.0/8",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+		{
+			description:      "Suffix and Prefix",
+			a:                "0.0.0.1/-8",
+			b:                "10.0.0.0/8",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Prefix and Suffix",
+			a:                "10.0.0.0/8",
+			b:                "0.0.0.1/-8",
+			expectedContains: false,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Range and Prefix",
+			a:                "10.0.0.0-10.255.255.255",
+			b:                "10.1.0.0/16",
+			expectedContains: true,
+			expectedOverlaps: true,
+		},
+		{
+			description:      "Suffixes",
+			a:                "0.0.0.1/-8",
+			b:                "0.0.0.1/-8",
+			expectedContains: false,
+			expectedOverlaps: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		a, err := cmd.ParsePattern(tc.a)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := cmd.ParsePattern(tc.b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if contains := cmd.Contains(a, b); contains != tc.expectedContains {
+			t.Errorf("contains: expected: %v, got: %v", tc.expectedContains, contains)
+		}
+		if overlaps := cmd.Overlaps(a, b); overlaps != tc.expectedOverlaps {
+			t.Errorf("overlaps: expected: %v, got: %v", tc.expectedOverlaps, overlaps)
+		}
+	}
+}
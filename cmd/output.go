@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// checkOutput returns an error if output is not an output of Options.Output.
+func checkOutput(output string) error {
+	switch output {
+	case "", "text", "json", "csv", "ipset":
+		return nil
+	}
+	return fmt.Errorf("invalid output: %s", output)
+}
+
+// record is a selected IP address printed by the structured outputs.
+// The fields other than IP and Version are set only when the corresponding
+// options are set.
+type record struct {
+	IP      string `json:"ip"`
+	Version int    `json:"version"`
+	// Line is set with LineNumber.
+	Line int `json:"line,omitempty"`
+	// File is set when the file names are printed.
+	File string `json:"file,omitempty"`
+	// Pattern is set with ShowPattern.
+	Pattern string `json:"pattern,omitempty"`
+	// Class is set with Classify.
+	Class string `json:"class,omitempty"`
+	// ASN is set with ShowASN, and 0 for no ASN data.
+	ASN uint `json:"asn,omitempty"`
+}
+
+// encodeJSON returns the record as a line of JSON Lines without the newline.
+func encodeJSON(r record) string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		// a record has only strings and numbers
+		panic(err)
+	}
+	return string(b)
+}
+
+// csvHeader is the header row of the csv output, whose columns are the
+// fields of record.
+var csvHeader = []string{"ip", "version", "line", "file", "pattern", "class", "asn"}
+
+// encodeCSV returns the fields as a row of CSV without the newline.
+func encodeCSV(fields []string) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	// writing to a strings.Builder does not fail
+	w.Write(fields)
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// csvFields returns the fields of the record in the columns of csvHeader.
+// The fields not set are empty.
+func (r record) csvFields() []string {
+	line := ""
+	if r.Line > 0 {
+		line = strconv.Itoa(r.Line)
+	}
+	asn := ""
+	if r.ASN > 0 {
+		asn = strconv.FormatUint(uint64(r.ASN), 10)
+	}
+	return []string{r.IP, strconv.Itoa(r.Version), line, r.File, r.Pattern, r.Class, asn}
+}
+
+// DefaultIPSetName is the name of the sets of the ipset output used when
+// Options.IPSetName is empty.
+const DefaultIPSetName = "gipp"
+
+// ipsetName returns the name of the set of the IP version. A set holds the
+// addresses of one family, so the IPv6 set is suffixed with "-v6".
+func ipsetName(name string, version int) string {
+	if name == "" {
+		name = DefaultIPSetName
+	}
+	if version == 6 {
+		return name + "-v6"
+	}
+	return name
+}
+
+// ipsetCreate returns the command of ipset restore creating the set of the
+// IP version.
+func ipsetCreate(name string, version int) string {
+	family := "inet"
+	if version == 6 {
+		family = "inet6"
+	}
+	return fmt.Sprintf("create %s hash:ip family %s", name, family)
+}
+
+// ipsetAdd returns the command of ipset restore adding ip to the set.
+// The zone of ip is dropped as ipset does not accept it.
+func ipsetAdd(name string, ip IPAddress) string {
+	if n, ok := ip.(Network); ok {
+		ip = Network{IP: ipFromBytes(n.Bytes()), Bits: n.Bits}
+	} else {
+		ip = ipFromBytes(ip.Bytes())
+	}
+	return fmt.Sprintf("add %s %s", name, ip)
+}
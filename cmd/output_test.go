@@ -0,0 +1,239 @@
+package cmd_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// jsonRecord is an object printed with the json output.
+type jsonRecord struct {
+	IP      string  `json:"ip"`
+	Version int     `json:"version"`
+	Line    *int    `json:"line"`
+	File    *string `json:"file"`
+	Pattern *string `json:"pattern"`
+	Class   *string `json:"class"`
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestRunWithOptionsJSON(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    []jsonRecord
+	}{
+		{
+			description: "Addresses",
+			input:       sampleInput,
+			opts:        cmd.Options{Output: "json"},
+			expected: []jsonRecord{
+				{IP: "10.222.200.200", Version: 4},
+				{IP: "192.168.57.163", Version: 4},
+				{IP: "192.168.57.4", Version: 4},
+				{IP: "fe80::5474:3fa5:9fca:99f3", Version: 6},
+			},
+		},
+		{
+			description: "All Fields",
+			input:       sampleInput,
+			opts:        cmd.Options{Output: "json", LineNumber: true, ShowPattern: true, Classify: true, Filename: "a.txt", WithFilename: true},
+			expected: []jsonRecord{
+				{IP: "10.222.200.200", Version: 4, Line: intPtr(5), File: stringPtr("a.txt"), Pattern: stringPtr("10.222.0.0/16"), Class: stringPtr("private")},
+				{IP: "192.168.57.163", Version: 4, Line: intPtr(10), File: stringPtr("a.txt"), Pattern: stringPtr("192.168.57.0/24"), Class: stringPtr("private")},
+				{IP: "192.168.57.4", Version: 4, Line: intPtr(14), File: stringPtr("a.txt"), Pattern: stringPtr("192.168.57.0/24"), Class: stringPtr("private")},
+				{IP: "fe80::5474:3fa5:9fca:99f3", Version: 6, Line: intPtr(26), File: stringPtr("a.txt"), Pattern: stringPtr("fe80::5400:0:0:0/72"), Class: stringPtr("link-local")},
+			},
+		},
+		{
+			description: "Extract",
+			input:       "from 10.222.0.1 to 192.168.57.1\nfrom 172.16.0.1\n",
+			opts:        cmd.Options{Output: "json", Extract: true, LineNumber: true},
+			expected: []jsonRecord{
+				{IP: "10.222.0.1", Version: 4, Line: intPtr(1)},
+				{IP: "192.168.57.1", Version: 4, Line: intPtr(1)},
+			},
+		},
+		{
+			description: "Inverted",
+			input:       "10.222.0.1\n172.16.0.1\n",
+			opts:        cmd.Options{Output: "json", Invert: true},
+			expected: []jsonRecord{
+				{IP: "172.16.0.1", Version: 4},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// one object per line
+		var records []jsonRecord
+		sc := bufio.NewScanner(outbuf)
+		for sc.Scan() {
+			var r jsonRecord
+			if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			records = append(records, r)
+		}
+		if !reflect.DeepEqual(records, tc.expected) {
+			t.Errorf("expected: %+v, got: %+v", tc.expected, records)
+		}
+	}
+}
+
+func TestRunWithOptionsCSV(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    [][]string
+	}{
+		{
+			description: "Addresses",
+			input:       sampleInput,
+			opts:        cmd.Options{Output: "csv"},
+			expected: [][]string{
+				{"ip", "version", "line", "file", "pattern", "class", "asn"},
+				{"10.222.200.200", "4", "", "", "", "", ""},
+				{"192.168.57.163", "4", "", "", "", "", ""},
+				{"192.168.57.4", "4", "", "", "", "", ""},
+				{"fe80::5474:3fa5:9fca:99f3", "6", "", "", "", "", ""},
+			},
+		},
+		{
+			description: "All Fields",
+			input:       sampleInput,
+			opts:        cmd.Options{Output: "csv", LineNumber: true, ShowPattern: true, Classify: true, Filename: "a,b.txt", WithFilename: true},
+			expected: [][]string{
+				{"ip", "version", "line", "file", "pattern", "class", "asn"},
+				{"10.222.200.200", "4", "5", "a,b.txt", "10.222.0.0/16", "private", ""},
+				{"192.168.57.163", "4", "10", "a,b.txt", "192.168.57.0/24", "private", ""},
+				{"192.168.57.4", "4", "14", "a,b.txt", "192.168.57.0/24", "private", ""},
+				{"fe80::5474:3fa5:9fca:99f3", "6", "26", "a,b.txt", "fe80::5400:0:0:0/72", "link-local", ""},
+			},
+		},
+		{
+			description: "No Header",
+			input:       "10.222.0.1\n172.16.0.1\n",
+			opts:        cmd.Options{Output: "csv", NoHeader: true},
+			expected: [][]string{
+				{"10.222.0.1", "4", "", "", "", "", ""},
+			},
+		},
+		{
+			description: "No Records",
+			input:       "172.16.0.1\n",
+			opts:        cmd.Options{Output: "csv"},
+			expected:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		rows, err := csv.NewReader(outbuf).ReadAll()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(rows, tc.expected) {
+			t.Errorf("expected: %q, got: %q", tc.expected, rows)
+		}
+	}
+}
+
+func TestRunWithOptionsIPSet(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Both Versions",
+			input:       sampleInput,
+			opts:        cmd.Options{Output: "ipset", IPSetName: "blocklist"},
+			expected: `create blocklist hash:ip family inet
+add blocklist 10.222.200.200
+add blocklist 192.168.57.163
+add blocklist 192.168.57.4
+create blocklist-v6 hash:ip family inet6
+add blocklist-v6 fe80::5474:3fa5:9fca:99f3
+`,
+		},
+		{
+			description: "Default Name",
+			input:       "10.222.0.1\n",
+			opts:        cmd.Options{Output: "ipset", LineNumber: true},
+			expected: `create gipp hash:ip family inet
+add gipp 10.222.0.1
+`,
+		},
+		{
+			description: "Zone",
+			input:       "fe80::5474:3fa5:9fca:99f3%eth0\n",
+			opts:        cmd.Options{Output: "ipset"},
+			expected: `create gipp-v6 hash:ip family inet6
+add gipp-v6 fe80::5474:3fa5:9fca:99f3
+`,
+		},
+		{
+			description: "Sorted",
+			input:       "192.168.57.2\nfe80::5474:3fa5:9fca:99f3\n10.222.0.1\n",
+			opts:        cmd.Options{Output: "ipset", Sort: true},
+			expected: `create gipp hash:ip family inet
+create gipp-v6 hash:ip family inet6
+add gipp 10.222.0.1
+add gipp 192.168.57.2
+add gipp-v6 fe80::5474:3fa5:9fca:99f3
+`,
+		},
+		{
+			description: "Extract",
+			input:       "from 10.222.0.1 to 192.168.57.1\n",
+			opts:        cmd.Options{Output: "ipset", Extract: true},
+			expected: `create gipp hash:ip family inet
+add gipp 10.222.0.1
+add gipp 192.168.57.1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
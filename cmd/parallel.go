@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"sync"
+)
+
+// batchSize is the number of lines sent to a worker at once.
+const batchSize = 256
+
+// batch is a chunk of consecutive input lines.
+type batch struct {
+	seq   int
+	lines []scannedLine
+}
+
+// scanParallel is like scan but evaluates the lines with Options.Jobs
+// workers, or one with Options.PTRMatches. The lines are emitted in input order unless Options.NoOrder is
+// set, in which case batches are emitted as soon as they are evaluated.
+// When emit reports to stop, it returns without waiting for the reader, which
+// may still be using sc.
+func (s *searcher) scanParallel(ctx context.Context, sc *bufio.Scanner, emit func(scannedLine) bool) (int, error) {
+	// stop the reader and the workers when emit reports to stop
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	n := max(s.opts.Jobs, 1)
+	jobs := make(chan batch, n)
+	results := make(chan batch, n)
+
+	// read the lines in batches
+	lineno := 0
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		defer close(jobs)
+		seq := 0
+		lines := make([]scannedLine, 0, batchSize)
+		send := func() bool {
+			select {
+			case jobs <- batch{seq: seq, lines: lines}:
+				seq++
+				lines = make([]scannedLine, 0, batchSize)
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for sc.Scan() {
+			lineno++
+			// the bytes of the scanner are reused for the next line
+			text := append([]byte(nil), sc.Bytes()...)
+			lines = append(lines, scannedLine{lineno: lineno, text: text})
+			if len(lines) == batchSize && !send() {
+				return
+			}
+		}
+		if len(lines) > 0 {
+			send()
+		}
+	}()
+
+	// evaluate the batches
+	var workers sync.WaitGroup
+	for i := 0; i < n; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for b := range jobs {
+				s.evaluateBatch(b.lines)
+				select {
+				case results <- b:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	// emit the evaluated lines, reordering the batches by their sequence
+	stopped := false
+	// last is the number of the last line emitted
+	last := 0
+	next := 0
+	pending := map[int]batch{}
+	emitBatch := func(b batch) {
+		for _, l := range b.lines {
+			last = l.lineno
+			if emit(l) {
+				stopped = true
+				cancel()
+				return
+			}
+		}
+	}
+	for b := range results {
+		if s.opts.NoOrder {
+			emitBatch(b)
+		} else {
+			pending[b.seq] = b
+			for !stopped {
+				b, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				emitBatch(b)
+			}
+		}
+		// the reader may be blocked reading a pipe that is never closed, so
+		// it is left to stop at its next line once ctx is canceled
+		if stopped {
+			return last, nil
+		}
+	}
+	readers.Wait()
+
+	// lines may be dropped when the parent context is done
+	if !stopped && ctx.Err() != nil {
+		return lineno, ctx.Err()
+	}
+	return lineno, nil
+}
+
+// evaluateBatch evaluates the lines of a batch. With Options.PTRMatches,
+// they are evaluated concurrently so that their lookups wait for the
+// network together, up to ptrJobs at once.
+func (s *searcher) evaluateBatch(lines []scannedLine) {
+	if s.ptr == nil {
+		for i := range lines {
+			s.evaluate(&lines[i])
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for i := range lines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.evaluate(&lines[i])
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,169 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// generateInput returns n lines of IPv4, IPv6 and invalid lines.
+func generateInput(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&sb, "10.%d.%d.%d\n", i>>16&0xff, i>>8&0xff, i&0xff)
+		case 1:
+			fmt.Fprintf(&sb, "fe80::%x:%x\n", i>>16, i&0xffff)
+		case 2:
+			fmt.Fprintf(&sb, "line %d\n", i)
+		}
+	}
+	return sb.String()
+}
+
+func TestRunWithOptionsJobs(t *testing.T) {
+	input := generateInput(10000)
+	patterns := []string{"10.0.0.0/12", "::1/-1"}
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+	}{
+		{description: "Lines", opts: cmd.Options{}},
+		{description: "Line Number", opts: cmd.Options{LineNumber: true}},
+		{description: "Inverted", opts: cmd.Options{Invert: true, LineNumber: true}},
+		{description: "Count", opts: cmd.Options{Count: true}},
+		{description: "Unique", opts: cmd.Options{Unique: true, Normalize: true}},
+		{description: "Quiet", opts: cmd.Options{Quiet: true}},
+		{description: "Files with Matches", opts: cmd.Options{FilesWithMatches: true}},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		expected := &bytes.Buffer{}
+		expectedMatched, err := cmd.RunWithOptions(strings.NewReader(input), expected, &bytes.Buffer{}, patterns, tc.opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, jobs := range []int{2, 4, 8} {
+			opts := tc.opts
+			opts.Jobs = jobs
+			outbuf := &bytes.Buffer{}
+			matched, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, patterns, opts)
+			if err != nil {
+				t.Errorf("jobs %d: unexpected error: %v", jobs, err)
+			}
+			if matched != expectedMatched {
+				t.Errorf("jobs %d: expected matched: %v, got: %v", jobs, expectedMatched, matched)
+			}
+			if outbuf.String() != expected.String() {
+				t.Errorf("jobs %d: output differs from the sequential one", jobs)
+			}
+		}
+	}
+}
+
+func TestRunWithOptionsJobsNoOrder(t *testing.T) {
+	input := generateInput(10000)
+	patterns := []string{"10.0.0.0/12", "::1/-1"}
+
+	expected := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(input), expected, &bytes.Buffer{}, patterns, cmd.Options{LineNumber: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	outbuf := &bytes.Buffer{}
+	_, err = cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, patterns, cmd.Options{LineNumber: true, Jobs: 4, NoOrder: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the same lines in any order
+	expectedLines := strings.Split(expected.String(), "\n")
+	lines := strings.Split(outbuf.String(), "\n")
+	slices.Sort(expectedLines)
+	slices.Sort(lines)
+	if !slices.Equal(lines, expectedLines) {
+		t.Errorf("lines differ from the sequential ones")
+	}
+}
+
+func TestRunWithOptionsJobsStopOnPipe(t *testing.T) {
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+	}{
+		{description: "Quiet", opts: cmd.Options{Quiet: true}},
+		{description: "Files with Matches", opts: cmd.Options{FilesWithMatches: true}},
+		{description: "No Order", opts: cmd.Options{Quiet: true, NoOrder: true}},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		// the first batch is evaluated while the pipe is never closed
+		r, w := io.Pipe()
+		go w.Write([]byte(strings.Repeat("10.0.0.1\n", 300)))
+
+		tc.opts.Jobs = 4
+		done := make(chan error, 1)
+		go func() {
+			_, err := cmd.RunWithOptions(r, &bytes.Buffer{}, &bytes.Buffer{}, []string{"10.0.0.0/8"}, tc.opts)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("expected to stop at the first selected line without the end of the input")
+		}
+		w.Close()
+	}
+}
+
+func benchmarkRunJobs(b *testing.B, jobs int) {
+	input := generateInput(100000)
+	patterns := []string{"10.0.0.0/12", "fe80::/10", "::1/-1", "192.168.0.0/16"}
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := cmd.RunWithOptions(strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{}, patterns, cmd.Options{Jobs: jobs})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRunSequential(b *testing.B) {
+	benchmarkRunJobs(b, 1)
+}
+
+func BenchmarkRunJobs4(b *testing.B) {
+	benchmarkRunJobs(b, 4)
+}
+
+// BenchmarkRunCount reports the allocations for 100000 lines that are not
+// printed. IPv4 addresses and other text are read without allocations.
+func BenchmarkRunCount(b *testing.B) {
+	input := generateInput(100000)
+	patterns := []string{"192.168.0.0/16"}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := cmd.RunWithOptions(strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{}, patterns, cmd.Options{Count: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
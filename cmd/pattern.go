@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// RangePattern matches IP addresses from Start to End inclusive, written as
+// 192.168.1.10-192.168.1.50.
+type RangePattern struct {
+	Start IPAddress
+	End   IPAddress
+}
+
+func (p RangePattern) Match(ip IPAddress) bool {
+	if ip.Version() != p.Start.Version() {
+		return false
+	}
+	return compareIP(p.Start, ip) <= 0 && compareIP(ip, p.End) <= 0
+}
+
+func (p RangePattern) String() string {
+	return p.Start.String() + "-" + p.End.String()
+}
+
+func parseRangePattern(s string) (Pattern, error) {
+	// ハイフンで分割する
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, ErrInvalidPattern
+	}
+	start, err := ParseIp(parts[0])
+	if err != nil {
+		return nil, ErrInvalidPattern
+	}
+	end, err := ParseIp(parts[1])
+	if err != nil {
+		return nil, ErrInvalidPattern
+	}
+
+	// バージョンが異なる場合や始点が終点より大きい場合はエラー
+	if start.Version() != end.Version() || compareIP(start, end) > 0 {
+		return nil, ErrInvalidPattern
+	}
+
+	return RangePattern{Start: start, End: end}, nil
+}
+
+// WildcardPattern matches IP addresses whose bits set in Mask are equal to
+// the ones of IP. It is written with "*" for any octet of IPv4 or any group
+// of IPv6, e.g. 192.168.*.5 and 2001:db8:*::1.
+type WildcardPattern struct {
+	IP   IPAddress
+	Mask []byte
+}
+
+func (p WildcardPattern) Match(ip IPAddress) bool {
+	if ip.Version() != p.IP.Version() {
+		return false
+	}
+	ipBytes := ip.Bytes()
+	pBytes := p.IP.Bytes()
+	for i := range p.Mask {
+		if (ipBytes[i]^pBytes[i])&p.Mask[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the pattern with "*" for the octets or groups not compared,
+// e.g. 192.168.*.5, or with the netmask of IPv4 such as 10.0.0.1/255.0.0.255
+// if the mask does not fit them.
+func (p WildcardPattern) String() string {
+	ipBytes := p.IP.Bytes()
+	if p.IP.Version() == 4 {
+		blocks := make([]string, 4)
+		for i := range blocks {
+			switch p.Mask[i] {
+			case 0xff:
+				blocks[i] = strconv.Itoa(int(ipBytes[i]))
+			case 0:
+				blocks[i] = "*"
+			default:
+				return p.IP.String() + "/" + ipFromBytes(p.Mask).String()
+			}
+		}
+		return strings.Join(blocks, ".")
+	}
+
+	blocks := make([]string, 8)
+	for i := range blocks {
+		if p.Mask[i*2] == 0 {
+			blocks[i] = "*"
+			continue
+		}
+		blocks[i] = strconv.FormatUint(uint64(ipBytes[i*2])<<8|uint64(ipBytes[i*2+1]), 16)
+	}
+	// 最も長い0のブロックの連続を省略する (ワイルドカードは省略しない)
+	zeroStart, zeroLen := -1, 1
+	for i := 0; i < len(blocks); i++ {
+		j := i
+		for j < len(blocks) && blocks[j] == "0" {
+			j++
+		}
+		if j-i > zeroLen {
+			zeroStart, zeroLen = i, j-i
+		}
+	}
+	str := strings.Join(blocks, ":")
+	if zeroStart >= 0 {
+		str = strings.Join(blocks[:zeroStart], ":") + "::" + strings.Join(blocks[zeroStart+zeroLen:], ":")
+	}
+	if v6, ok := p.IP.(IPv6Address); ok && v6.Zone != "" {
+		str += "%" + v6.Zone
+	}
+	return str
+}
+
+func parseWildcardPattern(s string) (Pattern, error) {
+	// マスクとの併用は不可
+	if strings.Contains(s, "/") {
+		return nil, ErrInvalidPattern
+	}
+	if strings.Contains(s, ":") {
+		return parseIPv6WildcardPattern(s)
+	}
+	return parseIPv4WildcardPattern(s)
+}
+
+func parseIPv4WildcardPattern(s string) (Pattern, error) {
+	blocks := strings.Split(s, ".")
+	if len(blocks) != 4 {
+		return nil, ErrInvalidPattern
+	}
+
+	// ワイルドカードのブロックを0に置き換えてマスクを作る
+	mask := []byte{0xff, 0xff, 0xff, 0xff}
+	for i, block := range blocks {
+		if !strings.Contains(block, "*") {
+			continue
+		}
+		// ワイルドカードが他の文字と混ざっている場合はエラー
+		if block != "*" {
+			return nil, ErrInvalidPattern
+		}
+		blocks[i] = "0"
+		mask[i] = 0
+	}
+
+	ip, err := parseIPv4(strings.Join(blocks, "."))
+	if err != nil {
+		return nil, ErrInvalidPattern
+	}
+	return WildcardPattern{IP: ip, Mask: mask}, nil
+}
+
+func parseIPv6WildcardPattern(s string) (Pattern, error) {
+	// ワイルドカードはゾーン識別子ではなくアドレスのブロックに書く
+	addr, _, _ := strings.Cut(s, "%")
+	if !strings.Contains(addr, "*") {
+		return nil, ErrInvalidPattern
+	}
+	// ワイルドカードのブロックが他の文字と混ざっている場合はエラー
+	for _, block := range strings.Split(addr, ":") {
+		if strings.Contains(block, "*") && block != "*" {
+			return nil, ErrInvalidPattern
+		}
+	}
+
+	// ワイルドカードのブロックの位置を数えるため、IPv4アドレス表記は使えない
+	if strings.Contains(addr, ".") {
+		return nil, ErrInvalidPattern
+	}
+
+	// ワイルドカードのブロックを0に置き換えてアドレスを読む
+	ip, err := parseIPv6(strings.ReplaceAll(addr, "*", "0") + s[len(addr):])
+	if err != nil {
+		return nil, ErrInvalidPattern
+	}
+
+	// ワイルドカードのブロックの位置のマスクを0にする
+	// 略記の前のブロックは先頭から、後ろのブロックは末尾から数える
+	mask := bytes.Repeat([]byte{0xff}, 16)
+	head, tail, _ := strings.Cut(addr, "::")
+	for i, block := range splitIPv6Blocks(head) {
+		if block == "*" {
+			mask[i*2], mask[i*2+1] = 0, 0
+		}
+	}
+	tailBlocks := splitIPv6Blocks(tail)
+	for i, block := range tailBlocks {
+		if block == "*" {
+			j := 8 - len(tailBlocks) + i
+			mask[j*2], mask[j*2+1] = 0, 0
+		}
+	}
+	return WildcardPattern{IP: ip, Mask: mask}, nil
+}
+
+// splitIPv6Blocks splits s by colons, returning no blocks for the empty s
+// before or after "::".
+func splitIPv6Blocks(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ":")
+}
+
+// parseNetmaskPattern parses an IPv4 pattern with a dotted decimal netmask
+// such as 192.168.1.0/255.255.255.0. A contiguous netmask is the same as the
+// prefix length, and a non-contiguous one such as 255.255.0.255 selects the
+// bits like a wildcard pattern.
+func parseNetmaskPattern(ip IPAddress, masks []string) (Pattern, error) {
+	// ネットマスクはIPv4のみで、他のマスクとは併用できない
+	if ip.Version() != 4 || len(masks) != 2 {
+		return nil, ErrInvalidPattern
+	}
+	netmask, err := parseIPv4(masks[1])
+	if err != nil {
+		return nil, ErrInvalidPattern
+	}
+	mask := netmask.Bytes()
+
+	// 先頭から連続する1のビットの数を数える
+	prefix := 0
+	for prefix < 32 && mask[prefix/8]&(1<<(7-prefix%8)) != 0 {
+		prefix++
+	}
+	// 残りのビットに1がある場合は連続していない
+	for i := prefix; i < 32; i++ {
+		if mask[i/8]&(1<<(7-i%8)) != 0 {
+			// マスクの外のビットは0にする
+			return WildcardPattern{IP: maskIPWith(ip, mask), Mask: mask}, nil
+		}
+	}
+
+	return MaskPattern{IP: maskIP(ip, 0, prefix), MaskStart: 0, MaskEnd: prefix}, nil
+}
+
+// CompositePattern matches IP addresses matching any of Patterns. It is
+// written as Name, the name of the special-purpose ranges such as "private",
+// which Patterns must be for String to parse into the same pattern.
+type CompositePattern struct {
+	Name     string
+	Patterns []Pattern
+}
+
+func (p CompositePattern) Match(ip IPAddress) bool {
+	for _, pattern := range p.Patterns {
+		if pattern.Match(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CompositePattern) String() string {
+	return p.Name
+}
+
+// NegatedPattern matches IP addresses not matching the pattern, written with
+// a leading "!" such as !10.0.0.0/8.
+type NegatedPattern struct {
+	Pattern Pattern
+}
+
+func (p NegatedPattern) Match(ip IPAddress) bool {
+	return !p.Pattern.Match(ip)
+}
+
+func (p NegatedPattern) String() string {
+	return "!" + p.Pattern.String()
+}
+
+func parseNegatedPattern(s string) (Pattern, error) {
+	// 否定の否定はエラー
+	if strings.HasPrefix(s, "!") {
+		return nil, ErrInvalidPattern
+	}
+	pattern, err := ParsePattern(s)
+	if err != nil {
+		return nil, err
+	}
+	return NegatedPattern{Pattern: pattern}, nil
+}
+
+// parseMACPattern parses the pattern of the interface IDs generated from a
+// MAC address by EUI-64, written as mac:00:12:34:56:78:90/-64. Without a
+// mask, the whole interface ID of /-64 is compared.
+func parseMACPattern(s string) (Pattern, error) {
+	// MACアドレスとマスクに分割する
+	macPart, maskPart, found := strings.Cut(s, "/")
+	if !found {
+		maskPart = "-64"
+	}
+	mac, err := net.ParseMAC(macPart)
+	if err != nil || len(mac) != 6 {
+		return nil, ErrInvalidPattern
+	}
+
+	// インターフェースIDに変換したアドレスにマスクを適用する
+	ip := MACToEUI64([6]byte(mac), IPv6Address{})
+	pattern, err := ParsePattern(ip.String() + "/" + maskPart)
+	if err != nil {
+		return nil, err
+	}
+	// インターフェースID以外のビットを比較する場合はエラー
+	mp, ok := pattern.(MaskPattern)
+	if !ok || mp.MaskStart < 64 {
+		return nil, ErrInvalidPattern
+	}
+	return mp, nil
+}
+
+// checkStrictCIDR returns a *PatternError wrapping ErrInvalidPattern for the
+// first prefix pattern whose host bits are set, e.g. 192.168.1.5/24. The
+// patterns failing to parse are left to NewMatcher.
+func checkStrictCIDR(patterns []string) error {
+	for i, s := range patterns {
+		inner := strings.TrimPrefix(s, "!")
+		pattern, err := ParsePattern(inner)
+		if err != nil {
+			continue
+		}
+		mp, ok := pattern.(MaskPattern)
+		if !ok || mp.MaskStart != 0 {
+			continue
+		}
+		// the address as written before the host bits are cleared
+		ipPart, _, _ := strings.Cut(inner, "/")
+		ip, err := ParseIp(ipPart)
+		if err != nil {
+			continue
+		}
+		if !ip.Equal(mp.IP) {
+			return &PatternError{Index: i, Pattern: s, Err: ErrInvalidPattern}
+		}
+	}
+	return nil
+}
+
+// negatePatterns returns the patterns with a leading "!", which exclude the IP
+// addresses matching them.
+func negatePatterns(patterns []string) []string {
+	negated := make([]string, len(patterns))
+	for i, s := range patterns {
+		negated[i] = "!" + s
+	}
+	return negated
+}
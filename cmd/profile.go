@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiles starts the CPU profile written to cpuFile, and returns the
+// function stopping it and writing the heap profile to memFile. The files
+// are created first, not to search in vain when they can't be. The empty
+// names skip the profiles.
+func startProfiles(cpuFile, memFile string) (func() error, error) {
+	var cpu, mem *os.File
+	if memFile != "" {
+		f, err := os.Create(memFile)
+		if err != nil {
+			return nil, err
+		}
+		mem = f
+	}
+	if cpuFile != "" {
+		f, err := os.Create(cpuFile)
+		if err == nil {
+			err = pprof.StartCPUProfile(f)
+			if err != nil {
+				f.Close()
+			}
+		}
+		if err != nil {
+			if mem != nil {
+				mem.Close()
+			}
+			return nil, err
+		}
+		cpu = f
+	}
+
+	return func() error {
+		var err error
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			err = cpu.Close()
+		}
+		if mem != nil {
+			// get up-to-date statistics of the live objects
+			runtime.GC()
+			if werr := pprof.WriteHeapProfile(mem); werr != nil && err == nil {
+				err = werr
+			}
+			if cerr := mem.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,36 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRootCmdProfiles(t *testing.T) {
+	paths := writeFiles(t, sampleInput)
+	dir := t.TempDir()
+	cpuPath := filepath.Join(dir, "cpu.pprof")
+	memPath := filepath.Join(dir, "mem.pprof")
+
+	_, err := execute(t, "--cpuprofile", cpuPath, "--memprofile", memPath, "-e", "private", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, path := range []string{cpuPath, memPath} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() == 0 {
+			t.Errorf("expected a non-empty profile: %s", path)
+		}
+	}
+
+	// the files can't be created in the missing directory
+	for _, flag := range []string{"--cpuprofile", "--memprofile"} {
+		_, err := execute(t, flag, filepath.Join(dir, "missing", "profile.pprof"), "-e", "private", paths[0])
+		if err == nil {
+			t.Errorf("expected an error of %s", flag)
+		}
+	}
+}
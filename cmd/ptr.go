@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver looks up the names of IP addresses. *net.Resolver implements it.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// DefaultPTRTimeout is the timeout of a PTR lookup used when
+// Options.PTRTimeout is zero.
+const DefaultPTRTimeout = 5 * time.Second
+
+// ptrJobs is the number of the PTR lookups made concurrently regardless of
+// Options.Jobs. The lookups wait for the network rather than the CPU.
+const ptrJobs = 16
+
+// ptrFilter selects the IP addresses whose PTR records match a regular
+// expression. It is safe to use concurrently.
+type ptrFilter struct {
+	re       *regexp.Regexp
+	resolver Resolver
+	timeout  time.Duration
+
+	// sem bounds the lookups made concurrently to ptrJobs.
+	sem chan struct{}
+
+	mu sync.Mutex
+	// cache holds the results by the canonical forms of the IP addresses,
+	// including the ones being looked up.
+	cache map[string]*ptrResult
+}
+
+// ptrResult is the result of looking up an IP address, which is set before
+// done is closed.
+type ptrResult struct {
+	done    chan struct{}
+	matched bool
+}
+
+// newPTRFilter returns a ptrFilter of the regular expression expr.
+// A nil resolver uses net.DefaultResolver.
+func newPTRFilter(expr string, resolver Resolver, timeout time.Duration) (*ptrFilter, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PTR pattern: %w", err)
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if timeout <= 0 {
+		timeout = DefaultPTRTimeout
+	}
+	return &ptrFilter{
+		re:       re,
+		resolver: resolver,
+		timeout:  timeout,
+		sem:      make(chan struct{}, ptrJobs),
+		cache:    map[string]*ptrResult{},
+	}, nil
+}
+
+// match reports whether any PTR record of ip matches. Networks and the IP
+// addresses failed to look up match nothing. Each IP address is looked up
+// once, and the concurrent calls with it wait for the result.
+func (f *ptrFilter) match(ip IPAddress) bool {
+	if _, ok := ip.(Network); ok {
+		return false
+	}
+	key := ip.String()
+	f.mu.Lock()
+	r, ok := f.cache[key]
+	if !ok {
+		r = &ptrResult{done: make(chan struct{})}
+		f.cache[key] = r
+	}
+	f.mu.Unlock()
+	if ok {
+		<-r.done
+		return r.matched
+	}
+
+	f.sem <- struct{}{}
+	r.matched = f.lookup(key)
+	<-f.sem
+	close(r.done)
+	return r.matched
+}
+
+// lookup looks up the PTR records of the IP address and reports whether any
+// of them matches.
+func (f *ptrFilter) lookup(addr string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	names, _ := f.resolver.LookupAddr(ctx, addr)
+	for _, name := range names {
+		// the names are fully qualified with the trailing dot
+		if f.re.MatchString(strings.TrimSuffix(name, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// stubResolver returns the names of the IP addresses without DNS.
+type stubResolver struct {
+	names map[string][]string
+	// block makes the lookups wait until their contexts are done.
+	block bool
+	// delay makes each lookup take the time.
+	delay time.Duration
+
+	mu      sync.Mutex
+	lookups map[string]int
+	// inFlight and maxInFlight are the numbers of the lookups being made
+	// now and at most.
+	inFlight    int
+	maxInFlight int
+}
+
+func (r *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.mu.Lock()
+	if r.lookups == nil {
+		r.lookups = map[string]int{}
+	}
+	r.lookups[addr]++
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+
+	time.Sleep(r.delay)
+
+	if r.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	names, ok := r.names[addr]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return names, nil
+}
+
+func TestRunWithOptionsPTRMatches(t *testing.T) {
+	resolver := &stubResolver{names: map[string][]string{
+		"10.222.0.1":   {"scanner.evil.example."},
+		"10.222.0.2":   {"www.example.com.", "tor-exit.evil.example."},
+		"10.222.0.3":   {"mail.example.com."},
+		"192.168.57.1": {"host.evil.example."},
+	}}
+
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "Matched",
+			input:       "10.222.0.1\n10.222.0.2\n10.222.0.3\n10.222.0.4\n172.16.0.1\n",
+			opts:        cmd.Options{PTRMatches: `\.evil\.example$`},
+			expected:    "10.222.0.1\n10.222.0.2\n",
+		},
+		{
+			description: "Inverted",
+			input:       "10.222.0.1\n10.222.0.3\n",
+			opts:        cmd.Options{PTRMatches: "evil", Invert: true},
+			expected:    "10.222.0.3\n",
+		},
+		{
+			description: "Extract",
+			input:       "from 10.222.0.3 to 192.168.57.1\nfrom 10.222.0.4\n",
+			opts:        cmd.Options{PTRMatches: "evil", Extract: true, OnlyMatching: true},
+			expected:    "192.168.57.1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		tc.opts.Resolver = resolver
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsPTRMatchesCache(t *testing.T) {
+	resolver := &stubResolver{names: map[string][]string{"10.222.0.1": {"scanner.evil.example."}}}
+	input := strings.Repeat("10.222.0.1\n172.16.0.1\n", 10)
+	outbuf := &bytes.Buffer{}
+	_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{PTRMatches: "evil", Resolver: resolver})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := strings.Repeat("10.222.0.1\n", 10); outbuf.String() != expected {
+		t.Errorf("expected: %v, got: %v", expected, outbuf.String())
+	}
+	// only the matched IP addresses are looked up, once each
+	if expected := map[string]int{"10.222.0.1": 1}; fmt.Sprint(resolver.lookups) != fmt.Sprint(expected) {
+		t.Errorf("expected lookups: %v, got: %v", expected, resolver.lookups)
+	}
+}
+
+func TestRunWithOptionsPTRMatchesConcurrent(t *testing.T) {
+	resolver := &stubResolver{names: map[string][]string{}, delay: 50 * time.Millisecond}
+	input := &strings.Builder{}
+	expected := &strings.Builder{}
+	for i := 1; i <= 40; i++ {
+		addr := fmt.Sprintf("10.222.0.%d", i)
+		resolver.names[addr] = []string{"scanner.evil.example."}
+		fmt.Fprintln(input, addr)
+		fmt.Fprintln(expected, addr)
+	}
+
+	testCases := []struct {
+		description string
+		opts        cmd.Options
+	}{
+		{description: "One Job", opts: cmd.Options{}},
+		{description: "Jobs", opts: cmd.Options{Jobs: 4}},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		resolver.lookups = nil
+		resolver.maxInFlight = 0
+		tc.opts.PTRMatches = "evil"
+		tc.opts.Resolver = resolver
+		outbuf := &bytes.Buffer{}
+		start := time.Now()
+		_, err := cmd.RunWithOptions(strings.NewReader(input.String()), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != expected.String() {
+			t.Errorf("expected: %v, got: %v", expected.String(), outbuf.String())
+		}
+		// 40 lookups one by one would take 2s
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the lookups to be made concurrently, took: %v", elapsed)
+		}
+		if resolver.maxInFlight > 16 {
+			t.Errorf("expected at most 16 lookups at once, got: %v", resolver.maxInFlight)
+		}
+	}
+}
+
+func TestRunWithOptionsPTRTimeout(t *testing.T) {
+	resolver := &stubResolver{block: true}
+	outbuf := &bytes.Buffer{}
+	start := time.Now()
+	_, err := cmd.RunWithOptions(strings.NewReader("10.222.0.1\n10.222.0.2\n"), outbuf, &bytes.Buffer{}, samplePatterns, cmd.Options{PTRMatches: "evil", PTRTimeout: 10 * time.Millisecond, Resolver: resolver})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if outbuf.String() != "" {
+		t.Errorf("expected no lines, got: %v", outbuf.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the lookups to time out, took: %v", elapsed)
+	}
+}
+
+func TestRunWithOptionsInvalidPTRMatches(t *testing.T) {
+	in := &onceReader{data: "10.222.0.1\n"}
+	_, err := cmd.RunWithOptions(in, &bytes.Buffer{}, &bytes.Buffer{}, samplePatterns, cmd.Options{PTRMatches: "(evil", Resolver: &stubResolver{}})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+	if in.read {
+		t.Errorf("expected the input not to be read")
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newRulesCmd() *cobra.Command {
+	var patterns []string
+	var patternFiles []string
+	var format string
+	var action string
+
+	cmd := &cobra.Command{
+		Use:   "rules [flags] [-e pattern] [-f file]",
+		Short: "Generate firewall rules from patterns",
+		Long: `The rules command prints iptables or nftables rules taking the action on the
+packets from the addresses matching the patterns.
+Prefixes, ranges and the names of special-purpose ranges are written as networks
+in CIDR notation. The other patterns, e.g. suffixes and wildcards, are skipped
+with a warning since they have no CIDR notation.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// load pattern files
+			for _, file := range patternFiles {
+				ps, err := readPatternFile(file)
+				if err != nil {
+					return err
+				}
+				patterns = append(patterns, ps...)
+			}
+			if len(patterns) == 0 {
+				return fmt.Errorf("no patterns specified")
+			}
+			if format != "iptables" && format != "nftables" {
+				return fmt.Errorf("invalid format: %s", format)
+			}
+			if action != "drop" && action != "accept" {
+				return fmt.Errorf("invalid action: %s", action)
+			}
+
+			// check all the patterns before printing anything
+			var networks []Network
+			for i, s := range patterns {
+				pattern, err := ParsePattern(s)
+				if err != nil {
+					return &PatternError{Index: i, Pattern: s, Err: err}
+				}
+				ns, ok := patternNetworks(pattern)
+				if !ok {
+					fmt.Fprintf(cmd.ErrOrStderr(), "skipped: not in CIDR notation: %s\n", s)
+					continue
+				}
+				networks = append(networks, ns...)
+			}
+
+			w := bufio.NewWriter(cmd.OutOrStdout())
+			defer w.Flush()
+			if format == "iptables" {
+				writeIptablesRules(w, networks, action)
+			} else {
+				writeNftablesRules(w, networks, action)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&patterns, "pattern", "e", []string{}, "pattern")
+	cmd.Flags().StringSliceVarP(&patternFiles, "file", "f", []string{}, "read patterns from the file, one per line")
+	cmd.Flags().StringVar(&format, "format", "nftables", "format of the rules; nftables or iptables")
+	cmd.Flags().StringVar(&action, "action", "drop", "action on the matching packets; drop or accept")
+
+	cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"nftables", "iptables"}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.RegisterFlagCompletionFunc("action", cobra.FixedCompletions([]string{"drop", "accept"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+// patternNetworks returns the networks matching the same addresses as the
+// pattern: a prefix, a range or a composite of them. The second result is
+// false for the other patterns.
+func patternNetworks(p Pattern) ([]Network, bool) {
+	switch p := p.(type) {
+	case MaskPattern:
+		n, ok := prefixNetwork(p)
+		if !ok {
+			return nil, false
+		}
+		// the host bits are rejected by nft
+		return []Network{{IP: n.First(), Bits: n.Bits}}, true
+	case RangePattern:
+		return appendRangePrefixes(nil, p.Start, p.End), true
+	case CompositePattern:
+		var networks []Network
+		for _, inner := range p.Patterns {
+			ns, ok := patternNetworks(inner)
+			if !ok {
+				return nil, false
+			}
+			networks = append(networks, ns...)
+		}
+		return networks, true
+	}
+	return nil, false
+}
+
+// writeIptablesRules writes the commands appending the rules to the INPUT
+// chain, with ip6tables for IPv6.
+func writeIptablesRules(w io.Writer, networks []Network, action string) {
+	for _, n := range networks {
+		command := "iptables"
+		if n.Version() == 6 {
+			command = "ip6tables"
+		}
+		fmt.Fprintf(w, "%s -A INPUT -s %s -j %s\n", command, n, strings.ToUpper(action))
+	}
+}
+
+// writeNftablesRules writes a table of the inet family filtering the input
+// of both versions.
+func writeNftablesRules(w io.Writer, networks []Network, action string) {
+	fmt.Fprintln(w, "table inet gipp {")
+	fmt.Fprintln(w, "\tchain input {")
+	fmt.Fprintln(w, "\t\ttype filter hook input priority 0; policy accept;")
+	for _, n := range networks {
+		family := "ip"
+		if n.Version() == 6 {
+			family = "ip6"
+		}
+		fmt.Fprintf(w, "\t\t%s saddr %s %s\n", family, n, action)
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "}")
+}
@@ -0,0 +1,87 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRulesCmd(t *testing.T) {
+	patternFile := filepath.Join("testdata", "rules", "patterns.txt")
+
+	testCases := []struct {
+		description string
+		args        []string
+		golden      string
+	}{
+		{
+			description: "nftables",
+			args:        []string{"--format", "nftables"},
+			golden:      "nftables-drop.golden",
+		},
+		{
+			description: "iptables",
+			args:        []string{"--format", "iptables"},
+			golden:      "iptables-drop.golden",
+		},
+		{
+			description: "Accept",
+			args:        []string{"--format", "iptables", "--action", "accept"},
+			golden:      "iptables-accept.golden",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		errbuf := &bytes.Buffer{}
+		root := cmd.NewRootCmd()
+		root.SetArgs(append([]string{"rules", "-f", patternFile}, tc.args...))
+		root.SetOut(outbuf)
+		root.SetErr(errbuf)
+		if err := root.Execute(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		expected, err := os.ReadFile(filepath.Join("testdata", "rules", tc.golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outbuf.String() != string(expected) {
+			t.Errorf("expected: %v, got: %v", string(expected), outbuf.String())
+		}
+		// the suffix and the wildcard are reported
+		expectedErr := "skipped: not in CIDR notation: 0.0.0.1/-8\nskipped: not in CIDR notation: 192.168.*.5\n"
+		if errbuf.String() != expectedErr {
+			t.Errorf("expected: %v, got: %v", expectedErr, errbuf.String())
+		}
+	}
+}
+
+func TestRulesCmdInvalid(t *testing.T) {
+	testCases := []struct {
+		description string
+		args        []string
+	}{
+		{description: "No Patterns", args: []string{"rules"}},
+		{description: "Invalid Pattern", args: []string{"rules", "-e", "10.0.0.0/8,invalid"}},
+		{description: "Invalid Format", args: []string{"rules", "-e", "10.0.0.0/8", "--format", "pf"}},
+		{description: "Invalid Action", args: []string{"rules", "-e", "10.0.0.0/8", "--action", "reject"}},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, tc.args...)
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+		if strings.TrimSpace(out) != "" && !strings.HasPrefix(out, "Usage") {
+			t.Errorf("expected no rules, got: %v", out)
+		}
+	}
+}
@@ -0,0 +1,896 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"text/template"
+)
+
+// searcher selects the lines of one or more inputs with the same patterns
+// and options, keeping the state shared across the inputs.
+type searcher struct {
+	m    *Matcher
+	opts Options
+	// out is flushed per line with Options.LineBuffered, and must be flushed
+	// with flushWriters after use otherwise.
+	out  *bufio.Writer
+	eout io.Writer
+	// matchedOut, unmatchedOut, invalidOut and invertOut are the buffered
+	// writers of Options.MatchedOut, UnmatchedOut, InvalidOut and InvertOut,
+	// or nil.
+	matchedOut   *bufio.Writer
+	unmatchedOut *bufio.Writer
+	invalidOut   *bufio.Writer
+	invertOut    *bufio.Writer
+	// tmpl is the parsed Options.Template, or nil.
+	tmpl *template.Template
+	// ptr filters the matched IP addresses with Options.PTRMatches, or nil.
+	ptr *ptrFilter
+	// country filters the matched IP addresses with Options.Countries, or nil.
+	country *countryFilter
+	// asn filters the matched IP addresses with Options.ASNs and looks up
+	// them for Options.ShowASN, or nil.
+	asn *asnFilter
+
+	// seen holds the canonical forms of the selected IP addresses for Unique.
+	seen map[string]bool
+	// bloom holds them for UniqueApprox instead, or is nil.
+	bloom *bloomFilter
+	// sorted holds the selected lines to be sorted for Sort.
+	sorted []sortedLine
+	// headerWritten reports whether the header row of the csv output is printed.
+	headerWritten bool
+	// setsCreated holds the IP versions whose sets of the ipset output are
+	// created.
+	setsCreated map[int]bool
+	// stats holds the number of the selected lines matching each pattern for
+	// Stats.
+	stats map[string]int
+	// attributed holds the number of the selected lines attributed to each
+	// pattern for PerPatternLimit.
+	attributed map[string]int
+	// summary holds the numbers of the lines of all the inputs.
+	summary RunSummary
+}
+
+// sortedLine is a selected line waiting to be sorted by its IP address.
+type sortedLine struct {
+	ip   IPAddress
+	text string
+}
+
+// newSearcher returns a searcher, or an error if the template of opts is
+// invalid so that it is reported before reading any input. m is created by
+// newMatcher with the same opts.
+func newSearcher(m *Matcher, out, eout io.Writer, opts Options) (*searcher, error) {
+	s := &searcher{
+		m:    m,
+		opts: opts,
+		out:  bufio.NewWriter(out),
+		eout: eout,
+		seen: map[string]bool{},
+
+		setsCreated: map[int]bool{},
+		stats:       map[string]int{},
+		attributed:  map[string]int{},
+	}
+	if opts.MatchedOut != nil {
+		s.matchedOut = bufio.NewWriter(opts.MatchedOut)
+	}
+	if opts.UnmatchedOut != nil {
+		s.unmatchedOut = bufio.NewWriter(opts.UnmatchedOut)
+	}
+	// the invalid lines go to the unmatched ones by default
+	s.invalidOut = s.unmatchedOut
+	if opts.InvalidOut != nil {
+		s.invalidOut = bufio.NewWriter(opts.InvalidOut)
+	}
+	if opts.InvertOut != nil {
+		s.invertOut = bufio.NewWriter(opts.InvertOut)
+	}
+	if opts.Field < 0 {
+		return nil, fmt.Errorf("invalid field: %d", opts.Field)
+	}
+	// the lines passed through would break the records
+	if opts.Passthrough && (opts.Output == "json" || opts.Output == "csv" || opts.Output == "ipset") {
+		return nil, fmt.Errorf("passthrough with the %s output", opts.Output)
+	}
+	if opts.PerPatternLimit < 0 {
+		return nil, fmt.Errorf("invalid per-pattern limit: %d", opts.PerPatternLimit)
+	}
+	if opts.UniqueApprox {
+		fp, size := opts.UniqueFP, opts.UniqueSize
+		if fp == 0 {
+			fp = DefaultUniqueFP
+		}
+		if size == 0 {
+			size = DefaultUniqueSize
+		}
+		bloom, err := newBloomFilter(size, fp)
+		if err != nil {
+			return nil, err
+		}
+		s.bloom = bloom
+	}
+	if opts.Template != "" {
+		t, err := parseTemplate(opts.Template)
+		if err != nil {
+			return nil, err
+		}
+		s.tmpl = t
+		// find the pattern of each address only if the template prints it
+		if usesField(t, "Pattern") {
+			s.opts.ShowPattern = true
+		}
+	}
+	if len(opts.Countries) > 0 {
+		if opts.GeoIP == nil {
+			return nil, errors.New("no GeoIP database for the countries")
+		}
+		s.country = newCountryFilter(opts.GeoIP, opts.Countries)
+	}
+	if len(opts.ASNs) > 0 || opts.ShowASN {
+		if opts.ASNLookup == nil {
+			return nil, errors.New("no ASN database for the autonomous systems")
+		}
+		s.asn = &asnFilter{lookup: opts.ASNLookup, asns: opts.ASNs}
+	}
+	// the lines of a followed file are awaited without the buffer filled
+	if opts.Follow {
+		s.opts.LineBuffered = true
+	}
+	if opts.PTRMatches != "" {
+		f, err := newPTRFilter(opts.PTRMatches, opts.Resolver, opts.PTRTimeout)
+		if err != nil {
+			return nil, err
+		}
+		s.ptr = f
+	}
+	return s, nil
+}
+
+// displayName returns the name of the input to be printed.
+func displayName(filename string) string {
+	if filename == "" {
+		return "(standard input)"
+	}
+	return filename
+}
+
+// searchFile opens the file and selects its lines.
+// With Options.Follow, it keeps reading the appended lines until ctx is done.
+func (s *searcher) searchFile(ctx context.Context, filename string, withFilename bool) (int, error) {
+	if s.opts.Follow {
+		r, err := openFollow(ctx, filename)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		return s.search(ctx, r, filename, withFilename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return s.search(ctx, f, filename, withFilename)
+}
+
+// scannedLine is an input line with the result of matching it.
+type scannedLine struct {
+	lineno int
+	// text is the line without the newline. It may be overwritten by the next
+	// line in scan, so it must be copied to be kept.
+	text []byte
+	// ip is nil if the line is not an IP address.
+	ip IPAddress
+	// selected reports whether the line is selected by the patterns and Invert.
+	selected bool
+	// pattern is the first pattern the line matches with ShowPattern.
+	pattern string
+	// spans are the IP addresses embedded in the line with Extract.
+	spans []ipSpan
+	// sources are all the patterns the line matches with Stats.
+	sources []string
+	// ignored reports whether the line is a blank or comment line with Hosts
+	// or SkipComments, which is not an invalid line.
+	ignored bool
+}
+
+// evaluate parses the line and matches it against the patterns.
+// It is safe to call concurrently.
+func (s *searcher) evaluate(l *scannedLine) {
+	if s.opts.SkipComments && s.isComment(l.text) {
+		l.ignored = true
+		return
+	}
+	if s.opts.Extract {
+		s.evaluateSpans(l)
+		return
+	}
+	if s.opts.Hosts {
+		s.evaluateHost(l)
+		return
+	}
+	if s.opts.Field > 0 {
+		s.evaluateField(l)
+		return
+	}
+	text := l.text
+	// the surrounding spaces are not printed
+	if s.opts.OnlyMatching {
+		text = bytes.TrimSpace(text)
+	}
+	ip, err := parseIPBytes(text)
+	if err != nil && s.opts.LooseIPv4 {
+		ip, err = parseLooseIPv4Bytes(text)
+	}
+	if err != nil {
+		// a network in CIDR notation
+		if bytes.IndexByte(text, '/') < 0 {
+			return
+		}
+		n, err := ParseNetwork(string(text))
+		if err != nil {
+			return
+		}
+		ip = n
+	}
+	l.ip = ip
+	// drop the IP addresses of the other version before matching
+	if s.opts.Version != 0 && ip.Version() != s.opts.Version {
+		return
+	}
+	// select the line once even if it matches several patterns
+	var matched bool
+	n, isNetwork := ip.(Network)
+	switch {
+	case isNetwork:
+		l.pattern, matched = s.m.matchNetwork(n, s.opts.Overlaps)
+	case s.opts.ShowPattern:
+		l.pattern, matched = s.matchSource(ip)
+	default:
+		matched = s.m.Match(ip)
+	}
+	if matched {
+		matched = s.filter(ip)
+	}
+	if matched && (s.opts.Stats || s.opts.PerPatternLimit > 0) {
+		l.sources = s.m.matchAll(ip, s.opts.Overlaps)
+	}
+	l.selected = matched != s.opts.Invert
+}
+
+// matchSource matches ip against the patterns and returns the pattern to be
+// shown, which is the longest prefix with Options.LPM.
+func (s *searcher) matchSource(ip IPAddress) (string, bool) {
+	if s.opts.LPM {
+		return s.m.MatchLongest(ip)
+	}
+	return s.m.MatchSource(ip)
+}
+
+// subnet returns the subnet of ip in the longest prefix pattern it matches
+// to be appended for Options.ShowNetwork, or an empty string if none.
+func (s *searcher) subnet(ip IPAddress) string {
+	bits, ok := s.m.matchPrefix(ip)
+	if !ok {
+		return ""
+	}
+	return subnetText(ip, bits)
+}
+
+// filter reports whether the matched IP address is kept by the filters
+// other than the patterns. The cheaper filters come first.
+func (s *searcher) filter(ip IPAddress) bool {
+	if s.country != nil && !s.country.match(ip) {
+		return false
+	}
+	if len(s.opts.ASNs) > 0 && !s.asn.match(ip) {
+		return false
+	}
+	if s.ptr != nil && !s.ptr.match(ip) {
+		return false
+	}
+	return true
+}
+
+// isComment reports whether the line is blank or starts with the comment
+// prefix after spaces.
+func (s *searcher) isComment(text []byte) bool {
+	prefix := s.opts.CommentChar
+	if prefix == "" {
+		prefix = DefaultCommentChar
+	}
+	text = bytes.TrimLeft(text, " \t\r\v\f")
+	return len(text) == 0 || bytes.HasPrefix(text, []byte(prefix))
+}
+
+// evaluateSpans matches the IP addresses embedded in the line.
+func (s *searcher) evaluateSpans(l *scannedLine) {
+	s.matchSpans(l, extractIPs(l.text))
+}
+
+// evaluateHost matches the address of the line of a hosts file, keeping the
+// host names as they are.
+func (s *searcher) evaluateHost(l *scannedLine) {
+	start, end, ok := hostsAddress(l.text)
+	if !ok {
+		l.ignored = true
+		return
+	}
+	s.evaluateSpan(l, start, end)
+}
+
+// evaluateField matches the field of the line selected by Options.Field,
+// keeping the other fields as they are. A line with fewer fields is handled
+// like the ones that are not IP addresses.
+func (s *searcher) evaluateField(l *scannedLine) {
+	start, end, ok := fieldSpan(l.text, s.opts.Field, s.opts.Delimiter)
+	if !ok {
+		return
+	}
+	s.evaluateSpan(l, start, end)
+}
+
+// evaluateSpan matches the address in l.text[start:end].
+func (s *searcher) evaluateSpan(l *scannedLine, start, end int) {
+	ip, err := parseIPBytes(l.text[start:end])
+	if err != nil && s.opts.LooseIPv4 {
+		ip, err = parseLooseIPv4Bytes(l.text[start:end])
+	}
+	if err != nil {
+		return
+	}
+	s.matchSpans(l, []ipSpan{{start: start, end: end, ip: ip}})
+}
+
+// matchSpans matches the IP addresses in the line. The line is selected if
+// any of them matches, and its IP address is the first one matching or the
+// first one if none matches.
+func (s *searcher) matchSpans(l *scannedLine, spans []ipSpan) {
+	if len(spans) == 0 {
+		return
+	}
+	l.ip = spans[0].ip
+	// drop the IP addresses of the other version before matching
+	if s.opts.Version != 0 {
+		spans = slices.DeleteFunc(spans, func(span ipSpan) bool {
+			return span.ip.Version() != s.opts.Version
+		})
+		if len(spans) == 0 {
+			return
+		}
+		l.ip = spans[0].ip
+	}
+	l.spans = spans
+	matched := false
+	for i := range l.spans {
+		span := &l.spans[i]
+		if s.opts.ShowPattern {
+			span.pattern, span.matched = s.matchSource(span.ip)
+		} else {
+			span.matched = s.m.Match(span.ip)
+		}
+		if span.matched {
+			span.matched = s.filter(span.ip)
+		}
+		if span.matched && !matched {
+			l.pattern = span.pattern
+			l.ip = span.ip
+			matched = true
+		}
+		// count the line once for a pattern matching several IP addresses
+		if span.matched && (s.opts.Stats || s.opts.PerPatternLimit > 0) {
+			for _, source := range s.m.MatchAll(span.ip) {
+				if !slices.Contains(l.sources, source) {
+					l.sources = append(l.sources, source)
+				}
+			}
+		}
+	}
+	l.selected = matched != s.opts.Invert
+}
+
+// search prints the lines of in selected by the patterns and returns the
+// number of selected lines. An empty filename stands for the standard input.
+// It stops reading with the error of ctx when ctx is done.
+func (s *searcher) search(ctx context.Context, in io.Reader, filename string, withFilename bool) (int, error) {
+	opts := s.opts
+	name := displayName(filename)
+
+	// handle the evaluated lines in order and report whether to stop reading
+	count := 0
+	skipped := 0
+	var invalid error
+	var tmplErr error
+	// the addresses are found in the lines instead of the whole lines
+	spanned := opts.Extract || opts.Hosts || opts.Field > 0
+	// the addresses are rewritten in the format
+	normalize := opts.Normalize || opts.Format == "int" || opts.Format == "hex"
+	printsLines := !opts.Quiet && !opts.FilesWithMatches && !opts.FilesWithoutMatches && !opts.Count
+	// prefix adds the line number and the file name to the line
+	prefix := func(l scannedLine, line string) string {
+		if opts.LineNumber {
+			line = fmt.Sprintf("%d:%s", l.lineno, line)
+		}
+		if withFilename {
+			line = name + ":" + line
+		}
+		return line
+	}
+	// output prints the output line of the IP address
+	output := func(ip IPAddress, line string) {
+		// the header row precedes the first record of all the inputs
+		if opts.Output == "csv" && !opts.NoHeader && !s.headerWritten {
+			s.println(encodeCSV(csvHeader))
+			s.headerWritten = true
+		}
+		// print the lines after all the inputs are read
+		if opts.Sort {
+			s.sorted = append(s.sorted, sortedLine{ip: ip, text: line})
+			return
+		}
+		s.println(line)
+	}
+	emit := func(l scannedLine) bool {
+		s.summary.Lines++
+		if l.ip == nil {
+			s.summary.Skipped++
+		} else {
+			s.summary.Parsed++
+		}
+
+		// split every line into the files before it is dropped by the options
+		switch {
+		case l.ip == nil:
+			s.writeLine(s.invalidOut, l.text)
+		case l.selected:
+			s.writeLine(s.matchedOut, l.text)
+		default:
+			s.writeLine(s.unmatchedOut, l.text)
+			s.writeLine(s.invertOut, l.text)
+		}
+
+		// report the lines that are not IP addresses
+		if l.ip == nil && l.ignored {
+			if opts.Passthrough && printsLines {
+				s.println(prefix(l, string(l.text)))
+			}
+			return false
+		}
+		if l.ip == nil {
+			if opts.AbortOnInvalid {
+				invalid = fmt.Errorf("%s:%d: %w: %s", name, l.lineno, ErrInvalidIP, l.text)
+				return true
+			}
+			if opts.Strict {
+				fmt.Fprintf(s.eout, "%s:%d: %s: %s\n", name, l.lineno, ErrInvalidIP, l.text)
+			}
+			// print the line as it is whether inverted or not
+			if opts.Passthrough {
+				if printsLines {
+					s.println(prefix(l, string(l.text)))
+				}
+				return false
+			}
+			skipped++
+			return false
+		}
+		if !l.selected {
+			return false
+		}
+		ip := l.ip
+
+		// count the duplicated lines too, which match the patterns as well
+		for _, source := range l.sources {
+			s.stats[source]++
+		}
+
+		// skip the IP addresses already selected
+		if s.bloom != nil {
+			if s.bloom.add(ip.String()) {
+				return false
+			}
+		} else if opts.Unique {
+			key := ip.String()
+			if s.seen[key] {
+				return false
+			}
+			s.seen[key] = true
+		}
+		// drop the line once all the patterns it matches have enough lines
+		if opts.PerPatternLimit > 0 && !s.attribute(l.sources) {
+			return false
+		}
+		count++
+		s.summary.Matched++
+
+		// one line is enough to decide
+		if opts.Quiet || opts.FilesWithMatches || opts.FilesWithoutMatches {
+			return true
+		}
+		if opts.Count {
+			return false
+		}
+
+		// the IP addresses that selected the line
+		spans := []ipSpan{{ip: ip, matched: !opts.Invert, pattern: l.pattern}}
+		structured := opts.Output == "json" || opts.Output == "csv" || opts.Output == "ipset"
+		if spanned && (opts.OnlyMatching || opts.Template != "" || structured) {
+			spans = l.spans
+		}
+
+		// print the IP addresses with the template
+		if s.tmpl != nil {
+			for _, span := range spans {
+				if span.matched == opts.Invert {
+					continue
+				}
+				data := templateData{
+					IP:      string(bytes.TrimSpace(l.text)),
+					Version: span.ip.Version(),
+					Line:    l.lineno,
+					File:    name,
+					Pattern: span.pattern,
+					Text:    string(l.text),
+				}
+				if spanned {
+					data.IP = string(l.text[span.start:span.end])
+				}
+				text, err := executeTemplate(s.tmpl, data)
+				if err != nil {
+					tmplErr = fmt.Errorf("%s:%d: %w", name, l.lineno, err)
+					return true
+				}
+				output(span.ip, text)
+			}
+			return false
+		}
+
+		// print the IP addresses as records
+		if structured {
+			for _, span := range spans {
+				if span.matched == opts.Invert {
+					continue
+				}
+				if opts.Output == "ipset" {
+					version := span.ip.Version()
+					name := ipsetName(opts.IPSetName, version)
+					// the set of each version precedes its first entry
+					if !s.setsCreated[version] {
+						s.println(ipsetCreate(name, version))
+						s.setsCreated[version] = true
+					}
+					output(span.ip, ipsetAdd(name, span.ip))
+					continue
+				}
+				r := record{IP: formatIP(span.ip, opts.Format), Version: span.ip.Version()}
+				if opts.LineNumber {
+					r.Line = l.lineno
+				}
+				if withFilename {
+					r.File = name
+				}
+				if opts.ShowPattern {
+					r.Pattern = span.pattern
+				}
+				if opts.Classify {
+					r.Class = Classify(span.ip)
+				}
+				if opts.ShowASN {
+					r.ASN = s.asn.asn(span.ip)
+				}
+				if opts.Output == "csv" {
+					output(span.ip, encodeCSV(r.csvFields()))
+					continue
+				}
+				output(span.ip, encodeJSON(r))
+			}
+			return false
+		}
+
+		// print only the IP addresses that selected the line
+		if opts.OnlyMatching {
+			for _, span := range spans {
+				if span.matched == opts.Invert {
+					continue
+				}
+				text := formatIP(span.ip, opts.Format)
+				if opts.Color && span.matched {
+					text = colorize(text)
+				}
+				if opts.ShowPattern && span.pattern != "" {
+					text += " [" + span.pattern + "]"
+				}
+				if opts.Classify {
+					text += " (" + Classify(span.ip) + ")"
+				}
+				if opts.ShowASN {
+					text += " AS" + strconv.FormatUint(uint64(s.asn.asn(span.ip)), 10)
+				}
+				if opts.ShowNetwork {
+					text += s.subnet(span.ip)
+				}
+				if opts.ShowMAC {
+					text += macText(span.ip)
+				}
+				output(span.ip, prefix(l, text))
+			}
+			return false
+		}
+
+		var line string
+		switch {
+		case spanned:
+			line = rewriteSpans(l.text, l.spans, opts.Format, normalize, opts.Color)
+		case normalize:
+			line = formatIP(ip, opts.Format)
+		default:
+			line = string(l.text)
+		}
+		if opts.Color && !opts.Invert && !spanned {
+			line = colorize(line)
+		}
+		if opts.ShowPattern && l.pattern != "" {
+			line += " [" + l.pattern + "]"
+		}
+		if opts.Classify {
+			line += " (" + Classify(ip) + ")"
+		}
+		if opts.ShowASN {
+			line += " AS" + strconv.FormatUint(uint64(s.asn.asn(ip)), 10)
+		}
+		if opts.ShowNetwork {
+			line += s.subnet(ip)
+		}
+		if opts.ShowMAC {
+			line += macText(ip)
+		}
+		output(ip, prefix(l, line))
+		return false
+	}
+
+	// read compressed input transparently; a followed file is read as it is
+	// since peeking its magic bytes would wait for more lines
+	_, follow := in.(*followReader)
+	if !follow {
+		var err error
+		in, err = decompress(in, filename)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	// read input stream line by line; bufio.ScanLines drops the CR of CRLF,
+	// so the lines of Windows files parse and are printed without it
+	maxLineLength := opts.MaxLineLength
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, min(maxLineLength, bufio.MaxScanTokenSize)), maxLineLength)
+	if opts.NullData {
+		sc.Split(scanNulls)
+	}
+	// stopped reports whether emit stopped reading once the result is decided
+	stopped := false
+	stop := func(l scannedLine) bool {
+		stopped = emit(l)
+		return stopped
+	}
+	var lineno int
+	var err error
+	// the lines are evaluated in batches by the workers, which would hold
+	// the lines of a followed file; the lines of a batch are looked up
+	// concurrently with PTRMatches
+	if (opts.Jobs > 1 || s.ptr != nil) && !follow {
+		lineno, err = s.scanParallel(ctx, sc, stop)
+	} else {
+		lineno, err = s.scan(ctx, sc, stop)
+	}
+	if err != nil {
+		return count, err
+	}
+	if invalid != nil {
+		return count, invalid
+	}
+	if tmplErr != nil {
+		return count, tmplErr
+	}
+
+	// report the line that is too long instead of stopping silently, unless
+	// reading stopped at the decided result, when sc may still be in use by
+	// the reader of scanParallel
+	if !stopped && errors.Is(sc.Err(), bufio.ErrTooLong) {
+		return count, fmt.Errorf("%s:%d: line too long", name, lineno+1)
+	}
+	// report the read errors, e.g. of the broken compressed input and the
+	// end of following, instead of stopping silently
+	if !stopped && sc.Err() != nil {
+		return count, fmt.Errorf("%s: %w", name, sc.Err())
+	}
+
+	// tell that some lines are dropped unless they are already reported or
+	// no lines are printed, e.g. with Quiet and Count
+	if skipped > 0 && !opts.Strict && printsLines {
+		fmt.Fprintf(s.eout, "%s: %d lines skipped: not IP addresses\n", name, skipped)
+	}
+
+	switch {
+	case opts.Quiet:
+		// print nothing
+	case opts.FilesWithMatches:
+		// print the name of the input
+		if count > 0 {
+			s.println(name)
+		}
+	case opts.FilesWithoutMatches:
+		if count == 0 {
+			s.println(name)
+		}
+	case opts.Count:
+		// print the count
+		if withFilename {
+			s.println(fmt.Sprintf("%s:%d", name, count))
+		} else {
+			s.println(strconv.Itoa(count))
+		}
+	}
+
+	return count, nil
+}
+
+// scan evaluates the lines of sc one by one until emit reports to stop, and
+// returns the number of the lines read.
+func (s *searcher) scan(ctx context.Context, sc *bufio.Scanner, emit func(scannedLine) bool) (int, error) {
+	lineno := 0
+	for sc.Scan() {
+		select {
+		case <-ctx.Done():
+			return lineno, ctx.Err()
+		default:
+		}
+
+		lineno++
+		l := scannedLine{lineno: lineno, text: sc.Bytes()}
+		s.evaluate(&l)
+		if emit(l) {
+			break
+		}
+	}
+	return lineno, nil
+}
+
+// allAddressesSource is the pattern the lines selected without patterns
+// other than negated ones are attributed to for PerPatternLimit.
+const allAddressesSource = ""
+
+// attribute counts the selected line for each of the patterns it matches
+// that have fewer lines than Options.PerPatternLimit, and reports whether
+// there was any.
+func (s *searcher) attribute(sources []string) bool {
+	// without patterns other than negated ones, the lines select all the
+	// IP addresses and are limited together
+	if len(sources) == 0 {
+		sources = []string{allAddressesSource}
+	}
+	attributed := false
+	for i, source := range sources {
+		// a pattern given twice counts the line once
+		if slices.Index(sources, source) < i || s.attributed[source] >= s.opts.PerPatternLimit {
+			continue
+		}
+		s.attributed[source]++
+		attributed = true
+	}
+	return attributed
+}
+
+// println prints the output line terminated by a newline, or by NUL with
+// Options.NullOutput.
+func (s *searcher) println(line string) {
+	s.out.WriteString(line)
+	if s.opts.NullOutput {
+		s.out.WriteByte(0)
+	} else {
+		s.out.WriteByte('\n')
+	}
+	if s.opts.LineBuffered {
+		s.out.Flush()
+	}
+}
+
+// writeLine writes the input line to w like println, or nothing if w is nil.
+func (s *searcher) writeLine(w *bufio.Writer, text []byte) {
+	if w == nil {
+		return
+	}
+	w.Write(text)
+	if s.opts.NullOutput {
+		w.WriteByte(0)
+	} else {
+		w.WriteByte('\n')
+	}
+	if s.opts.LineBuffered {
+		w.Flush()
+	}
+}
+
+// flushWriters flushes the output and the files the lines are split into.
+// It must be called after use.
+func (s *searcher) flushWriters() {
+	for _, w := range []*bufio.Writer{s.out, s.matchedOut, s.unmatchedOut, s.invalidOut, s.invertOut} {
+		if w != nil {
+			w.Flush()
+		}
+	}
+}
+
+// scanNulls is a bufio.SplitFunc like bufio.ScanLines but splitting the
+// input on NUL. The records may contain newlines.
+func scanNulls(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	// the last record without NUL
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// flush prints the lines and the statistics held until all the inputs are
+// read.
+func (s *searcher) flush() {
+	s.flushSorted()
+	if s.opts.Stats {
+		// the statistics follow the output on a terminal
+		s.out.Flush()
+		s.printStats()
+	}
+}
+
+// flushSorted prints the selected lines sorted for Sort.
+func (s *searcher) flushSorted() {
+	if !s.opts.Sort {
+		return
+	}
+	// keep the input order of the same IP addresses
+	slices.SortStableFunc(s.sorted, func(a, b sortedLine) int {
+		return compareIP(a.ip, b.ip)
+	})
+	for _, line := range s.sorted {
+		s.println(line.text)
+	}
+	s.sorted = nil
+}
+
+// printStats prints the number of the selected lines matching each pattern,
+// the largest first, as "pattern: count". The patterns matching nothing are
+// printed as well to tell the unused ones.
+func (s *searcher) printStats() {
+	var sources []string
+	for _, pattern := range s.m.patterns {
+		if !slices.Contains(sources, pattern.Source) {
+			sources = append(sources, pattern.Source)
+		}
+	}
+	// keep the order of the patterns of the same count
+	slices.SortStableFunc(sources, func(a, b string) int {
+		return s.stats[b] - s.stats[a]
+	})
+	for _, source := range sources {
+		fmt.Fprintf(s.eout, "%s: %d\n", source, s.stats[source])
+	}
+}
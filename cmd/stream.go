@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Match is a line of the input selected by Stream.
+type Match struct {
+	// Line is the line without the newline.
+	Line string
+	// IP is the IP address or the network of the line.
+	IP IPAddress
+	// LineNumber is the line number counted from 1.
+	LineNumber int
+	// Pattern is the first of the patterns the line matches, or empty
+	// without patterns other than negated ones.
+	Pattern string
+	// Err is the error stopping the scan, which is set only on the last
+	// Match sent before the channel is closed. The other fields are empty.
+	Err error
+}
+
+// Stream sends the lines of in matching m to the returned channel in order,
+// like RunWithOptions without options but without printing them. The lines
+// that are not IP addresses are skipped. The channel is closed at the end of
+// in, after a Match with the error if reading in fails, or when ctx is done.
+// The receiver must drain the channel or cancel ctx.
+func Stream(ctx context.Context, in io.Reader, m *Matcher) <-chan Match {
+	ch := make(chan Match)
+	s := &searcher{m: m, opts: Options{ShowPattern: true}}
+	go func() {
+		defer close(ch)
+		send := func(match Match) bool {
+			select {
+			case ch <- match:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		sc := bufio.NewScanner(in)
+		sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), DefaultMaxLineLength)
+		lineno := 0
+		for sc.Scan() {
+			if ctx.Err() != nil {
+				return
+			}
+			lineno++
+			l := scannedLine{lineno: lineno, text: sc.Bytes()}
+			s.evaluate(&l)
+			if !l.selected {
+				continue
+			}
+			match := Match{Line: string(l.text), IP: l.ip, LineNumber: lineno, Pattern: l.pattern}
+			if !send(match) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			send(Match{Err: fmt.Errorf("line %d: %w", lineno+1, err)})
+		}
+	}()
+	return ch
+}
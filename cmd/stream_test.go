@@ -0,0 +1,113 @@
+package cmd_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestStream(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		input       string
+		expected    []cmd.Match
+	}{
+		{
+			description: "Sample",
+			patterns:    samplePatterns,
+			input:       sampleInput,
+			expected: []cmd.Match{
+				{Line: "10.222.200.200", IP: mustParseIp(t, "10.222.200.200"), LineNumber: 5, Pattern: "10.222.0.0/16"},
+				{Line: "192.168.57.163", IP: mustParseIp(t, "192.168.57.163"), LineNumber: 10, Pattern: "192.168.57.0/24"},
+				{Line: "192.168.57.4", IP: mustParseIp(t, "192.168.57.4"), LineNumber: 14, Pattern: "192.168.57.0/24"},
+				{Line: "fe80::5474:3fa5:9fca:99f3", IP: mustParseIp(t, "fe80::5474:3fa5:9fca:99f3"), LineNumber: 26, Pattern: "fe80::5400:0:0:0/72"},
+			},
+		},
+		{
+			description: "Invalid Lines and Negation",
+			patterns:    []string{"!10.0.0.1"},
+			input:       "hello\n10.0.0.1\n\n10.0.0.2\n",
+			expected: []cmd.Match{
+				{Line: "10.0.0.2", IP: mustParseIp(t, "10.0.0.2"), LineNumber: 4},
+			},
+		},
+		{
+			description: "No Match",
+			patterns:    []string{"172.16.0.0/12"},
+			input:       "10.0.0.1\n",
+			expected:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		m, err := cmd.NewMatcher(tc.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var matches []cmd.Match
+		for match := range cmd.Stream(context.Background(), strings.NewReader(tc.input), m) {
+			matches = append(matches, match)
+		}
+		if len(matches) != len(tc.expected) {
+			t.Errorf("expected: %v, got: %v", tc.expected, matches)
+			continue
+		}
+		for i := range matches {
+			e, g := tc.expected[i], matches[i]
+			if g.Line != e.Line || !g.IP.Equal(e.IP) || g.LineNumber != e.LineNumber || g.Pattern != e.Pattern || g.Err != nil {
+				t.Errorf("expected: %v, got: %v", e, g)
+			}
+		}
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	m, err := cmd.NewMatcher([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "10.0.0.1\n" + strings.Repeat("a", cmd.DefaultMaxLineLength+1) + "\n10.0.0.2\n"
+
+	var matches []cmd.Match
+	for match := range cmd.Stream(context.Background(), strings.NewReader(input), m) {
+		matches = append(matches, match)
+	}
+	if len(matches) != 2 || matches[0].Line != "10.0.0.1" {
+		t.Fatalf("expected a match and an error, got: %v", matches)
+	}
+	if !errors.Is(matches[1].Err, bufio.ErrTooLong) || !strings.Contains(matches[1].Err.Error(), "line 2") {
+		t.Errorf("expected: %v at line 2, got: %v", bufio.ErrTooLong, matches[1].Err)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	m, err := cmd.NewMatcher([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := cmd.Stream(ctx, strings.NewReader(strings.Repeat("10.0.0.1\n", 1000)), m)
+
+	// stop receiving after the first match
+	<-ch
+	cancel()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected the channel to be closed")
+		}
+	}
+}
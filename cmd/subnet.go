@@ -0,0 +1,38 @@
+package cmd
+
+// matchPrefix returns the prefix length of the prefix pattern with the
+// longest prefix length that ip matches in its own version, or false if
+// none.
+func (m *Matcher) matchPrefix(ip IPAddress) (int, bool) {
+	if _, ok := ip.(Network); ok {
+		return 0, false
+	}
+	index := m.longest(ip)
+	if index < 0 {
+		return 0, false
+	}
+	return m.patterns[index].Pattern.(MaskPattern).MaskEnd, true
+}
+
+// subnetText returns the network of ip with the prefix length bits, and the
+// broadcast address and the host portion for IPv4 or the interface ID for
+// IPv6, e.g. " network=10.0.0.0/8 broadcast=10.255.255.255 host=0.1.2.3".
+func subnetText(ip IPAddress, bits int) string {
+	b := ip.Bytes()
+	mask := prefixMask(len(b), 0, bits)
+	network := make([]byte, len(b))
+	broadcast := make([]byte, len(b))
+	host := make([]byte, len(b))
+	for i := range b {
+		network[i] = b[i] & mask[i]
+		broadcast[i] = b[i] | ^mask[i]
+		host[i] = b[i] &^ mask[i]
+	}
+
+	text := " network=" + Network{IP: ipFromBytes(network), Bits: bits}.String()
+	// IPv6 has no broadcast addresses
+	if len(b) == 16 {
+		return text + " interface-id=" + ipFromBytes(host).String()
+	}
+	return text + " broadcast=" + ipFromBytes(broadcast).String() + " host=" + ipFromBytes(host).String()
+}
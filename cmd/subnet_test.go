@@ -0,0 +1,123 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRunWithOptionsShowNetwork(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		input       string
+		expected    string
+	}{
+		{
+			description: "IPv4",
+			patterns:    []string{"192.168.1.0/24"},
+			input:       "192.168.1.130\n",
+			expected:    "192.168.1.130 network=192.168.1.0/24 broadcast=192.168.1.255 host=0.0.0.130\n",
+		},
+		{
+			description: "IPv4 Unaligned Prefix",
+			patterns:    []string{"172.16.0.0/12"},
+			input:       "172.20.10.5\n",
+			expected:    "172.20.10.5 network=172.16.0.0/12 broadcast=172.31.255.255 host=0.4.10.5\n",
+		},
+		{
+			description: "IPv4 Host Bits in Pattern",
+			patterns:    []string{"10.1.2.3/8"},
+			input:       "10.200.0.1\n",
+			expected:    "10.200.0.1 network=10.0.0.0/8 broadcast=10.255.255.255 host=0.200.0.1\n",
+		},
+		{
+			description: "IPv4 /32",
+			patterns:    []string{"10.0.0.1/32"},
+			input:       "10.0.0.1\n",
+			expected:    "10.0.0.1 network=10.0.0.1/32 broadcast=10.0.0.1 host=0.0.0.0\n",
+		},
+		{
+			description: "IPv4 /0",
+			patterns:    []string{"0.0.0.0/0"},
+			input:       "10.0.0.1\n",
+			expected:    "10.0.0.1 network=0.0.0.0/0 broadcast=255.255.255.255 host=10.0.0.1\n",
+		},
+		{
+			description: "IPv6",
+			patterns:    []string{"2001:db8::/64"},
+			input:       "2001:db8::abcd:1\n",
+			expected:    "2001:db8::abcd:1 network=2001:db8::/64 interface-id=::abcd:1\n",
+		},
+		{
+			description: "IPv6 Unaligned Prefix",
+			patterns:    []string{"2001:db8:1200::/40"},
+			input:       "2001:db8:12ff:1::1%eth0\n",
+			expected:    "2001:db8:12ff:1::1%eth0 network=2001:db8:1200::/40 interface-id=0:0:ff:1::1\n",
+		},
+		{
+			description: "Longest Prefix",
+			patterns:    []string{"10.0.0.0/8", "10.1.0.0/16"},
+			input:       "10.1.2.3\n10.2.0.1\n",
+			expected: `10.1.2.3 network=10.1.0.0/16 broadcast=10.1.255.255 host=0.0.2.3
+10.2.0.1 network=10.0.0.0/8 broadcast=10.255.255.255 host=0.2.0.1
+`,
+		},
+		{
+			description: "No Prefix",
+			patterns:    []string{"0.0.0.1/-8", "10.0.0.0-10.0.0.9"},
+			input:       "192.168.0.1\n10.0.0.5\n",
+			expected:    "192.168.0.1\n10.0.0.5\n",
+		},
+		{
+			description: "Network",
+			patterns:    []string{"10.0.0.0/8"},
+			input:       "10.1.0.0/16\n",
+			expected:    "10.1.0.0/16\n",
+		},
+		{
+			description: "Only Matching",
+			patterns:    []string{"10.0.0.0/24", "192.168.0.0/16"},
+			opts:        cmd.Options{Extract: true, OnlyMatching: true},
+			input:       "from 10.0.0.1 to 192.168.3.4\n",
+			expected: `10.0.0.1 network=10.0.0.0/24 broadcast=10.0.0.255 host=0.0.0.1
+192.168.3.4 network=192.168.0.0/16 broadcast=192.168.255.255 host=0.0.3.4
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		tc.opts.ShowNetwork = true
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdShowNetwork(t *testing.T) {
+	paths := writeFiles(t, "10.0.0.1\n")
+
+	out, err := execute(t, "--show-network", "-e", "10.0.0.0/30", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "10.0.0.1 network=10.0.0.0/30 broadcast=10.0.0.3 host=0.0.0.1\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+
+	_, err = execute(t, "--show-network", "--output", "json", "-e", "10.0.0.0/30", paths[0])
+	if err == nil {
+		t.Errorf("expected an error of --show-network with --output")
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateData is a selected IP address passed to the template of
+// Options.Template.
+type templateData struct {
+	// IP is the IP address as written in the input.
+	IP string
+	// Version is 4 or 6.
+	Version int
+	// Line is the 1-based line number of the IP address.
+	Line int
+	// File is the name of the input.
+	File string
+	// Pattern is the first pattern the IP address matches, or empty if
+	// selected by Invert.
+	Pattern string
+	// Text is the whole line without the newline.
+	Text string
+}
+
+// templateFuncs are the functions available in the template.
+var templateFuncs = template.FuncMap{
+	// canonical returns the IP address or network in canonical form.
+	"canonical": func(s string) (string, error) {
+		ip, err := parseTemplateIP(s)
+		if err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	},
+	// classify returns the special-purpose range of the IP address.
+	"classify": func(s string) (string, error) {
+		ip, err := parseTemplateIP(s)
+		if err != nil {
+			return "", err
+		}
+		return Classify(ip), nil
+	},
+}
+
+// parseTemplateIP parses s as an IP address, a network in CIDR notation or a
+// loose IPv4 address, which are the forms of templateData.IP.
+func parseTemplateIP(s string) (IPAddress, error) {
+	if ip, err := ParseIp(s); err == nil {
+		return ip, nil
+	}
+	if strings.Contains(s, "/") {
+		if n, err := ParseNetwork(s); err == nil {
+			return n, nil
+		}
+	}
+	if ip, err := ParseLooseIPv4(s); err == nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidIP, s)
+}
+
+// parseTemplate parses the text of Options.Template.
+func parseTemplate(text string) (*template.Template, error) {
+	t, err := template.New("template").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return t, nil
+}
+
+// executeTemplate returns the template applied to the data.
+func executeTemplate(t *template.Template, data templateData) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// usesField reports whether the template may read the field name of
+// templateData. A template passing the whole data, such as {{printf "%v" .}},
+// is taken to read every field.
+func usesField(t *template.Template, name string) bool {
+	for _, t := range t.Templates() {
+		if t.Tree != nil && nodeUsesField(t.Tree.Root, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeUsesField reports whether the node or its children may read the field
+// name.
+func nodeUsesField(node parse.Node, name string) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Nodes {
+			if nodeUsesField(c, name) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeUsesField(n.Pipe, name)
+	case *parse.IfNode:
+		return nodeUsesBranch(&n.BranchNode, name)
+	case *parse.RangeNode:
+		return nodeUsesBranch(&n.BranchNode, name)
+	case *parse.WithNode:
+		return nodeUsesBranch(&n.BranchNode, name)
+	case *parse.TemplateNode:
+		// the called template is checked by itself, but it gets the data of
+		// the pipeline
+		return n.Pipe == nil || nodeUsesField(n.Pipe, name)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Cmds {
+			if nodeUsesField(c, name) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			if nodeUsesField(a, name) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return nodeUsesField(n.Node, name)
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == name
+	case *parse.VariableNode:
+		// $ is the data, and a declared variable may hold it
+		return len(n.Ident) == 1 || n.Ident[1] == name
+	case *parse.DotNode:
+		return true
+	}
+	return false
+}
+
+// nodeUsesBranch reports whether the pipeline or the lists of an if, range or
+// with action may read the field name.
+func nodeUsesBranch(n *parse.BranchNode, name string) bool {
+	return nodeUsesField(n.Pipe, name) || nodeUsesField(n.List, name) || nodeUsesField(n.ElseList, name)
+}
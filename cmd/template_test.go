@@ -0,0 +1,116 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestRunWithOptionsTemplate(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       string
+		opts        cmd.Options
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "Fields",
+			input:       "10.222.0.1\n172.16.0.1\nFE80::5474:3fa5:9fca:99f3\n",
+			opts:        cmd.Options{Template: "{{.File}}:{{.Line}}: {{.IP}} v{{.Version}} [{{.Pattern}}]", Filename: "a.txt"},
+			expected: `a.txt:1: 10.222.0.1 v4 [10.222.0.0/16]
+a.txt:3: FE80::5474:3fa5:9fca:99f3 v6 [fe80::5400:0:0:0/72]
+`,
+		},
+		{
+			description: "Functions",
+			input:       "10.222.0.1\nFE80:0:0:0:5474:3fa5:9fca:99f3\n",
+			opts:        cmd.Options{Template: "{{canonical .IP}} {{classify .IP}}"},
+			expected: `10.222.0.1 private
+fe80::5474:3fa5:9fca:99f3 link-local
+`,
+		},
+		{
+			description: "Extract",
+			input:       "from 10.222.0.1 to 192.168.57.1\nfrom 172.16.0.1\n",
+			opts:        cmd.Options{Template: "{{.IP}} in {{printf \"%q\" .Text}}", Extract: true},
+			expected: `10.222.0.1 in "from 10.222.0.1 to 192.168.57.1"
+192.168.57.1 in "from 10.222.0.1 to 192.168.57.1"
+`,
+		},
+		{
+			description: "Inverted",
+			input:       "10.222.0.1\n172.16.0.1\n",
+			opts:        cmd.Options{Template: "{{.IP}} {{classify .IP}}", Invert: true},
+			expected: `172.16.0.1 private
+`,
+		},
+		{
+			description: "Pattern Through Variable",
+			input:       "10.222.0.1\n",
+			opts:        cmd.Options{Template: "{{$.IP}} {{$.Pattern}}"},
+			expected: `10.222.0.1 10.222.0.0/16
+`,
+		},
+		{
+			description: "Pattern In Defined Template",
+			input:       "10.222.0.1\n",
+			opts:        cmd.Options{Template: `{{define "p"}}[{{.Pattern}}]{{end}}{{.IP}} {{template "p" .}}`},
+			expected: `10.222.0.1 [10.222.0.0/16]
+`,
+		},
+		{
+			description: "Pattern In Whole Data",
+			input:       "10.222.0.1\n",
+			opts:        cmd.Options{Template: `{{printf "%v" .}}`},
+			expected: `{10.222.0.1 4 1 (standard input) 10.222.0.0/16 10.222.0.1}
+`,
+		},
+		{
+			description: "Pattern Under With",
+			input:       "10.222.0.1\n",
+			opts:        cmd.Options{Template: `{{with .IP}}{{.}} {{$.Pattern}}{{end}}`},
+			expected: `10.222.0.1 10.222.0.0/16
+`,
+		},
+		{
+			description: "Unknown Field",
+			input:       "10.222.0.1\n",
+			opts:        cmd.Options{Template: "{{.Address}}"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(tc.input), outbuf, &bytes.Buffer{}, samplePatterns, tc.opts)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("expected an error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRunWithOptionsInvalidTemplate(t *testing.T) {
+	in := &onceReader{data: "10.222.0.1\n"}
+	_, err := cmd.RunWithOptions(in, &bytes.Buffer{}, &bytes.Buffer{}, samplePatterns, cmd.Options{Template: "{{.IP"})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+	// the template is checked before reading the input
+	if in.read {
+		t.Errorf("expected the input not to be read")
+	}
+}
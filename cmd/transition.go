@@ -0,0 +1,25 @@
+package cmd
+
+// EmbeddedIPv4 returns the IPv4 address embedded in the IPv6 address of a
+// transition mechanism, or false if ip is of neither of them:
+//
+//   - 6to4 (RFC 3056) is 2002::/16 followed by the IPv4 address of the site
+//     in the bits from 16 up to 48, e.g. 192.0.2.4 of 2002:c000:204::1.
+//   - Teredo (RFC 4380) is 2001::/32 followed by the IPv4 address of the
+//     server in the bits from 32 up to 64, the flags and the port in the
+//     bits up to 96, and the IPv4 address of the client with all the bits
+//     inverted in the rest. The client is returned, e.g. 192.0.2.45 of
+//     2001:0:4136:e378:8000:63bf:3fff:fdd2.
+func EmbeddedIPv4(ip IPv6Address) (IPv4Address, bool) {
+	switch {
+	case ip.IP[0] == 0x20 && ip.IP[1] == 0x02:
+		return IPv4Address{IP: [4]byte(ip.IP[2:6])}, true
+	case ip.IP[0] == 0x20 && ip.IP[1] == 0x01 && ip.IP[2] == 0 && ip.IP[3] == 0:
+		v4 := IPv4Address{}
+		for i := range v4.IP {
+			v4.IP[i] = ^ip.IP[12+i]
+		}
+		return v4, true
+	}
+	return IPv4Address{}, false
+}
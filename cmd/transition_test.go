@@ -0,0 +1,123 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestEmbeddedIPv4(t *testing.T) {
+	testCases := []struct {
+		description string
+		ip          string
+		expectedIP  string
+		expectedOk  bool
+	}{
+		{description: "6to4", ip: "2002:c000:204::1", expectedIP: "192.0.2.4", expectedOk: true},
+		{description: "6to4 Subnet", ip: "2002:cb00:71fe:1:2::3", expectedIP: "203.0.113.254", expectedOk: true},
+		{description: "6to4 Relay", ip: "2002:c058:6301::", expectedIP: "192.88.99.1", expectedOk: true},
+		{description: "Teredo", ip: "2001:0:4136:e378:8000:63bf:3fff:fdd2", expectedIP: "192.0.2.45", expectedOk: true},
+		{description: "Teredo Other Client", ip: "2001:0:4136:e378:8000:63bf:3bc6:fe01", expectedIP: "196.57.1.254", expectedOk: true},
+		{description: "Teredo Zero Client", ip: "2001::ffff:ffff", expectedIP: "0.0.0.0", expectedOk: true},
+		{description: "Not Teredo", ip: "2001:db8::3fff:fdd2", expectedOk: false},
+		{description: "Not 6to4", ip: "2003:c000:204::1", expectedOk: false},
+		{description: "IPv4-Mapped", ip: "::ffff:192.0.2.1", expectedOk: false},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		ip, ok := cmd.EmbeddedIPv4(mustParseIp(t, tc.ip).(cmd.IPv6Address))
+		if ok != tc.expectedOk {
+			t.Errorf("expected: %v, got: %v", tc.expectedOk, ok)
+		}
+		if ok && ip.String() != tc.expectedIP {
+			t.Errorf("expected: %v, got: %v", tc.expectedIP, ip)
+		}
+	}
+}
+
+func TestRunWithOptionsDecodeTransition(t *testing.T) {
+	input := `192.0.2.1
+2002:c000:204::1
+2001:0:4136:e378:8000:63bf:3fff:fdd2
+2002:cb00:7101::1
+2001:db8::1
+::ffff:192.0.2.2`
+
+	testCases := []struct {
+		description string
+		patterns    []string
+		opts        cmd.Options
+		expected    string
+	}{
+		{
+			description: "IPv4 Pattern",
+			patterns:    []string{"192.0.2.0/24"},
+			opts:        cmd.Options{DecodeTransition: true},
+			expected:    "192.0.2.1\n2002:c000:204::1\n2001:0:4136:e378:8000:63bf:3fff:fdd2\n",
+		},
+		{
+			description: "Without Decoding",
+			patterns:    []string{"192.0.2.0/24"},
+			expected:    "192.0.2.1\n",
+		},
+		{
+			description: "Teredo Server Not Matched",
+			patterns:    []string{"65.54.227.120/32"},
+			opts:        cmd.Options{DecodeTransition: true},
+			expected:    "",
+		},
+		{
+			description: "IPv6 Pattern",
+			patterns:    []string{"2002::/16"},
+			opts:        cmd.Options{DecodeTransition: true},
+			expected:    "2002:c000:204::1\n2002:cb00:7101::1\n",
+		},
+		{
+			description: "Negated IPv4 Pattern",
+			patterns:    []string{"2002::/16", "!192.0.2.0/24"},
+			opts:        cmd.Options{DecodeTransition: true},
+			expected:    "2002:cb00:7101::1\n",
+		},
+		{
+			description: "With MapIPv4",
+			patterns:    []string{"192.0.2.0/24"},
+			opts:        cmd.Options{DecodeTransition: true, MapIPv4: true},
+			expected:    "192.0.2.1\n2002:c000:204::1\n2001:0:4136:e378:8000:63bf:3fff:fdd2\n::ffff:192.0.2.2\n",
+		},
+		{
+			description: "Show Pattern",
+			patterns:    []string{"2001::/32", "192.0.2.0/24"},
+			opts:        cmd.Options{DecodeTransition: true, ShowPattern: true, OnlyMatching: true},
+			expected:    "192.0.2.1 [192.0.2.0/24]\n2002:c000:204::1 [192.0.2.0/24]\n2001:0:4136:e378:8000:63bf:3fff:fdd2 [2001::/32]\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		outbuf := &bytes.Buffer{}
+		_, err := cmd.RunWithOptions(strings.NewReader(input), outbuf, &bytes.Buffer{}, tc.patterns, tc.opts)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if outbuf.String() != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, outbuf.String())
+		}
+	}
+}
+
+func TestRootCmdDecodeTransition(t *testing.T) {
+	paths := writeFiles(t, "2002:c000:204::1\n2002:cb00:7101::1\n")
+
+	out, err := execute(t, "--decode-transition", "-e", "192.0.2.0/24", paths[0])
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := "2002:c000:204::1\n"
+	if out != expected {
+		t.Errorf("expected: %v, got: %v", expected, out)
+	}
+}
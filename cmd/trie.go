@@ -0,0 +1,72 @@
+package cmd
+
+// prefixTrie is a binary trie of prefixes, which matches an IP address in
+// time proportional to its bit length regardless of the number of prefixes.
+type prefixTrie struct {
+	root trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	// terminal reports whether a prefix ends at this node.
+	terminal bool
+	// index is the smallest index of the patterns ending at this node.
+	index int
+}
+
+// insert adds the first length bits of ip as a prefix of the pattern at index.
+func (t *prefixTrie) insert(ip []byte, length int, index int) {
+	node := &t.root
+	for i := 0; i < length; i++ {
+		bit := ip[i/8] >> (7 - i%8) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode{}
+		}
+		node = node.children[bit]
+	}
+	if !node.terminal || index < node.index {
+		node.index = index
+	}
+	node.terminal = true
+}
+
+// match returns the index of a pattern whose prefix is a prefix of ip, or -1
+// if none. If first is set, it returns the smallest one.
+func (t *prefixTrie) match(ip []byte, first bool) int {
+	found := -1
+	node := &t.root
+	for i := 0; ; i++ {
+		if node.terminal && (found < 0 || node.index < found) {
+			found = node.index
+			if !first {
+				return found
+			}
+		}
+		if i == len(ip)*8 {
+			return found
+		}
+		node = node.children[ip[i/8]>>(7-i%8)&1]
+		if node == nil {
+			return found
+		}
+	}
+}
+
+// longest returns the smallest index of the patterns of the longest prefix
+// matching ip, or -1 if none.
+func (t *prefixTrie) longest(ip []byte) int {
+	found := -1
+	node := &t.root
+	for i := 0; ; i++ {
+		if node.terminal {
+			found = node.index
+		}
+		if i == len(ip)*8 {
+			return found
+		}
+		node = node.children[ip[i/8]>>(7-i%8)&1]
+		if node == nil {
+			return found
+		}
+	}
+}
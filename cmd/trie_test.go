@@ -0,0 +1,102 @@
+package cmd_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+// randomPrefixes returns n random IPv4 and IPv6 prefix patterns.
+func randomPrefixes(r *rand.Rand, n int) []string {
+	patterns := make([]string, n)
+	for i := range patterns {
+		if i%2 == 0 {
+			patterns[i] = fmt.Sprintf("%d.%d.%d.0/%d", r.Intn(256), r.Intn(256), r.Intn(256), 8+r.Intn(17))
+		} else {
+			patterns[i] = fmt.Sprintf("2001:db8:%x:%x::/%d", r.Intn(0x10000), r.Intn(0x10000), 32+r.Intn(33))
+		}
+	}
+	return patterns
+}
+
+// randomIPs returns n random IPv4 and IPv6 addresses.
+func randomIPs(r *rand.Rand, n int) []cmd.IPAddress {
+	ips := make([]cmd.IPAddress, n)
+	for i := range ips {
+		if i%2 == 0 {
+			var ip cmd.IPv4Address
+			r.Read(ip.IP[:])
+			ips[i] = ip
+		} else {
+			ip := cmd.IPv6Address{IP: [16]byte{0x20, 0x01, 0x0d, 0xb8}}
+			r.Read(ip.IP[4:])
+			ips[i] = ip
+		}
+	}
+	return ips
+}
+
+// linearPattern parses the patterns into a pattern matching them one by one.
+func linearPattern(t testing.TB, patterns []string) cmd.CompositePattern {
+	linear := cmd.CompositePattern{Patterns: make([]cmd.Pattern, len(patterns))}
+	for i, p := range patterns {
+		pattern, err := cmd.ParsePattern(p)
+		if err != nil {
+			t.Fatalf("parse pattern: unexpected error: %v", err)
+		}
+		linear.Patterns[i] = pattern
+	}
+	return linear
+}
+
+func TestMatcherPrefixTrie(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	patterns := append(randomPrefixes(r, 1000), "0.0.0.1/-8", "10.0.0.0-10.0.0.255", "2001:db8::/-64/96")
+	m, err := cmd.NewMatcher(patterns)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	linear := linearPattern(t, patterns)
+
+	matched := 0
+	for _, ip := range randomIPs(r, 100000) {
+		expected := linear.Match(ip)
+		if m.Match(ip) != expected {
+			t.Errorf("%v: expected: %v, got: %v", ip, expected, m.Match(ip))
+		}
+		if expected {
+			matched++
+		}
+	}
+	// make sure both results appear
+	if matched == 0 {
+		t.Errorf("expected some addresses to match")
+	}
+}
+
+func BenchmarkMatcher10kPrefixes(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	m, err := cmd.NewMatcher(randomPrefixes(r, 10000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	ips := randomIPs(r, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(ips[i%len(ips)])
+	}
+}
+
+func BenchmarkLinear10kPrefixes(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	linear := linearPattern(b, randomPrefixes(r, 10000))
+	ips := randomIPs(r, 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linear.Match(ips[i%len(ips)])
+	}
+}
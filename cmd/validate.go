@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd() *cobra.Command {
+	var patternFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "validate [flags] -f file ...",
+		Short: "Check the patterns of pattern files",
+		Long: `The validate command parses every pattern of the pattern files and reports each
+invalid one with its line number, without reading any input.
+It exits with an error if any pattern is invalid.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(patternFiles) == 0 {
+				return fmt.Errorf("no pattern files specified")
+			}
+
+			eout := cmd.ErrOrStderr()
+			invalid := 0
+			for _, file := range patternFiles {
+				lines, err := readPatternLines(file)
+				if err != nil {
+					return err
+				}
+				for _, l := range lines {
+					if _, err := ParsePattern(l.text); err != nil {
+						fmt.Fprintf(eout, "%s:%d: %v: %s\n", file, l.lineno, err, l.text)
+						invalid++
+					}
+				}
+			}
+			if invalid > 0 {
+				// the patterns are already reported
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d invalid patterns", invalid)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&patternFiles, "file", "f", []string{}, "pattern file to check, one pattern per line")
+
+	return cmd
+}
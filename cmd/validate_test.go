@@ -0,0 +1,72 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestValidateCmd(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.txt")
+	mixed := filepath.Join(dir, "mixed.txt")
+	files := map[string]string{
+		valid: "# allowlist\n10.0.0.0/8\n\n  fe80::/10  \n!private\n",
+		mixed: "10.0.0.0/8\n10.0.0.0/33\n# comment\n10.0.0/8\n!!private\n192.168.*.5\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		description string
+		args        []string
+		expectedErr bool
+		expected    string
+	}{
+		{
+			description: "Valid Patterns",
+			args:        []string{"-f", valid},
+		},
+		{
+			description: "Invalid Patterns",
+			args:        []string{"-f", valid, "-f", mixed},
+			expectedErr: true,
+			expected: mixed + ":2: invalid pattern: 10.0.0.0/33\n" +
+				mixed + ":4: invalid ip: 10.0.0/8\n" +
+				mixed + ":5: invalid pattern: !!private\n",
+		},
+		{
+			description: "Missing File",
+			args:        []string{"-f", filepath.Join(dir, "missing.txt")},
+			expectedErr: true,
+		},
+		{
+			description: "No Files",
+			args:        []string{},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		errbuf := &bytes.Buffer{}
+		root := cmd.NewRootCmd()
+		root.SetArgs(append([]string{"validate"}, tc.args...))
+		root.SetOut(&bytes.Buffer{})
+		root.SetErr(errbuf)
+		err := root.Execute()
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if tc.expected != "" && !bytes.HasPrefix(errbuf.Bytes(), []byte(tc.expected)) {
+			t.Errorf("expected: %v, got: %v", tc.expected, errbuf.String())
+		}
+	}
+}
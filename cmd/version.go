@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// The build information set with -ldflags, e.g.
+// -X github.com/kusshi94/gipp/cmd.Version=v1.0.0
+var (
+	Version = "dev"
+	Commit  = "dev"
+	Date    = "dev"
+)
+
+// versionString returns the build information printed by --version and the
+// version subcommand.
+func versionString() string {
+	return fmt.Sprintf("gipp version %s (commit %s, built at %s)\n", Version, Commit, Date)
+}
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of gipp",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprint(cmd.OutOrStdout(), versionString())
+		},
+	}
+}
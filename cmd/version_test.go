@@ -0,0 +1,22 @@
+package cmd_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kusshi94/gipp/cmd"
+)
+
+func TestVersion(t *testing.T) {
+	for _, args := range [][]string{{"--version"}, {"version"}} {
+		fmt.Println(args[0])
+		out, err := execute(t, args...)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !strings.Contains(out, cmd.Version) || !strings.HasPrefix(out, "gipp version ") {
+			t.Errorf("expected the version %v, got: %v", cmd.Version, out)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math/bits"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+func newWhichCmd() *cobra.Command {
+	var patterns []string
+	var patternFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "which [flags] [-e pattern] [-f file] address",
+		Short: "Print the patterns matching an IP address",
+		Long: `The which command prints every pattern matching the IP address with the number of
+the bits it fixes, e.g. the prefix length of a prefix, the most specific first.
+A negated pattern excluding the address is printed as excluded after them.
+The exit status is 1 when no patterns match or the address is excluded, like
+no lines selected.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// load pattern files
+			for _, file := range patternFiles {
+				ps, err := readPatternFile(file)
+				if err != nil {
+					return err
+				}
+				patterns = append(patterns, ps...)
+			}
+			if len(patterns) == 0 {
+				return fmt.Errorf("no patterns specified")
+			}
+			ip, err := ParseIp(args[0])
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, args[0])
+			}
+
+			var matches []patternMatch
+			var exclusions []string
+			for i, s := range patterns {
+				pattern, err := ParsePattern(s)
+				if err != nil {
+					return &PatternError{Index: i, Pattern: s, Err: err}
+				}
+				// a negated pattern never matches but may exclude the address
+				if negated, ok := pattern.(NegatedPattern); ok {
+					if negated.Pattern.Match(ip) {
+						exclusions = append(exclusions, s)
+					}
+					continue
+				}
+				if pattern.Match(ip) {
+					matches = append(matches, patternMatch{source: s, bits: specificity(pattern, ip)})
+				}
+			}
+			// the patterns fixing as many bits keep the given order
+			slices.SortStableFunc(matches, func(a, b patternMatch) int {
+				return b.bits - a.bits
+			})
+
+			w := bufio.NewWriter(cmd.OutOrStdout())
+			defer w.Flush()
+			for _, m := range matches {
+				fmt.Fprintf(w, "%s %d\n", m.source, m.bits)
+			}
+			for _, s := range exclusions {
+				fmt.Fprintf(w, "%s excluded\n", s)
+			}
+			// an excluded address is not selected even if patterns match it
+			if len(exclusions) > 0 {
+				return checkMatched(cmd, 0)
+			}
+			return checkMatched(cmd, len(matches))
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&patterns, "pattern", "e", []string{}, "pattern")
+	cmd.Flags().StringSliceVarP(&patternFiles, "file", "f", []string{}, "read patterns from the file, one per line")
+
+	return cmd
+}
+
+// patternMatch is a pattern matching the address of the which command.
+type patternMatch struct {
+	source string
+	bits   int
+}
+
+// specificity returns the number of the bits that every address matching the
+// pattern has in common with it: the prefix length of a prefix, the common
+// prefix length of the ends of a range and the bits not masked by a
+// wildcard. A composite pattern fixes the most bits of its patterns that ip
+// matches.
+func specificity(p Pattern, ip IPAddress) int {
+	switch p := p.(type) {
+	case MaskPattern:
+		return p.MaskEnd - p.MaskStart
+	case RangePattern:
+		start, end := p.Start.Bytes(), p.End.Bytes()
+		n := 0
+		for i := range start {
+			n += bits.LeadingZeros8(start[i] ^ end[i])
+			if start[i] != end[i] {
+				break
+			}
+		}
+		return n
+	case WildcardPattern:
+		n := 0
+		for _, b := range p.Mask {
+			n += bits.OnesCount8(b)
+		}
+		return n
+	case CompositePattern:
+		n := 0
+		for _, inner := range p.Patterns {
+			if inner.Match(ip) {
+				n = max(n, specificity(inner, ip))
+			}
+		}
+		return n
+	case NegatedPattern:
+		return specificity(p.Pattern, ip)
+	}
+	return 0
+}
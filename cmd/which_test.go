@@ -0,0 +1,114 @@
+package cmd_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWhichCmd(t *testing.T) {
+	patternFile := filepath.Join(t.TempDir(), "patterns.txt")
+	if err := os.WriteFile(patternFile, []byte("# allowlist\n10.0.0.0/8\n10.222.0.0/16\n192.168.0.0/16\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		description string
+		args        []string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			description: "Overlapping Prefixes",
+			args:        []string{"-f", patternFile, "10.222.5.5"},
+			expected:    "10.222.0.0/16 16\n10.0.0.0/8 8\n",
+		},
+		{
+			description: "Most Specific First",
+			args:        []string{"-e", "10.0.0.0/8,10.222.5.0/24,10.222.0.0/16", "10.222.5.5"},
+			expected:    "10.222.5.0/24 24\n10.222.0.0/16 16\n10.0.0.0/8 8\n",
+		},
+		{
+			description: "Other Patterns",
+			args:        []string{"-e", "0.0.0.5/-8,10.222.5.0-10.222.5.127,10.*.5.*,private,::/0", "10.222.5.5"},
+			expected:    "10.222.5.0-10.222.5.127 25\n10.*.5.* 16\n0.0.0.5/-8 8\nprivate 8\n",
+		},
+		{
+			description: "Negated Pattern Not Excluding",
+			args:        []string{"-e", "10.0.0.0/8,!192.168.0.0/16", "10.222.5.5"},
+			expected:    "10.0.0.0/8 8\n",
+		},
+		{
+			description: "IPv6",
+			args:        []string{"-e", "fe80::/10,fe80::5400:0:0:0/72,::1/128", "fe80::5400:4ff:fe6f:1d4b"},
+			expected:    "fe80::5400:0:0:0/72 72\nfe80::/10 10\n",
+		},
+		{
+			description: "Invalid Address",
+			args:        []string{"-e", "10.0.0.0/8", "10.222.5"},
+			expectedErr: true,
+		},
+		{
+			description: "Invalid Pattern",
+			args:        []string{"-e", "10.0.0.0/33", "10.222.5.5"},
+			expectedErr: true,
+		},
+		{
+			description: "No Patterns",
+			args:        []string{"10.222.5.5"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, append([]string{"which"}, tc.args...)...)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+		}
+		if out != tc.expected && !tc.expectedErr {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
+
+func TestWhichCmdNoMatch(t *testing.T) {
+	out, err := execute(t, "which", "-e", "192.168.0.0/16", "10.222.5.5")
+	if err == nil || err.Error() != "no lines selected" {
+		t.Errorf("expected no lines selected error, got: %v", err)
+	}
+	if out != "" {
+		t.Errorf("expected: %v, got: %v", "", out)
+	}
+}
+
+func TestWhichCmdExcluded(t *testing.T) {
+	testCases := []struct {
+		description string
+		patterns    string
+		expected    string
+	}{
+		{
+			description: "Negated Pattern Only",
+			patterns:    "!10.0.0.0/8",
+			expected:    "!10.0.0.0/8 excluded\n",
+		},
+		{
+			description: "Matching and Negated Patterns",
+			patterns:    "10.0.0.0/8,!10.222.0.0/16,!192.168.0.0/16",
+			expected:    "10.0.0.0/8 8\n!10.222.0.0/16 excluded\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		fmt.Println(tc.description)
+		out, err := execute(t, "which", "-e", tc.patterns, "10.222.5.5")
+		if err == nil || err.Error() != "no lines selected" {
+			t.Errorf("expected no lines selected error, got: %v", err)
+		}
+		if out != tc.expected {
+			t.Errorf("expected: %v, got: %v", tc.expected, out)
+		}
+	}
+}
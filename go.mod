@@ -1,10 +1,16 @@
 module github.com/kusshi94/gipp
 
-go 1.21.1
+go 1.22
 
-require github.com/spf13/cobra v1.8.0
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/oschwald/maxminddb-golang v1.13.1
+	github.com/spf13/cobra v1.8.0
+	github.com/ulikunitz/xz v0.5.12
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 )